@@ -0,0 +1,101 @@
+// Command calibrate подбирает температуру, с которой корректор переводит скоры кандидатов
+// в уверенность (вероятность правильности), на отложенном размеченном корпусе (формат -
+// см. internal/eval), который не использовался при подборе параметров (cmd/tune).
+// Температура записывается в профиль (поле confidence_temperature) вместе с остальными
+// параметрами скоринга; отчет содержит Brier score и диаграмму надежности до и после.
+// Оценка "после" получена кросс-валидацией (-folds): каждое слово оценивается при
+// температуре, подобранной без него, иначе она завышала бы качество калибровки.
+//
+// Использование:
+//
+//	calibrate -corpus heldout.tsv -dict ru.txt -profile profile.json -report calibration.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"corrector/internal/calibrate"
+	sc "corrector/internal/corrector"
+	"corrector/internal/eval"
+)
+
+func main() {
+	corpus := flag.String("corpus", "", "отложенный корпус: исходное<TAB>правильное[<TAB>тип]")
+	dict := flag.String("dict", "ru.txt", "частотный словарь")
+	morph := flag.String("morph", "", "словарь morph.dawg (по умолчанию - как у сервера)")
+	errModel := flag.String("error-model", "", "модель ошибок (см. errtrain)")
+	profile := flag.String("profile", "", "профиль параметров скоринга (см. tune)")
+	out := flag.String("out", "", "куда записать профиль с температурой (по умолчанию - в -profile или profile.json)")
+	bins := flag.Int("bins", 10, "число интервалов диаграммы надежности")
+	folds := flag.Int("folds", 5, "число блоков кросс-валидации для оценки подобранной температуры")
+	report := flag.String("report", "", "файл отчета о калибровке (по умолчанию - стандартный вывод)")
+	flag.Parse()
+
+	if *corpus == "" || *folds < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *out == "" {
+		*out = *profile
+		if *out == "" {
+			*out = "profile.json"
+		}
+	}
+	samples, err := eval.ReadCorpusFile(*corpus)
+	if err != nil {
+		log.Fatalf("ошибка чтения корпуса: %v", err)
+	}
+
+	cfg := sc.DefaultConfig()
+	cfg.MorphDictPath = *morph
+	cfg.ErrorModelPath = *errModel
+	if *profile != "" {
+		if err := sc.LoadProfile(*profile, &cfg); err != nil {
+			log.Fatalf("ошибка загрузки профиля: %v", err)
+		}
+	}
+	corrector, err := sc.NewSpellCorrector(cfg, *dict, nil)
+	if err != nil {
+		log.Fatalf("init error: %v", err)
+	}
+
+	var examples []calibrate.Example
+	for _, s := range samples {
+		examples = append(examples, calibrate.Examples(corrector.CorrectText(s.Original, false), s.Expected)...)
+	}
+	if len(examples) == 0 {
+		log.Fatal("в корпусе нет слов, для которых корректор оценивал кандидатов")
+	}
+	// Исходная температура не подбирается по корпусу, поэтому ее оценивают на всех словах
+	before := calibrate.Evaluate(examples, cfg.ConfidenceTemperature, *bins)
+	after := calibrate.CrossValidate(examples, *folds, *bins)
+	t := calibrate.Fit(examples)
+	log.Printf("слов: %d (правильная форма среди кандидатов: %d)", after.Words, after.Covered)
+	log.Printf("температура %.4f -> %.4f, Brier %.4f -> %.4f, ECE %.4f -> %.4f (кросс-валидация, блоков: %d)",
+		before.Temperature, t, before.Brier, after.Brier, before.ECE, after.ECE, after.Folds)
+
+	scoring := cfg.Scoring()
+	scoring.ConfidenceTemperature = t
+	if err := sc.SaveProfile(*out, scoring); err != nil {
+		log.Fatalf("ошибка записи профиля: %v", err)
+	}
+	log.Printf("профиль записан в %s", *out)
+
+	data, err := json.MarshalIndent(struct {
+		// Temperature - температура, подобранная по всем словам и записанная в профиль.
+		Temperature float64          `json:"temperature"`
+		Before      calibrate.Report `json:"before"`
+		After       calibrate.Report `json:"after"`
+	}{t, before, after}, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *report == "" {
+		os.Stdout.Write(append(data, '\n'))
+	} else if err := os.WriteFile(*report, append(data, '\n'), 0o644); err != nil {
+		log.Fatalf("ошибка записи отчета: %v", err)
+	}
+}
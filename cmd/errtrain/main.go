@@ -0,0 +1,94 @@
+// Command errtrain обучает модель ошибок набора (internal/errmodel) по корпусу пар
+// "опечатка -> исправление" и записывает ее в JSON-файл, который корректор
+// загружает вместо ручных таблиц стоимостей (ERROR_MODEL_PATH).
+//
+// Формат корпуса: по паре в строке, поля через табуляцию - опечатка, исправление
+// и необязательное число повторов пары. Строки, начинающиеся с '#', пропускаются.
+//
+//	превет	привет	12
+//	сдесь	здесь
+//
+// Использование:
+//
+//	errtrain -in typos.tsv -out errmodel.json
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"corrector/internal/errmodel"
+)
+
+func main() {
+	in := flag.String("in", "", "корпус пар опечатка<TAB>исправление[<TAB>повторы]")
+	out := flag.String("out", "errmodel.json", "путь к файлу модели")
+	smoothing := flag.Float64("smoothing", 0.5, "аддитивное сглаживание счетчиков правок")
+	minContext := flag.Float64("min-context", 20, "минимальная частота символа или пары для оценки с учетом контекста")
+	maxDist := flag.Int("max-dist", 3, "пары с большим числом правок пропускаются")
+	flag.Parse()
+
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	f, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("ошибка открытия корпуса: %v", err)
+	}
+	defer f.Close()
+
+	t := errmodel.NewTrainer()
+	t.Smoothing = *smoothing
+	t.MinContextCount = *minContext
+	t.MaxDistance = *maxDist
+	skipped, err := readPairs(f, t)
+	if err != nil {
+		log.Fatalf("ошибка чтения корпуса: %v", err)
+	}
+	log.Printf("учтено пар: %d, пропущено: %d", t.Pairs(), skipped)
+
+	m, err := t.Model()
+	if err != nil {
+		log.Fatalf("ошибка обучения: %v", err)
+	}
+	if err := m.Save(*out); err != nil {
+		log.Fatalf("ошибка записи модели: %v", err)
+	}
+	log.Printf("модель записана в %s", *out)
+}
+
+// readPairs передает пары корпуса в t и возвращает число пропущенных пар.
+func readPairs(r io.Reader, t *errmodel.Trainer) (int, error) {
+	s := bufio.NewScanner(r)
+	skipped, lineNo := 0, 0
+	for s.Scan() {
+		lineNo++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || len(fields) > 3 {
+			return skipped, fmt.Errorf("строка %d: ожидается \"опечатка<TAB>исправление[<TAB>повторы]\"", lineNo)
+		}
+		weight := 1.0
+		if len(fields) == 3 {
+			n, err := strconv.ParseFloat(strings.TrimSpace(fields[2]), 64)
+			if err != nil || n <= 0 {
+				return skipped, fmt.Errorf("строка %d: некорректное число повторов %q", lineNo, fields[2])
+			}
+			weight = n
+		}
+		if !t.Add(strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1]), weight) {
+			skipped++
+		}
+	}
+	return skipped, s.Err()
+}
@@ -1,57 +1,77 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/redis/go-redis/v9"
 
+	"corrector/internal/analyzer"
 	sc "corrector/internal/corrector"
 	"corrector/internal/customdict"
 )
 
 func main() {
-	cfg := sc.CorrectorConfig{
-		MaxEditDistance:  2,
-		FreqTemperature:  2.0,
-		TopKSuggestions:  8,
-		BetaWeight:       1.0,
-		LambdaPenalty:    0.9,
-		GammaMorph:       1.05,
-		MarginThreshold:  0.25,
-		TauInVocab:       0.5,
-		TauOutVocab:      0.3,
-		UseSymSpell:      true,
-		UseMorphology:    true,
-		EnableContext:    true,
-		FilterShortWords: true,
-		TransposeCost:    0.6,
-		NeighborInsDel:   0.9,
-		KeyboardNearSub:  0.6,
-	}
-
-	redisAddr := getenv("REDIS_ADDR", "localhost:6379")
-	redisPassword := os.Getenv("REDIS_PASSWORD")
-	redisDB := getEnvInt("REDIS_DB", 0)
-
-	client := redis.NewClient(&redis.Options{
-		Addr:     redisAddr,
-		Password: redisPassword,
-		DB:       redisDB,
-	})
+	cfg := sc.DefaultConfig()
+	cfg.MorphDictPath = os.Getenv(analyzer.EnvDictPath)
+	cfg.ErrorModelPath = os.Getenv("ERROR_MODEL_PATH")
+	cfg.TaggerCorpusPath = os.Getenv("MORPH_TAGGER_CORPUS")
+	cfg.MaxUserWords = getEnvInt("USER_DICT_MAX_WORDS", cfg.MaxUserWords)
+	cfg.MaxTenantLexicons = getEnvInt("TENANT_DICT_CACHE_SIZE", cfg.MaxTenantLexicons)
+	cfg.MaxUserLexicons = getEnvInt("USER_DICT_CACHE_SIZE", cfg.MaxUserLexicons)
+	cfg.LexiconIdleTimeout = getEnvDuration("CUSTOM_DICT_IDLE_TIMEOUT", cfg.LexiconIdleTimeout)
+	cfg.CustomWordFrequency = float64(getEnvInt("CUSTOM_WORD_FREQUENCY", int(cfg.CustomWordFrequency)))
+	cfg.FeedbackMinUsers = getEnvInt("FEEDBACK_MIN_USERS", cfg.FeedbackMinUsers)
+	cfg.FeedbackRatePerMinute = getEnvInt("FEEDBACK_RATE_PER_MINUTE", cfg.FeedbackRatePerMinute)
+	// Параметры скоринга, подобранные cmd/tune
+	if path := os.Getenv("CORRECTOR_PROFILE"); path != "" {
+		if err := sc.LoadProfile(path, &cfg); err != nil {
+			log.Fatalf("profile error: %v", err)
+		}
+	}
 
-	dict := customdict.New(client)
+	dict, err := newCustomDictStore()
+	if err != nil {
+		log.Fatalf("custom dictionary error: %v", err)
+	}
 
 	dictionaryPath := getenv("DICTIONARY_PATH", "ru.txt")
 	corrector, err := sc.NewSpellCorrector(cfg, dictionaryPath, dict)
 	if err != nil {
 		log.Fatalf("init error: %v", err)
 	}
+	// Изменения словарей с других реплик: события хранилища и периодическая полная пересинхронизация
+	corrector.StartSync(context.Background(), getEnvDuration("CUSTOM_DICT_RESYNC_INTERVAL", time.Minute))
+	if morph := corrector.Morph(); morph != nil {
+		for _, ts := range []analyzer.TagSet{analyzer.OpenCorporaTagSet, analyzer.UDTagSet} {
+			if unmapped := morph.UnmappedGrammemes(ts); len(unmapped) > 0 {
+				log.Printf("tagset %s: no mapping for grammemes %v", ts.Name(), unmapped)
+			}
+		}
+	}
 
+	mux := newMux(cfg, corrector)
+
+	addr := getenv("HTTP_ADDR", ":8080")
+	log.Printf("listening on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+// newMux регистрирует обработчики HTTP API корректора.
+func newMux(cfg sc.CorrectorConfig, corrector *sc.SpellCorrector) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/api/v1/correct", func(w http.ResponseWriter, r *http.Request) {
@@ -60,19 +80,30 @@ func main() {
 			return
 		}
 		var req struct {
-			Text string `json:"text"`
+			Text   string `json:"text"`
+			Tenant string `json:"tenant"` // словарь тенанта; можно передать заголовком X-Tenant-ID
+			User   string `json:"user"`   // личный словарь пользователя; можно передать заголовком X-User-ID
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Text) == "" {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
 			return
 		}
-		res := corrector.CorrectText(req.Text, false)
+		scope := scopeOf(r, req.Tenant, req.User)
+		if !scope.Valid() {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tenant or user"})
+			return
+		}
+		res := corrector.CorrectTextFor(scope, req.Text, false)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"original":    res.Original,
 			"corrected":   res.Corrected,
 			"suggestions": res.Suggestions,
+			// откалиброванная вероятность того, что исправленный текст правильный
+			"confidence": res.Confidence,
+			// решения по позициям токенов: hint_only, auto_replace или forced_replace
+			"detailed_suggestions": res.DetailedSugs,
 		})
 	})
 
@@ -82,20 +113,73 @@ func main() {
 			return
 		}
 		var req struct {
-			Word string `json:"word"`
+			Word    string `json:"word"`
+			Like    string `json:"like"`    // слово-образец парадигмы ("банк" для "сбербанк")
+			Inflect bool   `json:"inflect"` // добавить все формы слова, предсказав парадигму
+			// Forms - явная парадигма: формы с тегами (в формате словаря или кодами OpenCorpora).
+			Forms  []customdict.Form `json:"forms"`
+			Tenant string            `json:"tenant"`
+			// Frequency заменяет частоту пользовательских слов по умолчанию (для лексемы - частоту
+			// всех ее форм); Note и Author - метаданные.
+			Frequency int64  `json:"frequency"`
+			Note      string `json:"note"`
+			Author    string `json:"author"` // по умолчанию - заголовок X-User-ID
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Word) == "" {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
 			return
 		}
-		if err := corrector.AddCustomWord(req.Word); err != nil {
+		tenant, ok := tenantOf(r, req.Tenant)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tenant"})
+			return
+		}
+		if len(req.Forms) > 0 || req.Inflect || strings.TrimSpace(req.Like) != "" {
+			lexeme := customdict.Lexeme{
+				Lemma:     req.Word,
+				Forms:     req.Forms,
+				Frequency: req.Frequency,
+				Note:      req.Note,
+				Author:    authorOf(r, req.Author),
+			}
+			var err error
+			if len(req.Forms) > 0 {
+				lexeme, err = corrector.AddLexeme(tenant, lexeme)
+			} else {
+				lexeme, err = corrector.AddCustomLexeme(tenant, lexeme, req.Like)
+			}
+			if errors.Is(err, sc.ErrInvalidWord) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, sc.ErrNoParadigm) {
+				writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusCreated, map[string]interface{}{"status": "ok", "lexeme": lexeme})
+			return
+		}
+		entry, err := corrector.AddCustomWord(tenant, customdict.Entry{
+			Word:      req.Word,
+			Frequency: req.Frequency,
+			Note:      req.Note,
+			Author:    authorOf(r, req.Author),
+		})
+		if errors.Is(err, sc.ErrInvalidWord) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"status": "ok", "entry": entry})
 	})
 
 	mux.HandleFunc("/api/v1/custom-word/", func(w http.ResponseWriter, r *http.Request) {
@@ -109,7 +193,12 @@ func main() {
 			json.NewEncoder(w).Encode(map[string]string{"error": "word is required"})
 			return
 		}
-		if err := corrector.RemoveCustomWord(word); err != nil {
+		tenant, ok := tenantOf(r, "")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tenant"})
+			return
+		}
+		if err := corrector.RemoveCustomWord(tenant, word); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
@@ -117,9 +206,786 @@ func main() {
 		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 	})
 
-	addr := getenv("HTTP_ADDR", ":8080")
-	log.Printf("listening on %s", addr)
-	log.Fatal(http.ListenAndServe(addr, mux))
+	// Пользовательский словарь тенанта целиком: GET - постраничный список отдельных слов
+	// с фильтром по префиксу (лексемы отдает выгрузка), POST - пакетный импорт
+	// (JSON или текст, по слову на строке).
+	mux.HandleFunc("/api/v1/custom-words", func(w http.ResponseWriter, r *http.Request) {
+		tenant, ok := tenantOf(r, "")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tenant"})
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			q := r.URL.Query()
+			offset, limit := queryInt(q.Get("offset"), 0), queryInt(q.Get("limit"), 100)
+			if offset < 0 || limit <= 0 || limit > maxPageSize {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid offset or limit"})
+				return
+			}
+			words, err := corrector.CustomWords(tenant)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			if prefix := strings.ToLower(strings.TrimSpace(q.Get("prefix"))); prefix != "" {
+				// Список отсортирован: слова с префиксом идут подряд
+				lo := sort.Search(len(words), func(i int) bool { return words[i].Word >= prefix })
+				hi := lo
+				for hi < len(words) && strings.HasPrefix(words[hi].Word, prefix) {
+					hi++
+				}
+				words = words[lo:hi]
+			}
+			page := words[min(offset, len(words)):min(offset+limit, len(words))]
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"tenant": tenant,
+				"total":  len(words),
+				"offset": offset,
+				"limit":  limit,
+				"words":  page,
+			})
+		case http.MethodPost:
+			list, err := readWordList(http.MaxBytesReader(w, r.Body, maxImportBytes), r.Header.Get("Content-Type"))
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			for i := range list.Words {
+				list.Words[i].Author = authorOf(r, list.Words[i].Author)
+			}
+			for i := range list.Lexemes {
+				list.Lexemes[i].Author = authorOf(r, list.Lexemes[i].Author)
+			}
+			added := 0
+			if len(list.Words) > 0 {
+				added, err = corrector.ImportCustomWords(tenant, list.Words)
+				if errors.Is(err, sc.ErrInvalidWord) {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+					return
+				}
+				if err != nil {
+					writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+					return
+				}
+			}
+			// Лексемы сохраняются по одной, после слов
+			for _, lx := range list.Lexemes {
+				if _, err := corrector.AddLexeme(tenant, lx); err != nil {
+					writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+					return
+				}
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"status":   "ok",
+				"received": len(list.Words),
+				"added":    added,
+				"lexemes":  len(list.Lexemes),
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	// Выгрузка словаря тенанта в формате импорта: ?format=json (по умолчанию, со словами
+	// и лексемами) или text (только отдельные слова: формы лексем в тексте не передать).
+	mux.HandleFunc("/api/v1/custom-words/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.NotFound(w, r)
+			return
+		}
+		tenant, ok := tenantOf(r, "")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tenant"})
+			return
+		}
+		entries, err := corrector.CustomWords(tenant)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		switch format := r.URL.Query().Get("format"); format {
+		case "", "json":
+			lexemes, err := corrector.CustomLexemes(tenant)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			w.Header().Set("Content-Disposition", `attachment; filename="custom_words.json"`)
+			writeJSON(w, http.StatusOK, map[string]interface{}{"tenant": tenant, "words": entries, "lexemes": lexemes})
+		case "text":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="custom_words.txt"`)
+			writeWordList(w, entries)
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown format"})
+		}
+	})
+
+	// Личный словарь пользователя: GET - список слов, POST - добавить слово.
+	mux.HandleFunc("/api/v1/user-words", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			scope := scopeOf(r, "", "")
+			words, err := corrector.UserWords(scope)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"tenant": scope.Tenant,
+				"user":   scope.User,
+				"words":  words,
+				"limit":  cfg.MaxUserWords,
+			})
+		case http.MethodPost:
+			var req struct {
+				Word   string `json:"word"`
+				Tenant string `json:"tenant"`
+				User   string `json:"user"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Word) == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+				return
+			}
+			err := corrector.AddUserWord(scopeOf(r, req.Tenant, req.User), req.Word)
+			switch {
+			case errors.Is(err, sc.ErrInvalidTenant), errors.Is(err, sc.ErrInvalidUser), errors.Is(err, sc.ErrInvalidWord):
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			case errors.Is(err, sc.ErrUserLimit):
+				writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+			case err != nil:
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			default:
+				writeJSON(w, http.StatusCreated, map[string]string{"status": "ok"})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/user-words/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.NotFound(w, r)
+			return
+		}
+		word := strings.TrimPrefix(r.URL.Path, "/api/v1/user-words/")
+		if word == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "word is required"})
+			return
+		}
+		existed, err := corrector.RemoveUserWord(scopeOf(r, "", ""), word)
+		switch {
+		case errors.Is(err, sc.ErrInvalidTenant), errors.Is(err, sc.ErrInvalidUser):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case err != nil:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		case !existed:
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "word not found"})
+		default:
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		}
+	})
+
+	// Стоп-лист тенанта: слова, которые никогда не предлагаются и не подставляются.
+	mux.HandleFunc("/api/v1/blocked-words", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			tenant, ok := tenantOf(r, "")
+			if !ok {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tenant"})
+				return
+			}
+			words, err := corrector.BlockedWords(tenant)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"tenant": tenant, "words": words})
+		case http.MethodPost:
+			var req struct {
+				Word   string `json:"word"`
+				Tenant string `json:"tenant"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Word) == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+				return
+			}
+			tenant, ok := tenantOf(r, req.Tenant)
+			if !ok {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tenant"})
+				return
+			}
+			err := corrector.BlockWord(tenant, req.Word)
+			switch {
+			case errors.Is(err, sc.ErrInvalidWord):
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			case err != nil:
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			default:
+				writeJSON(w, http.StatusCreated, map[string]string{"status": "ok"})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/blocked-words/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.NotFound(w, r)
+			return
+		}
+		word := strings.TrimPrefix(r.URL.Path, "/api/v1/blocked-words/")
+		if word == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "word is required"})
+			return
+		}
+		tenant, ok := tenantOf(r, "")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tenant"})
+			return
+		}
+		existed, err := corrector.UnblockWord(tenant, word)
+		switch {
+		case err != nil:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		case !existed:
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "word not found"})
+		default:
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		}
+	})
+
+	// Правила принудительной замены тенанта ("щас" -> "сейчас"), применяются до скоринга.
+	mux.HandleFunc("/api/v1/replace-rules", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			tenant, ok := tenantOf(r, "")
+			if !ok {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tenant"})
+				return
+			}
+			rules, err := corrector.Rules(tenant)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"tenant": tenant, "rules": rules})
+		case http.MethodPost:
+			var req struct {
+				From   string `json:"from"`
+				To     string `json:"to"`
+				Tenant string `json:"tenant"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+				return
+			}
+			tenant, ok := tenantOf(r, req.Tenant)
+			if !ok {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tenant"})
+				return
+			}
+			rule, err := corrector.AddRule(tenant, customdict.Rule{From: req.From, To: req.To})
+			switch {
+			case errors.Is(err, sc.ErrInvalidRule):
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			case err != nil:
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			default:
+				writeJSON(w, http.StatusCreated, map[string]interface{}{"status": "ok", "rule": rule})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	// Левая часть правила передается в пути: /api/v1/replace-rules/в%20кратце
+	mux.HandleFunc("/api/v1/replace-rules/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.NotFound(w, r)
+			return
+		}
+		from := strings.TrimPrefix(r.URL.Path, "/api/v1/replace-rules/")
+		if from == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "rule is required"})
+			return
+		}
+		tenant, ok := tenantOf(r, "")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tenant"})
+			return
+		}
+		existed, err := corrector.RemoveRule(tenant, from)
+		switch {
+		case err != nil:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		case !existed:
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "rule not found"})
+		default:
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		}
+	})
+
+	// Отзывы о подсказках: принятые и отклоненные пары влияют на скор кандидатов тенанта.
+	mux.HandleFunc("/api/v1/feedback", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			tenant, ok := tenantOf(r, "")
+			if !ok {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid tenant"})
+				return
+			}
+			pairs, err := corrector.Feedback(tenant)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"tenant": tenant, "pairs": pairs})
+		case http.MethodPost:
+			var req struct {
+				Original   string `json:"original"`
+				Suggestion string `json:"suggestion"`
+				Accepted   *bool  `json:"accepted"`
+				Context    string `json:"context"` // текст вокруг слова, для анализа отзывов
+				Tenant     string `json:"tenant"`
+				User       string `json:"user"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Accepted == nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+				return
+			}
+			vote, err := corrector.RecordFeedback(scopeOf(r, req.Tenant, req.User), customdict.Vote{
+				Original:   req.Original,
+				Suggestion: req.Suggestion,
+				Accepted:   *req.Accepted,
+				Context:    req.Context,
+				Client:     clientOf(r),
+			})
+			switch {
+			case errors.Is(err, sc.ErrInvalidTenant), errors.Is(err, sc.ErrInvalidUser), errors.Is(err, sc.ErrInvalidFeedback):
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			case errors.Is(err, sc.ErrFeedbackRateLimit):
+				writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+			case err != nil:
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			default:
+				writeJSON(w, http.StatusCreated, map[string]interface{}{"status": "ok", "vote": vote})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/morph/parse", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		morph := corrector.Morph()
+		if morph == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "morphology is disabled"})
+			return
+		}
+		var req struct {
+			Word    string   `json:"word"`
+			Words   []string `json:"words"`
+			Context bool     `json:"context"` // words - предложение; разборы ранжируются теггером
+			TagSet  string   `json:"tagset"`  // native (по умолчанию), opencorpora или ud
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+			return
+		}
+		words, ok := batch(req.Word, req.Words)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+			return
+		}
+		tagset, ok := analyzer.TagSetByName(req.TagSet)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown tagset"})
+			return
+		}
+		tagger := corrector.Tagger()
+		if req.Context && tagger == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "tagger is not configured"})
+			return
+		}
+		type result struct {
+			Word   string      `json:"word"`
+			Parses interface{} `json:"parses"`
+		}
+		sentence := make([][]*analyzer.Parsed, 0, len(words))
+		for _, word := range words {
+			// Словарные разборы, а при их отсутствии - предсказанные (см. поле origin).
+			parses := morph.Parse(word)
+			if len(parses) == 0 {
+				parses = morph.ParsePredicted(word)
+			}
+			if parses == nil {
+				parses = []*analyzer.Parsed{}
+			}
+			sentence = append(sentence, parses)
+		}
+		if req.Context {
+			sentence = tagger.Disambiguate(sentence)
+		}
+		results := make([]result, 0, len(words))
+		for i, word := range words {
+			results = append(results, result{Word: word, Parses: renderParses(tagset, sentence[i])})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+	})
+
+	mux.HandleFunc("/api/v1/morph/lemmatize", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		morph := corrector.Morph()
+		if morph == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "morphology is disabled"})
+			return
+		}
+		var req struct {
+			Text  string   `json:"text"`
+			Texts []string `json:"texts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+			return
+		}
+		texts, ok := batch(req.Text, req.Texts)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+			return
+		}
+		type result struct {
+			Text   string                `json:"text"`
+			Tokens []analyzer.LemmaToken `json:"tokens"`
+		}
+		results := make([]result, 0, len(texts))
+		for _, text := range texts {
+			results = append(results, result{Text: text, Tokens: morph.Lemmatize(text)})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+	})
+
+	mux.HandleFunc("/api/v1/morph/inflect", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		morph := corrector.Morph()
+		if morph == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "morphology is disabled"})
+			return
+		}
+		var req struct {
+			Word      string   `json:"word"`
+			Words     []string `json:"words"`
+			Grammemes []string `json:"grammemes"`
+			Number    *int     `json:"number"`
+			TagSet    string   `json:"tagset"` // система обозначений граммем запроса и ответа
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+			return
+		}
+		words, ok := batch(req.Word, req.Words)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+			return
+		}
+		tagset, ok := analyzer.TagSetByName(req.TagSet)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown tagset"})
+			return
+		}
+		// Граммемы записываются в той же системе обозначений, что и ответ.
+		var required analyzer.GrammemeSet
+		if len(req.Grammemes) > 0 {
+			var unknown []string
+			required, unknown = tagset.ParseGrammemes(req.Grammemes)
+			if len(unknown) > 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown grammemes: " + strings.Join(unknown, ", ")})
+				return
+			}
+		}
+
+		type result struct {
+			Word  string      `json:"word"`
+			Form  interface{} `json:"form,omitempty"`
+			Forms interface{} `json:"forms,omitempty"`
+			Error string      `json:"error,omitempty"`
+		}
+		results := make([]result, 0, len(words))
+		for _, word := range words {
+			res := result{Word: word}
+			// Без целевых граммем и числа возвращаем всю парадигму.
+			if required == nil && req.Number == nil {
+				if _, forms := morph.Analyze(word); forms == nil {
+					res.Error = "word not found"
+				} else {
+					res.Forms = renderParses(tagset, forms)
+				}
+				results = append(results, res)
+				continue
+			}
+
+			var form *analyzer.Parsed
+			if req.Number != nil {
+				// Число и запрошенные граммемы ставятся вместе: "рубль", 2, дательный → "рублям".
+				form = morph.InflectWithNumber(word, *req.Number, required)
+			} else {
+				form = morph.InflectTo(word, required)
+			}
+			if form == nil {
+				res.Error = "form not found"
+			} else {
+				res.Form = renderParses(tagset, []*analyzer.Parsed{form})[0]
+			}
+			results = append(results, res)
+		}
+
+		// Одиночный запрос сохраняет прежний формат ответа.
+		if len(req.Words) == 0 {
+			res := results[0]
+			switch {
+			case res.Error != "":
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": res.Error})
+			case res.Forms != nil:
+				writeJSON(w, http.StatusOK, map[string]interface{}{"word": res.Word, "forms": res.Forms})
+			default:
+				writeJSON(w, http.StatusOK, map[string]interface{}{"word": res.Word, "form": res.Form})
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+	})
+
+	return mux
+}
+
+// maxBatchSize ограничивает число элементов в пакетных запросах к морфологии.
+const maxBatchSize = 1000
+
+// batch собирает входные данные запроса: одиночное значение или пакет.
+// Возвращает false, если данных нет или пакет слишком большой.
+func batch(single string, many []string) ([]string, bool) {
+	var items []string
+	if strings.TrimSpace(single) != "" {
+		items = append(items, strings.TrimSpace(single))
+	}
+	for _, item := range many {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 || len(items) > maxBatchSize {
+		return nil, false
+	}
+	return items, true
+}
+
+// maxPageSize - наибольший размер страницы списка пользовательских слов.
+const maxPageSize = 1000
+
+// Ограничения пакетного импорта пользовательских слов.
+const (
+	maxImportWords = 100_000
+	maxImportBytes = 16 << 20
+)
+
+// wordList - словарь тенанта в формате импорта и выгрузки JSON. Лексемы хранятся
+// с формами и тегами, чтобы импорт выгрузки восстанавливал их целиком.
+type wordList struct {
+	Words   []customdict.Entry  `json:"words"`
+	Lexemes []customdict.Lexeme `json:"lexemes,omitempty"`
+}
+
+// readWordList читает список слов для импорта. При Content-Type text/plain это текст:
+// по слову на строке, через пробел или табуляцию - необязательная частота;
+// пустые строки и строки с '#' пропускаются. Иначе это JSON {"words": [...], "lexemes": [...]},
+// где элемент words - строка или запись слова {"word", "frequency", "note", ...},
+// а элемент lexemes - лексема {"lemma", "forms": [{"word", "tags"}], "frequency", "note", ...}.
+func readWordList(body io.Reader, contentType string) (wordList, error) {
+	var list wordList
+	if strings.HasPrefix(contentType, "text/plain") {
+		s := bufio.NewScanner(body)
+		lineNo := 0
+		for s.Scan() {
+			lineNo++
+			line := strings.TrimSpace(s.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			fields := strings.Fields(line)
+			if len(fields) > 2 {
+				return wordList{}, fmt.Errorf("line %d: expected \"word [frequency]\"", lineNo)
+			}
+			e := customdict.Entry{Word: fields[0]}
+			if len(fields) == 2 {
+				f, err := strconv.ParseInt(fields[1], 10, 64)
+				if err != nil || f < 0 {
+					return wordList{}, fmt.Errorf("line %d: invalid frequency %q", lineNo, fields[1])
+				}
+				e.Frequency = f
+			}
+			if list.Words = append(list.Words, e); len(list.Words) > maxImportWords {
+				return wordList{}, fmt.Errorf("too many words (max %d)", maxImportWords)
+			}
+		}
+		if err := s.Err(); err != nil {
+			return wordList{}, err
+		}
+	} else {
+		if err := json.NewDecoder(body).Decode(&list); err != nil {
+			return wordList{}, fmt.Errorf("invalid JSON: %v", err)
+		}
+		if len(list.Words)+len(list.Lexemes) > maxImportWords {
+			return wordList{}, fmt.Errorf("too many words (max %d)", maxImportWords)
+		}
+		for i, e := range list.Words {
+			e.Word = strings.TrimSpace(e.Word)
+			if e.Word == "" || strings.ContainsFunc(e.Word, unicode.IsSpace) || e.Frequency < 0 {
+				return wordList{}, fmt.Errorf("words[%d]: invalid word %q", i, e.Word)
+			}
+			list.Words[i] = e
+		}
+		for i, lx := range list.Lexemes {
+			if strings.TrimSpace(lx.Lemma) == "" || len(lx.Forms) == 0 {
+				return wordList{}, fmt.Errorf("lexemes[%d]: lemma and forms are required", i)
+			}
+			if lx.Frequency < 0 {
+				return wordList{}, fmt.Errorf("lexemes[%d]: invalid frequency %d", i, lx.Frequency)
+			}
+		}
+	}
+	if len(list.Words) == 0 && len(list.Lexemes) == 0 {
+		return wordList{}, errors.New("no words")
+	}
+	return list, nil
+}
+
+// writeWordList выводит слова в текстовом формате импорта.
+// Текст не передает формы и теги, поэтому лексемы в него не попадают.
+func writeWordList(w io.Writer, entries []customdict.Entry) {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		if e.Frequency > 0 {
+			fmt.Fprintf(bw, "%s\t%d\n", e.Word, e.Frequency)
+		} else {
+			fmt.Fprintln(bw, e.Word)
+		}
+	}
+	bw.Flush()
+}
+
+// queryInt разбирает числовой параметр запроса; при ошибке возвращает -1.
+func queryInt(v string, def int) int {
+	if v == "" {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return -1
+	}
+	return i
+}
+
+// tenantOf определяет тенанта запроса: поле тела запроса, иначе заголовок X-Tenant-ID,
+// иначе параметр ?tenant=. Без них используется общий словарь.
+// Возвращает false, если идентификатор недопустим.
+func tenantOf(r *http.Request, field string) (string, bool) {
+	tenant := strings.TrimSpace(field)
+	if tenant == "" {
+		tenant = strings.TrimSpace(r.Header.Get("X-Tenant-ID"))
+	}
+	if tenant == "" {
+		tenant = strings.TrimSpace(r.URL.Query().Get("tenant"))
+	}
+	return tenant, sc.ValidTenant(tenant)
+}
+
+// authorOf возвращает автора изменения словаря: поле запроса, иначе заголовок X-User-ID.
+func authorOf(r *http.Request, field string) string {
+	if author := strings.TrimSpace(field); author != "" {
+		return author
+	}
+	return strings.TrimSpace(r.Header.Get("X-User-ID"))
+}
+
+// scopeOf определяет тенанта (см. tenantOf) и пользователя запроса: поле тела запроса,
+// иначе заголовок X-User-ID, иначе параметр ?user=. Допустимость проверяет вызывающий.
+func scopeOf(r *http.Request, tenantField, userField string) sc.Scope {
+	tenant, _ := tenantOf(r, tenantField)
+	user := strings.TrimSpace(userField)
+	if user == "" {
+		user = strings.TrimSpace(r.Header.Get("X-User-ID"))
+	}
+	if user == "" {
+		user = strings.TrimSpace(r.URL.Query().Get("user"))
+	}
+	return sc.Scope{Tenant: tenant, User: user}
+}
+
+// clientOf определяет, кто прислал запрос: значение заголовка TRUSTED_CLIENT_HEADER,
+// если сервис стоит за прокси, который аутентифицирует клиентов и сам выставляет
+// этот заголовок, иначе адрес клиента. В отличие от пользователя запроса (scopeOf)
+// клиент не выбирает это значение сам.
+func clientOf(r *http.Request) string {
+	if header := os.Getenv("TRUSTED_CLIENT_HEADER"); header != "" {
+		if client := strings.TrimSpace(r.Header.Get(header)); client != "" {
+			return client
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// renderParses представляет разборы в запрошенной системе тегов;
+// для формата словаря разборы отдаются как есть.
+func renderParses(tagset analyzer.TagSet, parses []*analyzer.Parsed) []interface{} {
+	out := make([]interface{}, len(parses))
+	for i, p := range parses {
+		if tagset == analyzer.NativeTagSet {
+			out[i] = p
+		} else {
+			out[i] = p.Render(tagset)
+		}
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// newCustomDictStore создает хранилище пользовательского словаря по CUSTOM_DICT_BACKEND:
+// redis (по умолчанию), memory или file (JSON-файл CUSTOM_DICT_PATH).
+func newCustomDictStore() (customdict.Store, error) {
+	switch backend := getenv("CUSTOM_DICT_BACKEND", "redis"); backend {
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     getenv("REDIS_ADDR", "localhost:6379"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       getEnvInt("REDIS_DB", 0),
+		})
+		return customdict.New(client), nil
+	case "memory":
+		return customdict.NewMemory(), nil
+	case "file":
+		return customdict.NewFile(getenv("CUSTOM_DICT_PATH", "custom_dict.json"))
+	default:
+		return nil, fmt.Errorf("unknown CUSTOM_DICT_BACKEND %q", backend)
+	}
 }
 
 func getenv(key, def string) string {
@@ -140,3 +1006,14 @@ func getEnvInt(key string, def int) int {
 	}
 	return def
 }
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"corrector/internal/analyzer"
+	sc "corrector/internal/corrector"
+	"corrector/internal/customdict"
+)
+
+// newTestServer поднимает API на тестовом частотном словаре корректора
+// и морфологическом словаре, собранном из ../internal/analyzer/testdata/lexemes.tsv.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	src, err := os.Open(filepath.Join("..", "internal", "analyzer", "testdata", "lexemes.tsv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	b := analyzer.NewBuilder()
+	if err := analyzer.ReadTSV(src, b); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dictPath := filepath.Join(t.TempDir(), "morph.dawg")
+	if err := os.WriteFile(dictPath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := sc.DefaultConfig()
+	cfg.MorphDictPath = dictPath
+	corrector, err := sc.NewSpellCorrector(cfg, filepath.Join("..", "internal", "corrector", "testdata", "freq.txt"), customdict.NewMemory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(newMux(cfg, corrector))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// post отправляет JSON-запрос и декодирует ответ в out.
+func post(t *testing.T, srv *httptest.Server, path string, req, out interface{}) int {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(srv.URL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("%s: ответ не JSON: %v", path, err)
+	}
+	return resp.StatusCode
+}
+
+func TestMorphInflectNumber(t *testing.T) {
+	srv := newTestServer(t)
+	tests := []struct {
+		number    int
+		grammemes []string
+		want      string
+	}{
+		{2, []string{"Дательный"}, "рублям"},
+		{1, []string{"Дательный"}, "рублю"},
+		{5, nil, "рублей"},
+		{22, nil, "рубля"},
+		{5, []string{"Творительный"}, "рублями"},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Form analyzer.Parsed `json:"form"`
+		}
+		req := map[string]interface{}{"word": "рубль", "number": tt.number, "grammemes": tt.grammemes}
+		if code := post(t, srv, "/api/v1/morph/inflect", req, &resp); code != http.StatusOK || resp.Form.Word != tt.want {
+			t.Errorf("рубль, %d, %v: %d %q, ожидается %q", tt.number, tt.grammemes, code, resp.Form.Word, tt.want)
+		}
+	}
+}
+
+func TestMorphParse(t *testing.T) {
+	srv := newTestServer(t)
+	var resp struct {
+		Results []struct {
+			Word   string                   `json:"word"`
+			Parses []analyzer.RenderedParse `json:"parses"`
+		} `json:"results"`
+	}
+	req := map[string]interface{}{"words": []string{"дома", "гомами"}, "tagset": "ud"}
+	if code := post(t, srv, "/api/v1/morph/parse", req, &resp); code != http.StatusOK || len(resp.Results) != 2 {
+		t.Fatalf("parse: %d %+v", code, resp)
+	}
+	found := false
+	for _, p := range resp.Results[0].Parses {
+		found = found || p.Lemma == "дом" && p.POS == "NOUN" && p.Tags == "Animacy=Inan|Case=Gen|Gender=Masc|Number=Sing"
+	}
+	if !found {
+		t.Errorf("нет разбора дома как дом в родительном падеже: %+v", resp.Results[0].Parses)
+	}
+	// Несловарное слово разбирается предсказателем
+	if ps := resp.Results[1].Parses; len(ps) == 0 || ps[0].Origin != analyzer.OriginPredicted || ps[0].Lemma != "гом" {
+		t.Errorf("разборы гомами: %+v", ps)
+	}
+
+	var e map[string]string
+	for _, tt := range []struct {
+		req  map[string]interface{}
+		code int
+	}{
+		{map[string]interface{}{"word": " "}, http.StatusBadRequest},
+		{map[string]interface{}{"word": "дом", "tagset": "xpos"}, http.StatusBadRequest},
+		{map[string]interface{}{"words": []string{"дом"}, "context": true}, http.StatusServiceUnavailable},
+	} {
+		if code := post(t, srv, "/api/v1/morph/parse", tt.req, &e); code != tt.code {
+			t.Errorf("parse %v: %d, ожидается %d", tt.req, code, tt.code)
+		}
+	}
+}
+
+func TestMorphLemmatize(t *testing.T) {
+	srv := newTestServer(t)
+	var resp struct {
+		Results []struct {
+			Text   string                `json:"text"`
+			Tokens []analyzer.LemmaToken `json:"tokens"`
+		} `json:"results"`
+	}
+	req := map[string]interface{}{"texts": []string{"Красивые дома", "шла"}}
+	if code := post(t, srv, "/api/v1/morph/lemmatize", req, &resp); code != http.StatusOK || len(resp.Results) != 2 {
+		t.Fatalf("lemmatize: %d %+v", code, resp)
+	}
+	var lemmas []string
+	for _, r := range resp.Results {
+		for _, tok := range r.Tokens {
+			lemmas = append(lemmas, tok.Lemma)
+		}
+	}
+	if want := []string{"красивый", "дом", "идти"}; !slices.Equal(lemmas, want) {
+		t.Errorf("леммы %q, ожидается %q", lemmas, want)
+	}
+	// Смещения в символах
+	if tok := resp.Results[0].Tokens[1]; tok.Token != "дома" || tok.Start != 9 || tok.End != 13 {
+		t.Errorf("токен %+v", tok)
+	}
+	var e map[string]string
+	if code := post(t, srv, "/api/v1/morph/lemmatize", map[string]interface{}{"text": ""}, &e); code != http.StatusBadRequest {
+		t.Errorf("пустой текст: %d", code)
+	}
+}
+
+func TestMorphInflect(t *testing.T) {
+	srv := newTestServer(t)
+
+	var form struct {
+		Form analyzer.Parsed `json:"form"`
+	}
+	req := map[string]interface{}{"word": "дом", "grammemes": []string{"Множественное число", "Родительный"}}
+	if code := post(t, srv, "/api/v1/morph/inflect", req, &form); code != http.StatusOK || form.Form.Word != "домов" {
+		t.Errorf("дом -> %d %q, ожидается домов", code, form.Form.Word)
+	}
+
+	// Без граммем и числа - вся парадигма (омонимичные формы по разу)
+	var paradigm struct {
+		Forms []analyzer.Parsed `json:"forms"`
+	}
+	if code := post(t, srv, "/api/v1/morph/inflect", map[string]interface{}{"word": "дом"}, &paradigm); code != http.StatusOK || len(paradigm.Forms) != 9 {
+		t.Errorf("парадигма дом: %d, %d форм", code, len(paradigm.Forms))
+	}
+
+	// В пакете ошибка возвращается для отдельного слова
+	var batchResp struct {
+		Results []struct {
+			Word  string          `json:"word"`
+			Form  analyzer.Parsed `json:"form"`
+			Error string          `json:"error"`
+		} `json:"results"`
+	}
+	req = map[string]interface{}{"words": []string{"рубль", "в"}, "grammemes": []string{"Дательный"}}
+	if code := post(t, srv, "/api/v1/morph/inflect", req, &batchResp); code != http.StatusOK || len(batchResp.Results) != 2 {
+		t.Fatalf("пакет: %d %+v", code, batchResp)
+	}
+	if r := batchResp.Results; r[0].Form.Word != "рублю" || r[1].Error != "form not found" {
+		t.Errorf("пакет: %+v", r)
+	}
+
+	var e map[string]string
+	if code := post(t, srv, "/api/v1/morph/inflect", map[string]interface{}{"word": "в", "grammemes": []string{"Дательный"}}, &e); code != http.StatusNotFound {
+		t.Errorf("нет формы: %d, ожидается 404", code)
+	}
+}
+
+func TestMorphInflectTagSet(t *testing.T) {
+	srv := newTestServer(t)
+	tests := []struct {
+		tagset    string
+		grammemes []string
+		want      string
+		pos       string
+	}{
+		{"opencorpora", []string{"plur", "gent"}, "домов", "NOUN"},
+		{"ud", []string{"Case=Dat|Number=Plur"}, "домам", "NOUN"},
+		{"ud", []string{"Number=Sing", "Case=Loc"}, "доме", "NOUN"},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Form analyzer.RenderedParse `json:"form"`
+		}
+		req := map[string]interface{}{"word": "дом", "tagset": tt.tagset, "grammemes": tt.grammemes}
+		if code := post(t, srv, "/api/v1/morph/inflect", req, &resp); code != http.StatusOK || resp.Form.Word != tt.want || resp.Form.POS != tt.pos {
+			t.Errorf("%s %v: %d %+v, ожидается %q", tt.tagset, tt.grammemes, code, resp.Form, tt.want)
+		}
+	}
+
+	// Число и падеж в UD: "двум рублям"
+	var resp struct {
+		Form analyzer.RenderedParse `json:"form"`
+	}
+	req := map[string]interface{}{"word": "рубль", "number": 2, "tagset": "ud", "grammemes": []string{"Case=Dat"}}
+	if code := post(t, srv, "/api/v1/morph/inflect", req, &resp); code != http.StatusOK || resp.Form.Word != "рублям" {
+		t.Errorf("рубль, 2, Case=Dat: %d %q", code, resp.Form.Word)
+	}
+
+	// Граммемы не из выбранной системы обозначений отклоняются
+	for _, req := range []map[string]interface{}{
+		{"word": "дом", "tagset": "ud", "grammemes": []string{"gent"}},
+		{"word": "дом", "tagset": "opencorpora", "grammemes": []string{"Case=Gen"}},
+		{"word": "дом", "grammemes": []string{"Родит"}},
+	} {
+		var e map[string]string
+		if code := post(t, srv, "/api/v1/morph/inflect", req, &e); code != http.StatusBadRequest || e["error"] == "" {
+			t.Errorf("%v: %d %v, ожидается 400", req, code, e)
+		}
+	}
+}
+
+// TestCustomWordsExportImport проверяет, что импорт выгрузки восстанавливает словарь
+// тенанта, в том числе лексемы с формами и тегами.
+func TestCustomWordsExportImport(t *testing.T) {
+	srv := newTestServer(t)
+	var resp map[string]interface{}
+	lexeme := map[string]interface{}{
+		"word":   "вайбить",
+		"tenant": "acme",
+		"forms": []customdict.Form{
+			{Word: "вайбить", Tags: "INFN,impf,intr"},
+			{Word: "Вайбила", Tags: "VERB,impf,intr,femn,sing,past,indc"},
+		},
+	}
+	if code := post(t, srv, "/api/v1/custom-word", lexeme, &resp); code != http.StatusCreated {
+		t.Fatalf("лексема: %d %v", code, resp)
+	}
+	word := map[string]interface{}{"word": "зиплайн", "tenant": "acme", "frequency": 500}
+	if code := post(t, srv, "/api/v1/custom-word", word, &resp); code != http.StatusCreated {
+		t.Fatalf("слово: %d %v", code, resp)
+	}
+
+	export := func(srv *httptest.Server, format string) []byte {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/api/v1/custom-words/export?tenant=acme&format=" + format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(resp.Body); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("выгрузка %s: %d %v", format, resp.StatusCode, err)
+		}
+		return buf.Bytes()
+	}
+	body := export(srv, "json")
+	var list wordList
+	if err := json.Unmarshal(body, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Words) != 1 || list.Words[0].Word != "зиплайн" || list.Words[0].Frequency != 500 {
+		t.Errorf("слова выгрузки: %+v", list.Words)
+	}
+	if len(list.Lexemes) != 1 || len(list.Lexemes[0].Forms) != 2 || list.Lexemes[0].Forms[1].Word != "вайбила" || list.Lexemes[0].Forms[1].Tags == "" {
+		t.Errorf("лексемы выгрузки: %+v", list.Lexemes)
+	}
+	// В тексте лексем нет
+	if text := string(export(srv, "text")); text != "зиплайн\t500\n" {
+		t.Errorf("текстовая выгрузка: %q", text)
+	}
+
+	// Импорт выгрузки в пустой словарь восстанавливает его
+	other := newTestServer(t)
+	r, err := http.Post(other.URL+"/api/v1/custom-words?tenant=acme", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		t.Fatalf("импорт: %d", r.StatusCode)
+	}
+	var restored wordList
+	if err := json.Unmarshal(export(other, "json"), &restored); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.EqualFunc(restored.Lexemes, list.Lexemes, func(a, b customdict.Lexeme) bool {
+		return a.Lemma == b.Lemma && slices.Equal(a.Forms, b.Forms)
+	}) {
+		t.Errorf("лексемы после импорта: %+v, ожидается %+v", restored.Lexemes, list.Lexemes)
+	}
+	if len(restored.Words) != 1 || restored.Words[0].Word != "зиплайн" || restored.Words[0].Frequency != 500 {
+		t.Errorf("слова после импорта: %+v", restored.Words)
+	}
+
+	// Лексема без форм отклоняется до записи
+	bad := map[string]interface{}{"lexemes": []customdict.Lexeme{{Lemma: "вайбить"}}}
+	if code := post(t, other, "/api/v1/custom-words", bad, &resp); code != http.StatusBadRequest {
+		t.Errorf("лексема без форм: %d", code)
+	}
+}
+
+// TestCustomWordLexemeMetadata проверяет, что лексема сохраняет частоту и метаданные запроса.
+func TestCustomWordLexemeMetadata(t *testing.T) {
+	srv := newTestServer(t)
+	var resp struct {
+		Lexeme customdict.Lexeme `json:"lexeme"`
+	}
+	req := map[string]interface{}{"word": "сбербанк", "like": "банк", "frequency": 700, "note": "бренд", "author": "alice"}
+	if code := post(t, srv, "/api/v1/custom-word", req, &resp); code != http.StatusCreated {
+		t.Fatalf("лексема: %d", code)
+	}
+	if lx := resp.Lexeme; len(lx.Forms) < 2 || lx.Frequency != 700 || lx.Note != "бренд" || lx.Author != "alice" {
+		t.Errorf("лексема: %+v", lx)
+	}
+	var e map[string]string
+	req = map[string]interface{}{"word": "сбербанк", "inflect": true, "frequency": -1}
+	if code := post(t, srv, "/api/v1/custom-word", req, &e); code != http.StatusBadRequest {
+		t.Errorf("отрицательная частота лексемы: %d", code)
+	}
+}
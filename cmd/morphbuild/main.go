@@ -0,0 +1,96 @@
+// Command morphbuild компилирует морфологический словарь morph.dawg
+// из XML-выгрузки OpenCorpora (dict.opcorpora.xml) или из простого TSV-файла лексем.
+//
+// Формат TSV: лексемы разделяются пустой строкой, каждая строка - словоформа
+// и ее теги через табуляцию; первая строка лексемы - лемма. Теги можно
+// записывать как в формате словаря ("Существительное,Мужской,...") так и
+// кодами OpenCorpora ("NOUN,inan,masc,sing,nomn"). Строки, начинающиеся с '#', пропускаются.
+//
+//	дом	NOUN,inan,masc,sing,nomn
+//	дома	NOUN,inan,masc,sing,gent
+//	...
+//
+// Использование:
+//
+//	morphbuild -in dict.opcorpora.xml -out internal/analyzer/morph.dawg
+//
+// С флагом -verify команда ничего не собирает, а полностью проверяет готовый словарь:
+// контрольную сумму (при загрузке она по умолчанию не проверяется) и ссылки между секциями.
+//
+//	morphbuild -verify internal/analyzer/morph.dawg
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"corrector/internal/analyzer"
+)
+
+func main() {
+	in := flag.String("in", "", "входной словарь: OpenCorpora XML или TSV")
+	out := flag.String("out", "morph.dawg", "путь к выходному файлу")
+	format := flag.String("format", "", "формат входа: xml или tsv (по умолчанию - по расширению файла)")
+	maxSuffix := flag.Int("max-suffix", 5, "максимальная длина суффикса в правилах предсказателя")
+	minFreq := flag.Int("min-freq", 1, "минимальная частота правила предсказателя")
+	maxRules := flag.Int("max-rules", 16, "сколько правил хранить для одного суффикса")
+	verify := flag.String("verify", "", "проверить готовый словарь вместо сборки")
+	flag.Parse()
+
+	if *verify != "" {
+		if err := analyzer.VerifyDictionary(*verify); err != nil {
+			log.Fatalf("ошибка проверки словаря: %v", err)
+		}
+		log.Printf("словарь %s цел", *verify)
+		return
+	}
+
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = "tsv"
+		if strings.EqualFold(filepath.Ext(*in), ".xml") {
+			*format = "xml"
+		}
+	}
+
+	f, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("ошибка открытия словаря: %v", err)
+	}
+	defer f.Close()
+
+	b := analyzer.NewBuilder()
+	b.MaxSuffixLen = *maxSuffix
+	b.MinPredictFrequency = *minFreq
+	b.MaxPredictPayloads = *maxRules
+
+	switch *format {
+	case "xml":
+		err = analyzer.ReadOpenCorpora(f, b)
+	case "tsv":
+		err = analyzer.ReadTSV(f, b)
+	default:
+		err = fmt.Errorf("неизвестный формат %q", *format)
+	}
+	if err != nil {
+		log.Fatalf("ошибка чтения словаря: %v", err)
+	}
+	log.Printf("прочитано лексем: %d", b.Len())
+
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		log.Fatalf("ошибка компиляции словаря: %v", err)
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		log.Fatalf("ошибка записи словаря: %v", err)
+	}
+	log.Printf("словарь записан в %s (%d байт)", *out, buf.Len())
+}
@@ -0,0 +1,151 @@
+// Command tune подбирает параметры скоринга корректора (BetaWeight, LambdaPenalty,
+// GammaMorph, FreqTemperature, MarginThreshold, TauInVocab, TauOutVocab) на размеченном
+// корпусе (формат - см. internal/eval): покоординатным спуском по сетке, начиная
+// с текущих значений или с лучшей из случайных точек. Лучшие параметры записываются
+// в профиль, который сервер загружает из CORRECTOR_PROFILE; отчет содержит метрики
+// до и после подбора и чувствительность цели к каждому параметру. Если ни одна
+// проверенная точка не укладывается в бюджет ложных исправлений (-max-fp), профиль
+// не записывается: отчет выводится, а команда завершается с ошибкой.
+//
+// Использование:
+//
+//	tune -corpus gold.tsv -dict ru.txt -max-fp 0.01 -out profile.json -report tune.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+
+	sc "corrector/internal/corrector"
+	"corrector/internal/eval"
+	"corrector/internal/tune"
+)
+
+func main() {
+	corpus := flag.String("corpus", "", "размеченный корпус: исходное<TAB>правильное[<TAB>тип]")
+	dict := flag.String("dict", "ru.txt", "частотный словарь")
+	morph := flag.String("morph", "", "словарь morph.dawg (по умолчанию - как у сервера)")
+	errModel := flag.String("error-model", "", "модель ошибок (см. errtrain)")
+	profile := flag.String("profile", "", "начальный профиль (по умолчанию - настройки сервера)")
+	metric := flag.String("metric", "f0_5", "оптимизируемая метрика исправления: f0_5, precision или recall")
+	maxFP := flag.Float64("max-fp", 0.01, "бюджет доли ложных исправлений в чистых предложениях (0 - без ограничения)")
+	params := flag.String("params", "", "параметры через запятую (по умолчанию - все)")
+	rounds := flag.Int("rounds", 5, "наибольшее число кругов покоординатного спуска")
+	random := flag.Int("random", 0, "сколько случайных точек проверить перед спуском")
+	seed := flag.Int64("seed", 1, "зерно случайных точек")
+	out := flag.String("out", "profile.json", "файл профиля с лучшими параметрами")
+	report := flag.String("report", "", "файл отчета о подборе (по умолчанию - стандартный вывод)")
+	flag.Parse()
+
+	if *corpus == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	objective := tune.Objective{Metric: *metric, MaxFalsePositive: *maxFP}
+	if err := objective.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	samples, err := eval.ReadCorpusFile(*corpus)
+	if err != nil {
+		log.Fatalf("ошибка чтения корпуса: %v", err)
+	}
+
+	cfg := sc.DefaultConfig()
+	cfg.MorphDictPath = *morph
+	cfg.ErrorModelPath = *errModel
+	if *profile != "" {
+		if err := sc.LoadProfile(*profile, &cfg); err != nil {
+			log.Fatalf("ошибка загрузки профиля: %v", err)
+		}
+	}
+	corrector, err := sc.NewSpellCorrector(cfg, *dict, nil)
+	if err != nil {
+		log.Fatalf("init error: %v", err)
+	}
+
+	t := &tune.Tuner{
+		Params:    selectParams(*params),
+		Objective: objective,
+		Evaluate: func(s sc.Scoring) *eval.Report {
+			corrector.SetScoring(s)
+			return eval.Evaluate(func(text string) string {
+				return corrector.CorrectText(text, false).Corrected
+			}, samples, eval.Options{})
+		},
+		Log: log.Printf,
+	}
+	baseline := t.RandomStart(cfg.Scoring(), 0, nil)
+	log.Printf("исходные параметры: %.4f", baseline.Objective)
+	start := baseline
+	if *random > 0 {
+		start = t.RandomStart(cfg.Scoring(), *random, rand.New(rand.NewSource(*seed)))
+	}
+	best := t.CoordinateDescent(start, *rounds)
+	sensitivity := t.Sensitivity(best)
+
+	type result struct {
+		Scoring   sc.Scoring   `json:"scoring"`
+		Objective float64      `json:"objective"`
+		Feasible  bool         `json:"feasible"`
+		Report    *eval.Report `json:"report"`
+	}
+	data, err := json.MarshalIndent(struct {
+		Metric           string                      `json:"metric"`
+		MaxFalsePositive float64                     `json:"max_false_positive"`
+		Evaluations      int                         `json:"evaluations"`
+		Baseline         result                      `json:"baseline"`
+		Best             result                      `json:"best"`
+		Sensitivity      map[string]tune.Sensitivity `json:"sensitivity"`
+	}{
+		Metric:           *metric,
+		MaxFalsePositive: *maxFP,
+		Evaluations:      t.Evaluations(),
+		Baseline:         result{baseline.Scoring, baseline.Objective, baseline.Feasible, baseline.Report},
+		Best:             result{best.Scoring, best.Objective, best.Feasible, best.Report},
+		Sensitivity:      sensitivity,
+	}, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *report == "" {
+		os.Stdout.Write(append(data, '\n'))
+	} else if err := os.WriteFile(*report, append(data, '\n'), 0o644); err != nil {
+		log.Fatalf("ошибка записи отчета: %v", err)
+	}
+
+	if !best.Feasible {
+		log.Fatalf("ни одна из %d точек не укладывается в бюджет ложных исправлений %g (у лучшей точки: %.4f); профиль не записан",
+			t.Evaluations(), *maxFP, best.Report.Clean.FalsePositive)
+	}
+	if err := sc.SaveProfile(*out, best.Scoring); err != nil {
+		log.Fatalf("ошибка записи профиля: %v", err)
+	}
+	log.Printf("лучшие параметры: %.4f (прогонов корпуса: %d), профиль записан в %s",
+		best.Objective, t.Evaluations(), *out)
+}
+
+// selectParams возвращает параметры из списка names (все, если список пуст).
+func selectParams(names string) []tune.Param {
+	all := tune.DefaultParams()
+	if names == "" {
+		return all
+	}
+	var out []tune.Param
+	for _, name := range strings.Split(names, ",") {
+		found := false
+		for _, p := range all {
+			if p.Name == strings.TrimSpace(name) {
+				out = append(out, p)
+				found = true
+			}
+		}
+		if !found {
+			log.Fatalf("неизвестный параметр %q", name)
+		}
+	}
+	return out
+}
@@ -1,5 +1,5 @@
 // Этот файл содержит логику морфологического анализатора.
-// Он загружает скомпилированный бинарный словарь (morph_3.dawg) и предоставляет
+// Он загружает скомпилированный бинарный словарь (morph.dawg) и предоставляет
 // API для разбора, склонения и предсказания несловарных слов.
 // Ключевая особенность - использование mmap для Zero-Copy загрузки, что минимизирует
 // потребление ОЗУ.
@@ -8,18 +8,18 @@ package analyzer
 import (
 	"bytes"
 	"compress/gzip"
-	"encoding/binary"
 	"encoding/gob"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"reflect"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/edsrzf/mmap-go"
@@ -28,7 +28,13 @@ import (
 // --- ПЕРЕМЕННЫЕ ОКРУЖЕНИЯ ---
 
 // EnvDictPath - имя переменной окружения для переопределения пути к словарю.
-const EnvDictPath = "C:\\Users\\Арсений\\Desktop\\T1\\Corrector_5 (with morphy)\\internal\\analyzer\\morph.dawg"
+const EnvDictPath = "MORPH_DICT_PATH"
+
+// EnvDictVerify - имя переменной окружения, включающей проверку контрольной суммы
+// словаря при загрузке ("1" или "true"). Проверка читает весь файл и лишает mmap
+// ленивой подгрузки страниц, поэтому по умолчанию выключена; словарь можно проверить
+// отдельно командой `morphbuild -verify` (см. VerifyDictionary).
+const EnvDictVerify = "MORPH_DICT_VERIFY"
 
 // --- СТРУКТУРЫ ДАННЫХ ---
 
@@ -76,24 +82,37 @@ type ParadigmInfo struct {
 	NodeID uint32 // ID узла в "плоском" DAWG, где эта основа заканчивается.
 }
 
-// Header - Заголовок бинарного файла morph_3.dawg.
+// Header - Заголовок бинарного файла morph.dawg.
 // Это "карта" всего файла, которая позволяет анализатору загружать данные методом Zero-Copy.
 type Header struct {
-	Magic                 [4]byte // Сигнатура "DAW7" для проверки корректности файла.
-	ComplexDataOffset     int64   // Смещение до блока "сложных" данных (в байтах).
-	ComplexDataLength     int64   // Длина этого блока (в байтах).
-	NodesOffset           int64   // Смещение до массива узлов основного словаря.
-	NodesCount            int64   // Количество элементов в этом массиве.
-	EdgesOffset           int64   // Смещение до массива ребер основного словаря.
-	EdgesCount            int64   // Количество элементов.
-	PayloadsOffset        int64   // Смещение до массива payload-ов основного словаря.
-	PayloadsCount         int64   // Количество элементов.
-	PredictNodesOffset    int64   // Смещение до массива узлов предсказателя.
-	PredictNodesCount     int64   // Количество элементов.
-	PredictEdgesOffset    int64   // Смещение до массива ребер предсказателя.
-	PredictEdgesCount     int64   // Количество элементов.
-	PredictPayloadsOffset int64   // Смещение до массива payload-ов предсказателя.
-	PredictPayloadsCount  int64   // Количество элементов.
+	Magic    [4]byte // Сигнатура "DAWG" для проверки корректности файла.
+	Version  uint32  // Версия формата, см. formatVersion.
+	Checksum uint32  // CRC-32 (Castagnoli) всех байт файла, следующих за заголовком (см. VerifyDictionary).
+	Sections
+}
+
+// legacyHeader - заголовок словарей старого формата DAW7: без версии и контрольной суммы.
+type legacyHeader struct {
+	Magic [4]byte // Сигнатура "DAW7".
+	Sections
+}
+
+// Sections - смещения и размеры секций файла.
+type Sections struct {
+	ComplexDataOffset     int64 // Смещение до блока "сложных" данных (в байтах).
+	ComplexDataLength     int64 // Длина этого блока (в байтах).
+	NodesOffset           int64 // Смещение до массива узлов основного словаря.
+	NodesCount            int64 // Количество элементов в этом массиве.
+	EdgesOffset           int64 // Смещение до массива ребер основного словаря.
+	EdgesCount            int64 // Количество элементов.
+	PayloadsOffset        int64 // Смещение до массива payload-ов основного словаря.
+	PayloadsCount         int64 // Количество элементов.
+	PredictNodesOffset    int64 // Смещение до массива узлов предсказателя.
+	PredictNodesCount     int64 // Количество элементов.
+	PredictEdgesOffset    int64 // Смещение до массива ребер предсказателя.
+	PredictEdgesCount     int64 // Количество элементов.
+	PredictPayloadsOffset int64 // Смещение до массива payload-ов предсказателя.
+	PredictPayloadsCount  int64 // Количество элементов.
 }
 
 // ComplexData - Контейнер для всех данных, которые неэффективно хранить в "сыром" виде.
@@ -110,6 +129,7 @@ type MorphAnalyzer struct {
 	// Данные словаря.
 	LemmaPool         []string                  // Пул всех лемм.
 	tagsPool          []string                  // Пул всех наборов тегов.
+	tags              []Tag                     // Разобранные теги пула (tags[i] соответствует tagsPool[i]).
 	paradigms         map[uint32][]ParadigmInfo // Информация о парадигмах.
 	paradigmToLemmaID map[uint32]uint32         // Карта для быстрого поиска леммы по ID парадигмы.
 
@@ -123,6 +143,12 @@ type MorphAnalyzer struct {
 	predictEdges    []FlatEdge    // Ребра DAWG предсказателя.
 	predictPayloads []PredictInfo // Полезная нагрузка DAWG предсказателя.
 
+	// Пользовательский лексикон, который просматривается раньше словаря (см. overlay.go).
+	overlay overlay
+
+	// Статистика тегов по корпусу для оценки вероятностей разборов (см. SetTagStats).
+	stats atomic.Pointer[TagStats]
+
 	// Ссылка на mmap-объект, чтобы он не был собран сборщиком мусора
 	// и память оставалась доступной.
 	mmapFile mmap.MMap
@@ -138,30 +164,71 @@ type PredictionCandidate struct {
 // --- ЛОГИКА АНАЛИЗАТОРА ---
 
 // LoadMorphAnalyzer - конструктор анализатора.
+// Словарь ищется в следующем порядке: путь из переменной окружения EnvDictPath,
+// словарь, встроенный в бинарник (сборка с тегом `morphembed`), файл morph.dawg
+// рядом с исполняемым файлом и, наконец, файл morph.dawg рядом с исходниками пакета.
 func LoadMorphAnalyzer() (*MorphAnalyzer, error) {
-	dictPath := os.Getenv(EnvDictPath)
-	if dictPath != "" {
-		return loadInternal(dictPath)
-	}
+	return loadDefault(os.Getenv(EnvDictPath), embeddedDict, defaultDictPaths())
+}
 
-	_, currentFilePath, _, ok := runtime.Caller(0)
-	if !ok {
-		return nil, errors.New("не удалось определить путь к пакету gomorphy")
+// defaultDictPaths возвращает пути, по которым словарь ищется, если он не задан
+// переменной окружения и не встроен: рядом с исполняемым файлом и рядом с исходниками пакета.
+func defaultDictPaths() []string {
+	var paths []string
+	if exe, err := os.Executable(); err == nil {
+		paths = append(paths, filepath.Join(filepath.Dir(exe), "morph.dawg"))
 	}
+	if _, currentFilePath, _, ok := runtime.Caller(0); ok {
+		paths = append(paths, filepath.Join(filepath.Dir(currentFilePath), "morph.dawg"))
+	}
+	return paths
+}
 
-	packageDir := filepath.Dir(currentFilePath)
-	dictPath = filepath.Join(packageDir, "morph.dawg")
+// loadDefault выбирает словарь по правилам LoadMorphAnalyzer: путь envPath,
+// встроенный образ embedded, первый существующий файл из paths.
+func loadDefault(envPath string, embedded []byte, paths []string) (*MorphAnalyzer, error) {
+	if envPath != "" {
+		return LoadMorphAnalyzerFrom(envPath)
+	}
+	if len(embedded) > 0 {
+		return LoadMorphAnalyzerFromBytes(embedded)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return LoadMorphAnalyzerFrom(path)
+		}
+	}
+	return nil, fmt.Errorf(
+		"словарь не найден (пути: %s). "+
+			"Убедитесь, что файл 'morph.dawg' присутствует, соберите бинарник с тегом morphembed "+
+			"либо установите переменную окружения %s",
+		strings.Join(paths, ", "), EnvDictPath,
+	)
+}
 
-	if _, err := os.Stat(dictPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf(
-			"словарь не найден по вычисленному пути '%s'. "+
-				"Убедитесь, что библиотека установлена корректно и файл 'morph.dawg' присутствует. "+
-				"Либо установите переменную окружения %s",
-			dictPath, EnvDictPath,
-		)
+// LoadMorphAnalyzerFrom загружает анализатор из словаря по явно указанному пути.
+func LoadMorphAnalyzerFrom(path string) (*MorphAnalyzer, error) {
+	if path == "" {
+		return nil, errors.New("не указан путь к словарю morph.dawg")
+	}
+	a, err := loadInternal(path)
+	if err != nil {
+		return nil, fmt.Errorf("словарь '%s': %w", path, err)
 	}
+	return a, nil
+}
 
-	return loadInternal(dictPath)
+// LoadMorphAnalyzerFromBytes создает анализатор из образа словаря в памяти
+// (например, встроенного через go:embed). Срез не должен изменяться после вызова.
+func LoadMorphAnalyzerFromBytes(data []byte) (*MorphAnalyzer, error) {
+	// Массивы словаря читаются напрямую из `data`, поэтому начало образа
+	// должно быть выровнено так же, как страница mmap. Иначе копируем образ в выровненный буфер.
+	if len(data) > 0 && uintptr(unsafe.Pointer(&data[0]))%sectionAlign != 0 {
+		aligned := sliceBytes(make([]uint64, (len(data)+7)/8))[:len(data)]
+		copy(aligned, data)
+		data = aligned
+	}
+	return newAnalyzer(data)
 }
 
 // loadInternal Загружает бинарный словарь, читает его заголовок, декодирует "сложную" часть
@@ -174,6 +241,15 @@ func loadInternal(filepath string) (*MorphAnalyzer, error) {
 	}
 	defer file.Close()
 
+	// Пустой или обрезанный файл отсекаем до mmap: отображать нечего.
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %w", err)
+	}
+	if info.Size() < int64(legacyHeaderSize) {
+		return nil, fmt.Errorf("%w: файл слишком мал для заголовка (%d байт)", ErrCorruptDictionary, info.Size())
+	}
+
 	// 2. Отображаем весь файл в виртуальное адресное пространство процесса.
 	// Это самая важная операция: файл не копируется в ОЗУ, ОС сама подгружает
 	// нужные страницы по мере обращения к ним.
@@ -182,64 +258,89 @@ func loadInternal(filepath string) (*MorphAnalyzer, error) {
 		return nil, fmt.Errorf("ошибка mmap.Map: %w", err)
 	}
 
-	// 3. Читаем заголовок (карту файла) прямо из mmap-среза.
-	var header Header
-	headerSize := int(unsafe.Sizeof(header))
-	if len(mmapFile) < headerSize {
+	analyzer, err := newAnalyzer(mmapFile)
+	if err != nil {
 		_ = mmapFile.Unmap()
-		return nil, fmt.Errorf("файл слишком мал для заголовка")
+		return nil, err
 	}
-	if err := binary.Read(bytes.NewReader(mmapFile[:headerSize]), binary.LittleEndian, &header); err != nil {
-		_ = mmapFile.Unmap()
-		return nil, fmt.Errorf("ошибка чтения заголовка: %w", err)
+	analyzer.mmapFile = mmapFile
+	return analyzer, nil
+}
+
+// newAnalyzer разбирает образ словаря, уже находящийся в памяти.
+// Каждая секция проверяется на выход за границы файла и выравнивание,
+// а графы и индексы пулов - на целостность, так что поврежденный файл
+// приводит к ошибке, а не к панике при первом обращении.
+func newAnalyzer(data []byte) (*MorphAnalyzer, error) {
+	// 3. Читаем заголовок (карту файла).
+	sections, err := readHeader(data)
+	if err != nil {
+		return nil, err
 	}
-	if string(header.Magic[:]) != "DAW7" {
-		_ = mmapFile.Unmap()
-		return nil, fmt.Errorf("неверная сигнатура файла")
+	if verifyOnLoad() {
+		if err := verifyChecksum(data); err != nil {
+			return nil, err
+		}
 	}
 
 	// 4. Декодируем "сложный" блок (строки, карты) с помощью gob.
-	complexStart := header.ComplexDataOffset
-	complexEnd := complexStart + header.ComplexDataLength
-	compressedBlock := mmapFile[complexStart:complexEnd]
+	compressedBlock, err := byteSection(data, "complex", sections.ComplexDataOffset, sections.ComplexDataLength)
+	if err != nil {
+		return nil, err
+	}
 
 	// 4.1. Распаковываем блок в памяти
 	gzipReader, err := gzip.NewReader(bytes.NewReader(compressedBlock))
 	if err != nil {
-		_ = mmapFile.Unmap()
-		return nil, fmt.Errorf("ошибка создания gzip.Reader: %w", err)
+		return nil, fmt.Errorf("%w: ошибка создания gzip.Reader: %v", ErrCorruptDictionary, err)
 	}
 
 	decompressedBytes, err := io.ReadAll(gzipReader)
 	if err != nil {
-		_ = mmapFile.Unmap()
-		return nil, fmt.Errorf("ошибка распаковки данных: %w", err)
+		return nil, fmt.Errorf("%w: ошибка распаковки данных: %v", ErrCorruptDictionary, err)
 	}
 	if err := gzipReader.Close(); err != nil {
-		_ = mmapFile.Unmap()
-		return nil, fmt.Errorf("ошибка закрытия gzip.Reader: %w", err)
+		return nil, fmt.Errorf("%w: ошибка закрытия gzip.Reader: %v", ErrCorruptDictionary, err)
 	}
 
 	// 4.2 Декодируем РАСПАКОВАННЫЕ байты с помощью gob
 	var complexData ComplexData
 	if err := gob.NewDecoder(bytes.NewReader(decompressedBytes)).Decode(&complexData); err != nil {
-		_ = mmapFile.Unmap()
-		return nil, fmt.Errorf("ошибка gob-декодирования: %w", err)
+		return nil, fmt.Errorf("%w: ошибка gob-декодирования: %v", ErrCorruptDictionary, err)
 	}
 
 	// 5. Создаем "виртуальные" срезы, используя `bytesToSlice`.
-	// Эти срезы не владеют данными, а лишь указывают на нужные участки mmap-файла.
-	nodes := bytesToSlice[FlatNode](mmapFile[header.NodesOffset : header.NodesOffset+header.NodesCount*int64(unsafe.Sizeof(FlatNode{}))])
-	edges := bytesToSlice[FlatEdge](mmapFile[header.EdgesOffset : header.EdgesOffset+header.EdgesCount*int64(unsafe.Sizeof(FlatEdge{}))])
-	payloads := bytesToSlice[MorphInfo](mmapFile[header.PayloadsOffset : header.PayloadsOffset+header.PayloadsCount*int64(unsafe.Sizeof(MorphInfo{}))])
-	predictNodes := bytesToSlice[FlatNode](mmapFile[header.PredictNodesOffset : header.PredictNodesOffset+header.PredictNodesCount*int64(unsafe.Sizeof(FlatNode{}))])
-	predictEdges := bytesToSlice[FlatEdge](mmapFile[header.PredictEdgesOffset : header.PredictEdgesOffset+header.PredictEdgesCount*int64(unsafe.Sizeof(FlatEdge{}))])
-	predictPayloads := bytesToSlice[PredictInfo](mmapFile[header.PredictPayloadsOffset : header.PredictPayloadsOffset+header.PredictPayloadsCount*int64(unsafe.Sizeof(PredictInfo{}))])
-
-	// 6. Инициализируем и возвращаем готовый к работе анализатор.
+	// Эти срезы не владеют данными, а лишь указывают на нужные участки файла.
+	nodes, err := section[FlatNode](data, "nodes", sections.NodesOffset, sections.NodesCount)
+	if err != nil {
+		return nil, err
+	}
+	edges, err := section[FlatEdge](data, "edges", sections.EdgesOffset, sections.EdgesCount)
+	if err != nil {
+		return nil, err
+	}
+	payloads, err := section[MorphInfo](data, "payloads", sections.PayloadsOffset, sections.PayloadsCount)
+	if err != nil {
+		return nil, err
+	}
+	predictNodes, err := section[FlatNode](data, "predict nodes", sections.PredictNodesOffset, sections.PredictNodesCount)
+	if err != nil {
+		return nil, err
+	}
+	predictEdges, err := section[FlatEdge](data, "predict edges", sections.PredictEdgesOffset, sections.PredictEdgesCount)
+	if err != nil {
+		return nil, err
+	}
+	predictPayloads, err := section[PredictInfo](data, "predict payloads", sections.PredictPayloadsOffset, sections.PredictPayloadsCount)
+	if err != nil {
+		return nil, err
+	}
+
+	// 6. Инициализируем анализатор и проверяем целостность ссылок между секциями.
 	analyzer := &MorphAnalyzer{
 		LemmaPool:         complexData.LemmaPool,
 		tagsPool:          complexData.TagsPool,
+		tags:              decodeTags(complexData.TagsPool),
 		paradigms:         complexData.Paradigms,
 		paradigmToLemmaID: complexData.ParadigmToLemmaID,
 		nodes:             nodes,
@@ -248,22 +349,47 @@ func loadInternal(filepath string) (*MorphAnalyzer, error) {
 		predictNodes:      predictNodes,
 		predictEdges:      predictEdges,
 		predictPayloads:   predictPayloads,
-		mmapFile:          mmapFile,
 	}
-
+	if err := analyzer.validate(); err != nil {
+		return nil, err
+	}
 	return analyzer, nil
 }
 
-// bytesToSlice - "небезопасная" функция, которая создает заголовок среза,
-// указывающий на область байт, без копирования самих данных.
-func bytesToSlice[T any](b []byte) []T {
-	if len(b) == 0 {
+// verifyOnLoad сообщает, что переменная EnvDictVerify требует проверять контрольную сумму при загрузке.
+func verifyOnLoad() bool {
+	v, _ := strconv.ParseBool(os.Getenv(EnvDictVerify))
+	return v
+}
+
+// VerifyDictionary полностью проверяет файл словаря: контрольную сумму всех секций
+// и ссылки между ними. В отличие от загрузки, контрольная сумма проверяется всегда.
+func VerifyDictionary(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := verifyChecksum(data); err != nil {
+		return fmt.Errorf("словарь '%s': %w", path, err)
+	}
+	a, err := LoadMorphAnalyzerFromBytes(data)
+	if err != nil {
+		return fmt.Errorf("словарь '%s': %w", path, err)
+	}
+	return a.Close()
+}
+
+// Close освобождает отображенный в память файл словаря.
+// После вызова Close анализатором пользоваться нельзя.
+func (a *MorphAnalyzer) Close() error {
+	a.nodes, a.edges, a.payloads, a.tags = nil, nil, nil, nil
+	a.predictNodes, a.predictEdges, a.predictPayloads = nil, nil, nil
+	if a.mmapFile == nil {
 		return nil
 	}
-	var t T
-	size := int(unsafe.Sizeof(t))
-	header := reflect.SliceHeader{Data: uintptr(unsafe.Pointer(&b[0])), Len: len(b) / size, Cap: len(b) / size}
-	return *(*[]T)(unsafe.Pointer(&header))
+	err := a.mmapFile.Unmap()
+	a.mmapFile = nil
+	return err
 }
 
 // Analyze - главный публичный метод. Принимает слово и возвращает полный его разбор.
@@ -287,7 +413,7 @@ func (a *MorphAnalyzer) Analyze(word string) ([]*Parsed, []*Parsed) {
 	return predictedParses, predictedForms
 }
 
-// Inflect генерирует все словоформы для словарного слова.
+// Inflect генерирует все словоформы для словарного слова или слова пользовательского лексикона.
 func (a *MorphAnalyzer) Inflect(word string) []*Parsed {
 	// Находим все возможные разборы для введенного слова.
 	initialParses := a.Parse(word)
@@ -322,6 +448,15 @@ func (a *MorphAnalyzer) Inflect(word string) []*Parsed {
 	// Генерируем все формы для каждой найденной уникальной парадигмы.
 	finalResults := make(map[string]*Parsed) // Используем карту для уникальности результатов.
 
+	// Формы пользовательских лексем добавляются первыми и имеют приоритет над словарными.
+	for _, lf := range a.overlay.lexemesOf(lowerWord) {
+		for _, f := range lf.forms {
+			if _, exists := finalResults[f.Word]; !exists {
+				finalResults[f.Word] = f
+			}
+		}
+	}
+
 	for pID, lemma := range paradigmsToProcess {
 		// Получаем ВСЕ основы (stems) для данной парадигмы.
 		paradigmInfoSlice, ok := a.paradigms[pID]
@@ -337,7 +472,7 @@ func (a *MorphAnalyzer) Inflect(word string) []*Parsed {
 			for form, tagsID := range generatedForms {
 				// Добавляем в итоговую карту.
 				if _, exists := finalResults[form]; !exists {
-					finalResults[form] = newParsed(form, lemma, a.tagsPool[tagsID])
+					finalResults[form] = newParsedTag(form, lemma, &a.tags[tagsID], OriginDictionary)
 				}
 			}
 		}
@@ -360,9 +495,33 @@ func (a *MorphAnalyzer) Inflect(word string) []*Parsed {
 	return finalList
 }
 
-// Parse ищет слово в основном словаре (DAWG).
+// Parse ищет слово в пользовательском лексиконе и в основном словаре (DAWG).
+// Разборы отсортированы по убыванию Score - оценки P(теги | слово)
+// по статистике корпуса (см. SetTagStats); без статистики оценки равны.
+// Разборы пользовательского лексикона идут первыми: каждому из них
+// дается такой же вес, как всем словарным разборам вместе.
 func (a *MorphAnalyzer) Parse(word string) []*Parsed {
 	lowerWord := strings.ToLower(word)
+	user := a.overlay.parse(word, lowerWord)
+	dict := a.parseDictionary(word, lowerWord)
+	if len(user) == 0 {
+		return dict
+	}
+	total := float64(len(user))
+	if len(dict) > 0 {
+		total++
+	}
+	for _, p := range user {
+		p.Score = 1 / total
+	}
+	for _, p := range dict {
+		p.Score /= total
+	}
+	return append(user, dict...)
+}
+
+// parseDictionary ищет слово в основном словаре (DAWG).
+func (a *MorphAnalyzer) parseDictionary(word, lowerWord string) []*Parsed {
 	currentNodeIndex := uint32(0)
 
 	// Идем по графу символ за символом.
@@ -380,76 +539,147 @@ func (a *MorphAnalyzer) Parse(word string) []*Parsed {
 	}
 
 	// Если узел финальный, собираем все варианты разбора, используя его payload.
-	//var results []*Parsed
+	// Разборы размещаются одним блоком и ссылаются на заранее разобранные теги.
 	payloadStart, payloadEnd := node.PayloadIdx, node.PayloadIdx+uint32(node.PayloadLen)
-	results := make([]*Parsed, 0, node.PayloadLen)
-	for _, info := range a.payloads[payloadStart:payloadEnd] {
-		results = append(results, newParsed(word, a.LemmaPool[info.LemmaID], a.tagsPool[info.TagsID]))
+	parsed := make([]Parsed, node.PayloadLen)
+	results := make([]*Parsed, node.PayloadLen)
+	for i, info := range a.payloads[payloadStart:payloadEnd] {
+		parsed[i] = Parsed{Word: word, Lemma: a.LemmaPool[info.LemmaID], Tag: &a.tags[info.TagsID], Origin: OriginDictionary}
+		results[i] = &parsed[i]
 	}
+	a.scoreParses(lowerWord, results)
 	return results
 }
 
-// ParsePredicted пытается предсказать разбор для несловарного слова.
+// maxPredictedParses - сколько гипотез предсказания возвращает ParsePredicted.
+const maxPredictedParses = 8
+
+// ParsePredicted предсказывает разборы для несловарного слова.
+// Возвращает несколько гипотез, отсортированных по убыванию Score.
+// Сначала слово пробуется разложить на известную часть и словарное слово
+// (см. parseByAffix), а если это не удалось - применяются правила суффиксов.
 func (a *MorphAnalyzer) ParsePredicted(word string) []*Parsed {
+	if parses := a.parseByAffix(word); len(parses) > 0 {
+		return parses
+	}
+	return a.parseBySuffix(word)
+}
+
+// parseBySuffix предсказывает разборы по правилам суффиксов:
+// вес правила пропорционален его частоте и длине совпавшего суффикса.
+func (a *MorphAnalyzer) parseBySuffix(word string) []*Parsed {
 	lowerWord := strings.ToLower(word)
-	best := a.findBestPrediction(lowerWord)
-	if best == nil {
+	candidates := a.findPredictions(lowerWord)
+	if len(candidates) == 0 {
 		return nil
 	}
 
-	var predictedLemma string
+	// Одинаковые разборы (лемма + теги), полученные по разным правилам, объединяем, складывая веса.
+	type key struct {
+		lemma  string
+		tagsID uint32
+	}
+	weights := make(map[key]float64)
+	var order []key
+	var total float64
+	templates := make(templateForms)
+	for i := range candidates {
+		c := &candidates[i]
+		k := key{a.predictedLemma(lowerWord, c, templates), c.TagsID}
+		if _, ok := weights[k]; !ok {
+			order = append(order, k)
+		}
+		w := predictionWeight(c)
+		weights[k] += w
+		total += w
+	}
 
+	results := make([]*Parsed, 0, len(order))
+	for _, k := range order {
+		p := newParsedTag(word, k.lemma, &a.tags[k.tagsID], OriginPredicted)
+		if total > 0 {
+			p.Score = weights[k] / total
+		} else {
+			// У всех правил нулевая частота: гипотезы равновероятны.
+			p.Score = 1 / float64(len(order))
+		}
+		results = append(results, p)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > maxPredictedParses {
+		results = results[:maxPredictedParses]
+	}
+	return results
+}
+
+// predictionWeight - вес правила предсказания: частота, умноженная на длину суффикса,
+// чтобы более специфичные (длинные) суффиксы перевешивали частые короткие.
+func predictionWeight(c *PredictionCandidate) float64 {
+	return float64(c.Frequency) * float64(c.SuffixLen)
+}
+
+// predictedLemma вычисляет лемму несловарного слова по правилу предсказания
+// методом "пропорциональной замены": слово относится к своей лемме так же,
+// как слово-образец правила - к лемме образца.
+// Если аналогия не работает, леммой считается само слово.
+func (a *MorphAnalyzer) predictedLemma(lowerWord string, c *PredictionCandidate, templates templateForms) string {
 	// Получаем все формы и лемму для парадигмы-образца.
-	allFormsOfTemplate := a.getFormsByParadigmID(best.ParadigmID)
-	lemmaID, ok := a.paradigmToLemmaID[best.ParadigmID]
+	allFormsOfTemplate := templates.get(a, c.ParadigmID)
+	lemmaID, ok := a.paradigmToLemmaID[c.ParadigmID]
 
 	// Проверяем, что все данные на месте.
-	if !ok || len(allFormsOfTemplate) == 0 || int(best.FormIdx) >= len(allFormsOfTemplate) {
-		// Fallback: если что-то пошло не так, лемма - само слово.
-		predictedLemma = lowerWord
-	} else {
-		// Логика "пропорциональной замены" для определения леммы.
-		wordOfTemplate := allFormsOfTemplate[int(best.FormIdx)]
-		lemmaOfTemplate := a.LemmaPool[lemmaID]
-
-		if len([]rune(wordOfTemplate)) < best.SuffixLen {
-			// Fallback: слово-образец короче суффикса.
-			predictedLemma = lowerWord
-		} else {
-			commonSuffix := string([]rune(lowerWord)[len([]rune(lowerWord))-best.SuffixLen:])
-			if !strings.HasSuffix(wordOfTemplate, commonSuffix) {
-				// Fallback: аналогия неполная.
-				predictedLemma = lowerWord
-			} else {
-				// Все проверки пройдены, вычисления безопасны.
-				oovPrefix := strings.TrimSuffix(lowerWord, commonSuffix)
-				templateWordPrefix := strings.TrimSuffix(wordOfTemplate, commonSuffix)
-				if !strings.HasPrefix(lemmaOfTemplate, templateWordPrefix) {
-					// Сложный случай (супплетивизм).
-					predictedLemma = lowerWord
-				} else {
-					templateLemmaEnding := strings.TrimPrefix(lemmaOfTemplate, templateWordPrefix)
-					predictedLemma = oovPrefix + templateLemmaEnding
-				}
-			}
-		}
+	if !ok || len(allFormsOfTemplate) == 0 || int(c.FormIdx) >= len(allFormsOfTemplate) {
+		return lowerWord
 	}
+	wordOfTemplate := allFormsOfTemplate[int(c.FormIdx)]
+	lemmaOfTemplate := a.LemmaPool[lemmaID]
 
-	// Теги берем напрямую из найденного правила предсказания.
-	tags := a.tagsPool[best.TagsID]
-	return []*Parsed{newParsed(word, predictedLemma, tags)}
+	if len([]rune(wordOfTemplate)) < c.SuffixLen {
+		// Слово-образец короче суффикса.
+		return lowerWord
+	}
+	commonSuffix := string([]rune(lowerWord)[len([]rune(lowerWord))-c.SuffixLen:])
+	if !strings.HasSuffix(wordOfTemplate, commonSuffix) {
+		// Аналогия неполная.
+		return lowerWord
+	}
+	oovPrefix := strings.TrimSuffix(lowerWord, commonSuffix)
+	templateWordPrefix := strings.TrimSuffix(wordOfTemplate, commonSuffix)
+	if !strings.HasPrefix(lemmaOfTemplate, templateWordPrefix) {
+		// Сложный случай (супплетивизм).
+		return lowerWord
+	}
+	return oovPrefix + strings.TrimPrefix(lemmaOfTemplate, templateWordPrefix)
 }
 
-// Predict генерирует все словоформы для несловарного слова.
+// Predict генерирует все словоформы для несловарного слова с леммой `lemma`
+// (обычно взятой из результата ParsePredicted).
 func (a *MorphAnalyzer) Predict(word string, lemma string) []*Parsed {
+	if forms := a.predictByAffix(word, lemma); len(forms) > 0 {
+		return forms
+	}
+	return a.predictBySuffix(word, lemma)
+}
+
+// predictBySuffix генерирует словоформы по правилам суффиксов.
+// Используется лучшая гипотеза предсказания с леммой `lemma`, а если такой нет - лучшая вообще.
+func (a *MorphAnalyzer) predictBySuffix(word string, lemma string) []*Parsed {
 	lowerWord := strings.ToLower(word)
-	best := a.findBestPrediction(lowerWord)
-	if best == nil {
+	candidates := a.findPredictions(lowerWord)
+	if len(candidates) == 0 {
 		return nil
 	}
+	best := &candidates[0]
+	templates := make(templateForms)
+	for i := range candidates {
+		if a.predictedLemma(lowerWord, &candidates[i], templates) == lemma {
+			best = &candidates[i]
+			break
+		}
+	}
 
 	// Получаем слово-образец для вычисления префиксов.
-	allFormsInParadigm := a.getFormsByParadigmID(best.ParadigmID)
+	allFormsInParadigm := templates.get(a, best.ParadigmID)
 	if len(allFormsInParadigm) == 0 || int(best.FormIdx) >= len(allFormsInParadigm) {
 		return nil
 	}
@@ -481,7 +711,7 @@ func (a *MorphAnalyzer) Predict(word string, lemma string) []*Parsed {
 		if strings.HasPrefix(dictForm, dictPrefix) {
 			ending := strings.TrimPrefix(dictForm, dictPrefix)
 			newForm := inputPrefix + ending
-			results = append(results, newParsed(newForm, lemma, a.tagsPool[tagsID]))
+			results = append(results, newParsedTag(newForm, lemma, &a.tags[tagsID], OriginPredicted))
 		}
 	}
 
@@ -494,19 +724,18 @@ func (a *MorphAnalyzer) Predict(word string, lemma string) []*Parsed {
 	return results
 }
 
-// findBestPrediction ищет лучшее правило предсказания для слова.
-// Пробует суффиксы длиной от 5 до 1, ищет их в DAWG предсказателя.
-// Среди всех найденных правил выбирает то, у которого самый длинный суффикс,
-// а при равенстве длин - самая высокая частота.
-func (a *MorphAnalyzer) findBestPrediction(word string) *PredictionCandidate {
+// findPredictions ищет правила предсказания для слова.
+// Пробует суффиксы длиной от 5 до 1, ищет их в DAWG предсказателя; суффикс
+// должен быть короче слова, иначе у слова не остается основы.
+// Кандидаты упорядочены по убыванию веса (см. predictionWeight),
+// при равенстве - по длине суффикса.
+func (a *MorphAnalyzer) findPredictions(word string) []PredictionCandidate {
 	runes := []rune(word)
 	var candidates []PredictionCandidate
 
-	// Итерируемся по возможным длинам суффиксов, от самой длинной (5) к самой короткой (1).
-	// Это позволяет нам рано прекратить поиск, если будет найдено более специфичное правило.
 	for suffixLen := 5; suffixLen >= 1; suffixLen-- {
-		// Пропускаем, если слово короче, чем текущая длина суффикса.
-		if suffixLen > len(runes) {
+		// Пропускаем, если суффикс поглощает все слово.
+		if suffixLen >= len(runes) {
 			continue
 		}
 
@@ -537,23 +766,29 @@ func (a *MorphAnalyzer) findBestPrediction(word string) *PredictionCandidate {
 			candidates = append(candidates, PredictionCandidate{PredictInfo: p, SuffixLen: suffixLen})
 		}
 	}
-	// Если ни одного кандидата не найдено, возвращаем nil.
-	if len(candidates) == 0 {
-		return nil
-	}
 
-	// Сортируем всех найденных кандидатов по нашим правилам приоритета
-	sort.Slice(candidates, func(i, j int) bool {
-		// Сначала сравниваем по длине суффикса (чем больше, тем лучше).
-		if candidates[i].SuffixLen != candidates[j].SuffixLen {
-			return candidates[i].SuffixLen > candidates[j].SuffixLen
+	sort.SliceStable(candidates, func(i, j int) bool {
+		wi, wj := predictionWeight(&candidates[i]), predictionWeight(&candidates[j])
+		if wi != wj {
+			return wi > wj
 		}
-		// Если длины равны, сравниваем по частоте (чем больше, тем лучше).
-		return candidates[i].Frequency > candidates[j].Frequency
+		return candidates[i].SuffixLen > candidates[j].SuffixLen
 	})
+	return candidates
+}
+
+// templateForms запоминает формы парадигм-образцов на время одного предсказания:
+// кандидаты разных длин суффикса обычно ссылаются на одни и те же парадигмы,
+// а обход парадигмы в getFormsByParadigmID - самая дорогая часть predictedLemma.
+type templateForms map[uint32][]string
 
-	// Возвращаем самого лучшего кандидата, который оказался на первом месте после сортировки.
-	return &candidates[0]
+func (t templateForms) get(a *MorphAnalyzer, pID uint32) []string {
+	forms, ok := t[pID]
+	if !ok {
+		forms = a.getFormsByParadigmID(pID)
+		t[pID] = forms
+	}
+	return forms
 }
 
 // getFormsByParadigmID возвращает канонически отсортированный срез всех словоформ для данной парадигмы.
@@ -624,40 +859,38 @@ func (a *MorphAnalyzer) findChildGeneral(nodeIndex uint32, char rune, nodes []Fl
 // и собирает все возможные словоформы, добавляя к ним префикс.
 // Использует поиск в глубину (Depth-First Search).
 func (a *MorphAnalyzer) dfsGenerate(nodeIndex uint32, prefix []rune, targetID uint32, results map[string]uint32) {
-	// Создаем буфер для накапливания суффикса текущей формы.
-	suffixPart := make([]rune, 0)
-
-	// Объявляем рекурсивную функцию `findWord`, чтобы она могла ссылаться сама на себя.
-	var findWord func(uint32, []rune)
-
-	findWord = func(currNodeIdx uint32, currentSuffix []rune) {
-		// Получаем текущий узел.
-		currNode := a.nodes[currNodeIdx]
-		//  Проверяем, является ли узел финальным для какого-либо слова.
-		if currNode.IsFinal {
-			// Если да, то извлекаем его полезную информацию (payload).
-			payloadStart, payloadEnd := currNode.PayloadIdx, currNode.PayloadIdx+uint32(currNode.PayloadLen)
-			for _, info := range a.payloads[payloadStart:payloadEnd] {
-				// Проверяем, относится ли найденная информация к нашей целевой парадигме.
-				if info.ParadigmID == targetID {
-					// Если да, собираем полную форму (основа + найденный суффикс)
-					// и записываем ее в карту результатов вместе с ID ее тегов.
-					form := string(append(prefix, currentSuffix...))
-					results[form] = info.TagsID
-				}
-			}
-		}
+	a.dfsVisit(nodeIndex, prefix, targetID, func(form string, tagsID uint32) {
+		// Записываем форму в карту результатов вместе с ID ее тегов.
+		results[form] = tagsID
+	})
+}
+
+// dfsVisit обходит DAWG так же, как dfsGenerate, но не схлопывает омонимичные формы:
+// `visit` вызывается для каждой пары (словоформа, ID тегов) целевой парадигмы.
+// Текущая форма накапливается в одном буфере: символ добавляется при спуске по ребру
+// и снимается при возврате, поэтому обход не создает промежуточных срезов.
+func (a *MorphAnalyzer) dfsVisit(nodeIndex uint32, prefix []rune, targetID uint32, visit func(form string, tagsID uint32)) {
+	buf := make([]rune, len(prefix), len(prefix)+16)
+	copy(buf, prefix)
+	a.dfsWalk(nodeIndex, buf, targetID, visit)
+}
 
-		// Рекурсивно переходим ко всем дочерним узлам.
-		edgesStart, edgesEnd := currNode.EdgesIdx, currNode.EdgesIdx+uint32(currNode.EdgesLen)
-		for _, edge := range a.edges[edgesStart:edgesEnd] {
-			// При переходе к дочернему узлу мы добавляем символ с ребра к нашему суффиксу.
-			findWord(edge.NodeID, append(currentSuffix, edge.Char))
+func (a *MorphAnalyzer) dfsWalk(nodeIndex uint32, buf []rune, targetID uint32, visit func(form string, tagsID uint32)) []rune {
+	node := a.nodes[nodeIndex]
+	// Если узел финальный, проверяем его payload'ы на принадлежность целевой парадигме.
+	if node.IsFinal {
+		for _, info := range a.payloads[node.PayloadIdx : node.PayloadIdx+uint32(node.PayloadLen)] {
+			if info.ParadigmID == targetID {
+				visit(string(buf), info.TagsID)
+			}
 		}
 	}
-
-	// Запускаем рекурсивный поиск с начального узла и пустым суффиксом.
-	findWord(nodeIndex, suffixPart)
+	// Рекурсивно переходим ко всем дочерним узлам.
+	for _, edge := range a.edges[node.EdgesIdx : node.EdgesIdx+uint32(node.EdgesLen)] {
+		buf = a.dfsWalk(edge.NodeID, append(buf, edge.Char), targetID, visit)
+		buf = buf[:len(buf)-1]
+	}
+	return buf
 }
 
 // ParseList анализирует срез слов в конкурентном режиме, используя пул воркеров.
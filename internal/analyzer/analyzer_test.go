@@ -0,0 +1,124 @@
+package analyzer
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseBySuffixZeroFrequency(t *testing.T) {
+	a := loadFixture(t, "lexemes.tsv")
+	// Словарь, собранный не Builder, может содержать правила с нулевой частотой.
+	payloads := append([]PredictInfo(nil), a.predictPayloads...)
+	for i := range payloads {
+		payloads[i].Frequency = 0
+	}
+	a.predictPayloads = payloads
+
+	parses := a.parseBySuffix("гомами")
+	if len(parses) == 0 {
+		t.Fatal("parseBySuffix: нет гипотез")
+	}
+	sum := 0.0
+	for _, p := range parses {
+		if math.IsNaN(p.Score) || p.Score <= 0 {
+			t.Fatalf("Score гипотезы %s %s = %v", p.Lemma, p.Tags, p.Score)
+		}
+		sum += p.Score
+	}
+	if len(parses) < maxPredictedParses && math.Abs(sum-1) > 1e-9 {
+		t.Errorf("сумма Score = %v, ожидается 1", sum)
+	}
+}
+
+// benchWords - словарные слова тестового словаря, в том числе омонимичные.
+var benchWords = []string{"дома", "стали", "рублей", "красивую", "шла", "банками", "она", "в"}
+
+func BenchmarkParse(b *testing.B) {
+	a := loadFixture(b, "lexemes.tsv")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, w := range benchWords {
+			a.Parse(w)
+		}
+	}
+}
+
+func BenchmarkParsePredicted(b *testing.B) {
+	a := loadFixture(b, "lexemes.tsv")
+	words := []string{"гомами", "кранками", "мубле", "стрелью", "шмали"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, w := range words {
+			a.ParsePredicted(w)
+		}
+	}
+}
+
+func BenchmarkInflect(b *testing.B) {
+	a := loadFixture(b, "lexemes.tsv")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, w := range benchWords {
+			a.Inflect(w)
+		}
+	}
+}
+
+func TestLoadDefaultPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, fixture string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, compileFixture(t, readFixture(t, fixture)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	// Словари различаются словами: "дом" есть только в lexemes.tsv, "кошка" - только в opencorpora.xml
+	envPath := write("env.dawg", "lexemes.tsv")
+	filePath := write("morph.dawg", "lexemes.tsv")
+	embedded := compileFixture(t, readFixture(t, "opencorpora.xml"))
+	missing := filepath.Join(dir, "missing", "morph.dawg")
+
+	tests := []struct {
+		name     string
+		env      string
+		embedded []byte
+		paths    []string
+		word     string // слово, которое разбирает выбранный словарь
+	}{
+		{"переменная окружения важнее встроенного словаря", envPath, embedded, []string{filePath}, "дом"},
+		{"встроенный словарь важнее файла", "", embedded, []string{filePath}, "кошка"},
+		{"первый существующий файл", "", nil, []string{missing, filePath}, "дом"},
+	}
+	for _, tt := range tests {
+		a, err := loadDefault(tt.env, tt.embedded, tt.paths)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(a.Parse(tt.word)) == 0 {
+			t.Errorf("%s: выбран не тот словарь (нет разбора %q)", tt.name, tt.word)
+		}
+		a.Close()
+	}
+
+	if _, err := loadDefault("", nil, []string{missing}); err == nil {
+		t.Error("словарь без единого источника загружен")
+	}
+	// Явно заданный путь не подменяется другими источниками, даже если файла нет
+	if _, err := loadDefault(missing, embedded, []string{filePath}); err == nil {
+		t.Error("отсутствующий файл из переменной окружения подменен другим словарем")
+	}
+
+	t.Setenv(EnvDictPath, envPath)
+	a, err := LoadMorphAnalyzer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if len(a.Parse("дом")) == 0 {
+		t.Errorf("LoadMorphAnalyzer не прочитал словарь из %s", EnvDictPath)
+	}
+}
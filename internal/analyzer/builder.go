@@ -0,0 +1,547 @@
+// builder.go содержит компилятор бинарного словаря morph.dawg.
+// Builder принимает лексемы (лемма + все словоформы с тегами), строит из них
+// основной DAWG, таблицы парадигм и основ, а также DAWG предсказателя по суффиксам
+// с частотами правил, и записывает все это в формат, который читает `loadInternal`.
+package analyzer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// LexemeForm - одна словоформа лексемы.
+type LexemeForm struct {
+	Word string // Словоформа.
+	Tags string // Строка тегов в формате словаря: граммемы через запятую, часть речи первой.
+}
+
+// Lexeme - лексема входного словаря.
+type Lexeme struct {
+	Lemma string       // Нормальная форма.
+	Forms []LexemeForm // Все словоформы, включая саму лемму.
+}
+
+// DefaultPredictPOS - части речи, для которых строятся правила предсказателя.
+// Служебные и местоименные слова образуют закрытые классы, и предсказывать их по суффиксу бессмысленно.
+var DefaultPredictPOS = GrammemeSet{
+	"Существительное": {},
+	"Прилагательное":  {},
+	"Глагол":          {},
+	"Причастие":       {},
+	"Деепричастие":    {},
+	"Наречие":         {},
+}
+
+// Builder накапливает лексемы и компилирует из них бинарный словарь.
+type Builder struct {
+	MaxSuffixLen        int         // Максимальная длина суффикса в правилах предсказателя (по умолчанию 5).
+	MinPredictFrequency int         // Правила, встретившиеся реже, в предсказатель не попадают.
+	MaxPredictPayloads  int         // Сколько самых частых правил хранить для одного суффикса.
+	PredictPOS          GrammemeSet // Части речи, участвующие в построении предсказателя.
+
+	lemmaPool []string
+	lemmaIDs  map[string]uint32
+	tagsPool  []string
+	tagsIDs   map[string]uint32
+	lexemes   []builtLexeme // Индекс в срезе - это ID парадигмы.
+}
+
+// builtLexeme - лексема после интернирования строк.
+type builtLexeme struct {
+	lemmaID uint32
+	forms   []builtForm
+}
+
+type builtForm struct {
+	word   string
+	tagsID uint32
+}
+
+// NewBuilder создает компилятор с настройками по умолчанию.
+func NewBuilder() *Builder {
+	return &Builder{
+		MaxSuffixLen:        5,
+		MinPredictFrequency: 1,
+		MaxPredictPayloads:  16,
+		PredictPOS:          DefaultPredictPOS,
+		lemmaIDs:            make(map[string]uint32),
+		tagsIDs:             make(map[string]uint32),
+	}
+}
+
+// Add добавляет лексему в словарь. Каждая лексема получает собственную парадигму.
+func (b *Builder) Add(lex Lexeme) error {
+	lemma := strings.ToLower(strings.TrimSpace(lex.Lemma))
+	if lemma == "" {
+		return errors.New("пустая лемма")
+	}
+	if len(lex.Forms) == 0 {
+		return fmt.Errorf("у леммы '%s' нет словоформ", lemma)
+	}
+
+	bl := builtLexeme{lemmaID: b.internLemma(lemma)}
+	seen := make(map[builtForm]bool)
+	for _, f := range lex.Forms {
+		word := strings.ToLower(strings.TrimSpace(f.Word))
+		if word == "" {
+			return fmt.Errorf("пустая словоформа у леммы '%s'", lemma)
+		}
+		form := builtForm{word: word, tagsID: b.internTags(f.Tags)}
+		if seen[form] {
+			continue
+		}
+		seen[form] = true
+		bl.forms = append(bl.forms, form)
+	}
+	b.lexemes = append(b.lexemes, bl)
+	return nil
+}
+
+// Len возвращает число добавленных лексем.
+func (b *Builder) Len() int { return len(b.lexemes) }
+
+func (b *Builder) internLemma(lemma string) uint32 {
+	if id, ok := b.lemmaIDs[lemma]; ok {
+		return id
+	}
+	id := uint32(len(b.lemmaPool))
+	b.lemmaPool = append(b.lemmaPool, lemma)
+	b.lemmaIDs[lemma] = id
+	return id
+}
+
+func (b *Builder) internTags(tags string) uint32 {
+	if id, ok := b.tagsIDs[tags]; ok {
+		return id
+	}
+	id := uint32(len(b.tagsPool))
+	b.tagsPool = append(b.tagsPool, tags)
+	b.tagsIDs[tags] = id
+	return id
+}
+
+// WriteTo компилирует словарь и записывает его в `w`.
+func (b *Builder) WriteTo(w io.Writer) (int64, error) {
+	if len(b.lexemes) == 0 {
+		return 0, errors.New("словарь пуст")
+	}
+
+	// 1. Основной словарь: префиксное дерево словоформ.
+	root := &Node{Children: make(map[rune]*Node)}
+	for pID, lex := range b.lexemes {
+		for _, f := range lex.forms {
+			insertPayload(root, f.word, MorphInfo{LemmaID: lex.lemmaID, TagsID: f.tagsID, ParadigmID: uint32(pID)})
+		}
+	}
+
+	// 2. Предсказатель: дерево суффиксов с правилами.
+	predictRoot := b.buildPredictor()
+
+	// 3. Минимизируем деревья до DAWG и переводим в "плоский" вид.
+	nodes, edges, rawPayloads, err := flatten(minimize(root))
+	if err != nil {
+		return 0, err
+	}
+	predictNodes, predictEdges, rawPredict, err := flatten(minimize(predictRoot))
+	if err != nil {
+		return 0, err
+	}
+	payloads := make([]MorphInfo, len(rawPayloads))
+	for i, p := range rawPayloads {
+		payloads[i] = p.(MorphInfo)
+	}
+	predictPayloads := make([]PredictInfo, len(rawPredict))
+	for i, p := range rawPredict {
+		predictPayloads[i] = p.(PredictInfo)
+	}
+
+	// 4. Таблицы парадигм: для каждой лексемы - ее основы и узлы, где они заканчиваются.
+	complexData := ComplexData{
+		LemmaPool:         b.lemmaPool,
+		TagsPool:          b.tagsPool,
+		Paradigms:         make(map[uint32][]ParadigmInfo, len(b.lexemes)),
+		ParadigmToLemmaID: make(map[uint32]uint32, len(b.lexemes)),
+	}
+	for pID, lex := range b.lexemes {
+		for _, stem := range lexemeStems(lex.forms) {
+			nodeID, ok := walkFlat(nodes, edges, stem)
+			if !ok {
+				return 0, fmt.Errorf("не найден узел основы '%s'", stem)
+			}
+			complexData.Paradigms[uint32(pID)] = append(complexData.Paradigms[uint32(pID)], ParadigmInfo{Stem: stem, NodeID: nodeID})
+		}
+		complexData.ParadigmToLemmaID[uint32(pID)] = lex.lemmaID
+	}
+
+	var complexBuf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&complexBuf)
+	if err := gob.NewEncoder(gzipWriter).Encode(&complexData); err != nil {
+		return 0, fmt.Errorf("ошибка gob-кодирования: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return 0, fmt.Errorf("ошибка сжатия данных: %w", err)
+	}
+
+	// 5. Раскладываем секции по файлу. Массивы читаются через mmap без копирования,
+	// поэтому каждая секция выравнивается и пишется в том же представлении, что и в памяти.
+	sections := [][]byte{
+		complexBuf.Bytes(),
+		sliceBytes(nodes),
+		sliceBytes(edges),
+		sliceBytes(payloads),
+		sliceBytes(predictNodes),
+		sliceBytes(predictEdges),
+		sliceBytes(predictPayloads),
+	}
+	offsets := make([]int64, len(sections))
+	offset := alignUp(int64(headerSize), sectionAlign)
+	for i, s := range sections {
+		offsets[i] = offset
+		offset = alignUp(offset+int64(len(s)), sectionAlign)
+	}
+
+	header := Header{
+		Version: formatVersion,
+		Sections: Sections{
+			ComplexDataOffset:     offsets[0],
+			ComplexDataLength:     int64(len(sections[0])),
+			NodesOffset:           offsets[1],
+			NodesCount:            int64(len(nodes)),
+			EdgesOffset:           offsets[2],
+			EdgesCount:            int64(len(edges)),
+			PayloadsOffset:        offsets[3],
+			PayloadsCount:         int64(len(payloads)),
+			PredictNodesOffset:    offsets[4],
+			PredictNodesCount:     int64(len(predictNodes)),
+			PredictEdgesOffset:    offsets[5],
+			PredictEdgesCount:     int64(len(predictEdges)),
+			PredictPayloadsOffset: offsets[6],
+			PredictPayloadsCount:  int64(len(predictPayloads)),
+		},
+	}
+	copy(header.Magic[:], formatMagic)
+
+	// Тело файла собираем отдельно: контрольная сумма считается по всему, что идет после заголовка.
+	body := make([]byte, offset-int64(headerSize))
+	for i, s := range sections {
+		copy(body[offsets[i]-int64(headerSize):], s)
+	}
+	header.Checksum = crc32.Checksum(body, crcTable)
+
+	var file bytes.Buffer
+	if err := binary.Write(&file, binary.LittleEndian, &header); err != nil {
+		return 0, fmt.Errorf("ошибка записи заголовка: %w", err)
+	}
+	file.Write(body)
+	return file.WriteTo(w)
+}
+
+func alignUp(n, align int64) int64 {
+	return (n + align - 1) / align * align
+}
+
+// sliceBytes возвращает байтовое представление среза в памяти без копирования.
+// Обратная операция к `bytesToSlice`.
+func sliceBytes[T any](s []T) []byte {
+	if len(s) == 0 {
+		return nil
+	}
+	var t T
+	return unsafe.Slice((*byte)(unsafe.Pointer(&s[0])), len(s)*int(unsafe.Sizeof(t)))
+}
+
+// insertPayload добавляет ключ в дерево и дописывает payload в его конечный узел.
+func insertPayload(root *Node, key string, payload any) {
+	node := root
+	for _, char := range key {
+		child, ok := node.Children[char]
+		if !ok {
+			child = &Node{Children: make(map[rune]*Node)}
+			node.Children[char] = child
+		}
+		node = child
+	}
+	node.IsFinal = true
+	for _, p := range node.Payload {
+		if p == payload {
+			return
+		}
+	}
+	node.Payload = append(node.Payload, payload)
+}
+
+// lexemeStems возвращает основы лексемы: общий префикс всех ее словоформ.
+// Если общего префикса нет (супплетивные формы: "идти"/"шел"), формы группируются
+// по первой букве, и для каждой группы берется своя основа.
+func lexemeStems(forms []builtForm) []string {
+	words := make([]string, len(forms))
+	for i, f := range forms {
+		words[i] = f.word
+	}
+	if stem := commonPrefix(words); stem != "" {
+		return []string{stem}
+	}
+
+	groups := make(map[rune][]string)
+	var order []rune
+	for _, w := range words {
+		first := []rune(w)[0]
+		if _, ok := groups[first]; !ok {
+			order = append(order, first)
+		}
+		groups[first] = append(groups[first], w)
+	}
+	stems := make([]string, 0, len(order))
+	for _, first := range order {
+		stems = append(stems, commonPrefix(groups[first]))
+	}
+	return stems
+}
+
+// commonPrefix возвращает общий префикс (по рунам) всех строк.
+func commonPrefix(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	prefix := []rune(words[0])
+	for _, w := range words[1:] {
+		r := []rune(w)
+		n := 0
+		for n < len(prefix) && n < len(r) && prefix[n] == r[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
+
+// predictRule - правило предсказателя до упаковки в PredictInfo.
+type predictRule struct {
+	count      int
+	paradigmID uint32
+	form       string
+	tagsID     uint32
+}
+
+// buildPredictor строит дерево суффиксов. Лексемы с одинаковым набором окончаний и тегов
+// относятся к одному классу словоизменения; правило (суффикс, класс, форма) хранит
+// число лексем, в которых оно встретилось, и лексему-образец, по которой будут склоняться
+// несловарные слова.
+func (b *Builder) buildPredictor() *Node {
+	type ruleKey struct {
+		suffix  string
+		classID int
+		ending  string
+		tagsID  uint32
+	}
+	classIDs := make(map[string]int)
+	rules := make(map[ruleKey]*predictRule)
+	var keysOrder []ruleKey
+
+	for pID, lex := range b.lexemes {
+		stems := lexemeStems(lex.forms)
+		if len(stems) != 1 || !b.predictable(lex) {
+			continue
+		}
+		stem := stems[0]
+
+		// Класс словоизменения - отсортированный набор пар (окончание, теги).
+		endings := make([]string, len(lex.forms))
+		for i, f := range lex.forms {
+			endings[i] = strings.TrimPrefix(f.word, stem) + "\x00" + strconv.FormatUint(uint64(f.tagsID), 10)
+		}
+		sort.Strings(endings)
+		signature := strings.Join(endings, "\x01")
+		classID, ok := classIDs[signature]
+		if !ok {
+			classID = len(classIDs)
+			classIDs[signature] = classID
+		}
+
+		for _, f := range lex.forms {
+			runes := []rune(f.word)
+			ending := strings.TrimPrefix(f.word, stem)
+			// Суффикс не должен поглощать слово целиком: несловарному слову нужна своя основа.
+			for l := 1; l <= b.MaxSuffixLen && l < len(runes); l++ {
+				key := ruleKey{suffix: string(runes[len(runes)-l:]), classID: classID, ending: ending, tagsID: f.tagsID}
+				rule, ok := rules[key]
+				if !ok {
+					rule = &predictRule{paradigmID: uint32(pID), form: f.word, tagsID: f.tagsID}
+					rules[key] = rule
+					keysOrder = append(keysOrder, key)
+				}
+				rule.count++
+			}
+		}
+	}
+
+	// Группируем правила по суффиксам и оставляем самые частые.
+	bySuffix := make(map[string][]*predictRule)
+	var suffixes []string
+	for _, key := range keysOrder {
+		rule := rules[key]
+		if rule.count < b.MinPredictFrequency {
+			continue
+		}
+		if _, ok := bySuffix[key.suffix]; !ok {
+			suffixes = append(suffixes, key.suffix)
+		}
+		bySuffix[key.suffix] = append(bySuffix[key.suffix], rule)
+	}
+
+	formIdx := make(map[uint32][]string) // Кэш отсортированных форм лексем-образцов.
+	root := &Node{Children: make(map[rune]*Node)}
+	for _, suffix := range suffixes {
+		list := bySuffix[suffix]
+		sort.SliceStable(list, func(i, j int) bool { return list[i].count > list[j].count })
+		if b.MaxPredictPayloads > 0 && len(list) > b.MaxPredictPayloads {
+			list = list[:b.MaxPredictPayloads]
+		}
+		for _, rule := range list {
+			forms, ok := formIdx[rule.paradigmID]
+			if !ok {
+				forms = sortedForms(b.lexemes[rule.paradigmID].forms)
+				formIdx[rule.paradigmID] = forms
+			}
+			idx := sort.SearchStrings(forms, rule.form)
+			insertPayload(root, suffix, PredictInfo{
+				Frequency:  uint16(min(rule.count, math.MaxUint16)),
+				ParadigmID: rule.paradigmID,
+				FormIdx:    uint32(idx),
+				TagsID:     rule.tagsID,
+			})
+		}
+	}
+	return root
+}
+
+// predictable сообщает, участвует ли лексема в построении предсказателя.
+func (b *Builder) predictable(lex builtLexeme) bool {
+	if len(b.PredictPOS) == 0 {
+		return true
+	}
+	pos, _, _ := strings.Cut(b.tagsPool[lex.forms[0].tagsID], ",")
+	return inMap(pos, b.PredictPOS)
+}
+
+// sortedForms повторяет порядок `getFormsByParadigmID`: уникальные формы по алфавиту.
+func sortedForms(forms []builtForm) []string {
+	unique := make(map[string]bool, len(forms))
+	out := make([]string, 0, len(forms))
+	for _, f := range forms {
+		if !unique[f.word] {
+			unique[f.word] = true
+			out = append(out, f.word)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// minimize сливает эквивалентные поддеревья (одинаковые payload'ы, финальность и переходы),
+// превращая префиксное дерево в DAWG.
+func minimize(root *Node) *Node {
+	registry := make(map[string]*Node)
+	ids := make(map[*Node]int)
+
+	var visit func(n *Node) *Node
+	visit = func(n *Node) *Node {
+		for char, child := range n.Children {
+			n.Children[char] = visit(child)
+		}
+
+		var sig strings.Builder
+		if n.IsFinal {
+			sig.WriteByte('F')
+		}
+		for _, p := range n.Payload {
+			fmt.Fprintf(&sig, "|%v", p)
+		}
+		for _, char := range sortedChars(n) {
+			fmt.Fprintf(&sig, "/%d:%d", char, ids[n.Children[char]])
+		}
+
+		if existing, ok := registry[sig.String()]; ok {
+			return existing
+		}
+		registry[sig.String()] = n
+		ids[n] = len(ids)
+		return n
+	}
+	return visit(root)
+}
+
+// flatten переводит DAWG в "плоские" массивы узлов, ребер и payload'ов.
+// Корень всегда получает индекс 0, ребра каждого узла отсортированы по символу.
+func flatten(root *Node) ([]FlatNode, []FlatEdge, []any, error) {
+	ids := make(map[*Node]uint32)
+	var order []*Node
+	var assign func(n *Node)
+	assign = func(n *Node) {
+		if _, ok := ids[n]; ok {
+			return
+		}
+		ids[n] = uint32(len(order))
+		order = append(order, n)
+		for _, char := range sortedChars(n) {
+			assign(n.Children[char])
+		}
+	}
+	assign(root)
+
+	nodes := make([]FlatNode, len(order))
+	var edges []FlatEdge
+	var payloads []any
+	for i, n := range order {
+		if len(n.Payload) > math.MaxUint16 || len(n.Children) > math.MaxUint16 {
+			return nil, nil, nil, errors.New("слишком много payload'ов или ребер у одного узла")
+		}
+		nodes[i] = FlatNode{
+			PayloadIdx: uint32(len(payloads)),
+			EdgesIdx:   uint32(len(edges)),
+			PayloadLen: uint16(len(n.Payload)),
+			EdgesLen:   uint16(len(n.Children)),
+			IsFinal:    n.IsFinal,
+		}
+		payloads = append(payloads, n.Payload...)
+		for _, char := range sortedChars(n) {
+			edges = append(edges, FlatEdge{Char: char, NodeID: ids[n.Children[char]]})
+		}
+	}
+	return nodes, edges, payloads, nil
+}
+
+func sortedChars(n *Node) []rune {
+	chars := make([]rune, 0, len(n.Children))
+	for char := range n.Children {
+		chars = append(chars, char)
+	}
+	sort.Slice(chars, func(i, j int) bool { return chars[i] < chars[j] })
+	return chars
+}
+
+// walkFlat проходит по "плоскому" графу от корня по строке `key`.
+func walkFlat(nodes []FlatNode, edges []FlatEdge, key string) (uint32, bool) {
+	current := uint32(0)
+	for _, char := range key {
+		node := nodes[current]
+		window := edges[node.EdgesIdx : node.EdgesIdx+uint32(node.EdgesLen)]
+		i := sort.Search(len(window), func(i int) bool { return window[i].Char >= char })
+		if i == len(window) || window[i].Char != char {
+			return 0, false
+		}
+		current = window[i].NodeID
+	}
+	return current, true
+}
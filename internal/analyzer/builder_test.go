@@ -0,0 +1,159 @@
+package analyzer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// readFixture читает словарь из testdata: XML OpenCorpora или TSV (по расширению).
+func readFixture(tb testing.TB, name string) *Builder {
+	tb.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	b := NewBuilder()
+	if strings.HasSuffix(name, ".xml") {
+		err = ReadOpenCorpora(f, b)
+	} else {
+		err = ReadTSV(f, b)
+	}
+	if err != nil {
+		tb.Fatalf("%s: %v", name, err)
+	}
+	return b
+}
+
+// compileFixture компилирует словарь в образ morph.dawg.
+func compileFixture(tb testing.TB, b *Builder) []byte {
+	tb.Helper()
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// loadFixture компилирует словарь из testdata и загружает анализатор из памяти.
+func loadFixture(tb testing.TB, name string) *MorphAnalyzer {
+	tb.Helper()
+	a, err := LoadMorphAnalyzerFromBytes(compileFixture(tb, readFixture(tb, name)))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { a.Close() })
+	return a
+}
+
+func TestBuilderRoundTrip(t *testing.T) {
+	tests := []struct {
+		file string
+		// Несловарное слово, его лемма и граммемы лучшей гипотезы предсказателя по суффиксу.
+		oov, oovLemma string
+		oovTags       []string
+		oovForm       string // одна из форм, которые Predict строит по той же парадигме
+	}{
+		{"lexemes.tsv", "гомами", "гом", []string{"Творительный", "Множественное число"}, "гомов"},
+		{"opencorpora.xml", "мошками", "мошка", []string{"Творительный", "Множественное число"}, "мошек"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b := readFixture(t, tt.file)
+			a, err := LoadMorphAnalyzerFromBytes(compileFixture(t, b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.Close()
+
+			for _, lex := range b.lexemes {
+				lemma := b.lemmaPool[lex.lemmaID]
+				var want []string
+				for _, f := range lex.forms {
+					tags := b.tagsPool[f.tagsID]
+					if !hasParse(a.Parse(f.word), lemma, tags) {
+						t.Errorf("Parse(%q): нет разбора %s %s", f.word, lemma, tags)
+					}
+					want = append(want, f.word)
+				}
+				var got []string
+				for _, p := range a.Inflect(lemma) {
+					got = append(got, p.Word)
+				}
+				if w, g := uniqueSorted(want), uniqueSorted(got); w != g {
+					t.Errorf("Inflect(%q) = %s, ожидается %s", lemma, g, w)
+				}
+			}
+
+			parses := a.parseBySuffix(tt.oov)
+			if len(parses) == 0 {
+				t.Fatalf("parseBySuffix(%q): нет гипотез", tt.oov)
+			}
+			best := parses[0]
+			if best.Lemma != tt.oovLemma || best.Origin != OriginPredicted {
+				t.Errorf("parseBySuffix(%q) = %s (%s), ожидается лемма %s", tt.oov, best.Lemma, best.Origin, tt.oovLemma)
+			}
+			for _, g := range tt.oovTags {
+				if !inMap(g, best.grammemes) {
+					t.Errorf("parseBySuffix(%q): в тегах %q нет %q", tt.oov, best.Tags, g)
+				}
+			}
+			if !hasWord(a.Predict(tt.oov, best.Lemma), tt.oovForm) {
+				t.Errorf("Predict(%q, %q): нет формы %q", tt.oov, best.Lemma, tt.oovForm)
+			}
+		})
+	}
+}
+
+func TestReadOpenCorporaTags(t *testing.T) {
+	a := loadFixture(t, "opencorpora.xml")
+	want := "Существительное,Одушевленное,Женский,Единственное число,Винительный"
+	if !hasParse(a.Parse("кошку"), "кошка", want) {
+		t.Errorf("Parse(кошку) = %v, ожидается %s", a.Parse("кошку"), want)
+	}
+	if !hasParse(a.Parse("читать"), "читать", "Глагол,Несовершенный,Переходный,Инфинитив") {
+		t.Errorf("Parse(читать): граммемы леммы не перенесены в формы")
+	}
+}
+
+func TestReadSourceErrors(t *testing.T) {
+	if err := ReadTSV(strings.NewReader("дом\tNOUN\n\nдома NOUN,gent\n"), NewBuilder()); err == nil {
+		t.Error("ReadTSV: ожидается ошибка для строки без табуляции")
+	}
+	if err := ReadOpenCorpora(strings.NewReader(`<lemmata><lemma><l t="дом">`), NewBuilder()); err == nil {
+		t.Error("ReadOpenCorpora: ожидается ошибка для обрезанного XML")
+	}
+}
+
+func hasParse(parses []*Parsed, lemma, tags string) bool {
+	for _, p := range parses {
+		if p.Lemma == lemma && p.Tags == tags {
+			return true
+		}
+	}
+	return false
+}
+
+func hasWord(parses []*Parsed, word string) bool {
+	for _, p := range parses {
+		if p.Word == word {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueSorted(words []string) string {
+	sort.Strings(words)
+	out := words[:0]
+	for i, w := range words {
+		if i == 0 || w != words[i-1] {
+			out = append(out, w)
+		}
+	}
+	return strings.Join(out, " ")
+}
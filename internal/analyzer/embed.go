@@ -0,0 +1,11 @@
+//go:build morphembed
+
+package analyzer
+
+import _ "embed"
+
+// embeddedDict - словарь, встроенный в бинарник для развертывания одним файлом.
+// Сборка: go build -tags morphembed (файл morph.dawg должен лежать рядом с пакетом).
+//
+//go:embed morph.dawg
+var embeddedDict []byte
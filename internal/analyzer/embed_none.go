@@ -0,0 +1,6 @@
+//go:build !morphembed
+
+package analyzer
+
+// embeddedDict пуст, если бинарник собран без тега `morphembed`.
+var embeddedDict []byte
@@ -0,0 +1,217 @@
+// format.go описывает бинарный формат morph.dawg и его проверку при загрузке.
+// Файл начинается с заголовка (Header), за которым следуют секции: сжатый gob-блок
+// ComplexData и "плоские" массивы узлов, ребер и payload'ов двух DAWG.
+// Массивы читаются через mmap без копирования, поэтому каждая секция должна
+// целиком помещаться в файл и быть выровнена под свой тип.
+package analyzer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"unsafe"
+)
+
+const (
+	// formatMagic - сигнатура текущего формата словаря.
+	formatMagic = "DAWG"
+	// legacyMagic - сигнатура старого формата без версии и контрольной суммы.
+	legacyMagic = "DAW7"
+	// formatVersion - версия формата, которую пишет Builder и понимает загрузчик.
+	formatVersion = 8
+	// sectionAlign - выравнивание секций файла; подходит для всех "плоских" структур.
+	sectionAlign = 8
+)
+
+// ErrCorruptDictionary возвращается, если файл словаря поврежден, обрезан
+// или записан в неподдерживаемом формате.
+var ErrCorruptDictionary = errors.New("поврежденный словарь morph.dawg")
+
+var (
+	headerSize       = binary.Size(Header{})
+	legacyHeaderSize = binary.Size(legacyHeader{})
+	crcTable         = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// readHeader читает заголовок и возвращает таблицу секций.
+// Для текущего формата проверяется версия; контрольную сумму проверяет verifyChecksum,
+// так как она читает весь файл. Словари DAW7 принимаются как есть.
+func readHeader(data []byte) (Sections, error) {
+	if len(data) < legacyHeaderSize {
+		return Sections{}, fmt.Errorf("%w: файл слишком мал для заголовка (%d байт)", ErrCorruptDictionary, len(data))
+	}
+
+	switch string(data[:4]) {
+	case legacyMagic:
+		var header legacyHeader
+		if err := binary.Read(bytes.NewReader(data[:legacyHeaderSize]), binary.LittleEndian, &header); err != nil {
+			return Sections{}, fmt.Errorf("%w: ошибка чтения заголовка: %v", ErrCorruptDictionary, err)
+		}
+		return header.Sections, nil
+
+	case formatMagic:
+		if len(data) < headerSize {
+			return Sections{}, fmt.Errorf("%w: файл слишком мал для заголовка (%d байт)", ErrCorruptDictionary, len(data))
+		}
+		var header Header
+		if err := binary.Read(bytes.NewReader(data[:headerSize]), binary.LittleEndian, &header); err != nil {
+			return Sections{}, fmt.Errorf("%w: ошибка чтения заголовка: %v", ErrCorruptDictionary, err)
+		}
+		if header.Version != formatVersion {
+			return Sections{}, fmt.Errorf("%w: неподдерживаемая версия формата %d (ожидается %d)", ErrCorruptDictionary, header.Version, formatVersion)
+		}
+		return header.Sections, nil
+
+	default:
+		return Sections{}, fmt.Errorf("%w: неверная сигнатура файла %q", ErrCorruptDictionary, data[:4])
+	}
+}
+
+// verifyChecksum сверяет CRC-32C всех байт после заголовка с заголовком.
+// Проверка читает файл целиком, то есть подгружает в память все страницы mmap,
+// поэтому при загрузке она выполняется только по запросу (см. EnvDictVerify).
+// У словарей DAW7 контрольной суммы нет.
+func verifyChecksum(data []byte) error {
+	if len(data) < headerSize || string(data[:4]) != formatMagic {
+		return nil
+	}
+	var header Header
+	if err := binary.Read(bytes.NewReader(data[:headerSize]), binary.LittleEndian, &header); err != nil {
+		return fmt.Errorf("%w: ошибка чтения заголовка: %v", ErrCorruptDictionary, err)
+	}
+	if sum := crc32.Checksum(data[headerSize:], crcTable); sum != header.Checksum {
+		return fmt.Errorf("%w: контрольная сумма не совпадает (%08x, в заголовке %08x)", ErrCorruptDictionary, sum, header.Checksum)
+	}
+	return nil
+}
+
+// byteSection возвращает секцию произвольных байт, проверив ее границы.
+func byteSection(data []byte, name string, offset, length int64) ([]byte, error) {
+	if offset < int64(legacyHeaderSize) || length < 0 || offset > int64(len(data)) || length > int64(len(data))-offset {
+		return nil, fmt.Errorf("%w: секция %s [%d, +%d) выходит за пределы файла (%d байт)", ErrCorruptDictionary, name, offset, length, len(data))
+	}
+	return data[offset : offset+length], nil
+}
+
+// section возвращает секцию из `count` элементов типа T, проверив границы и выравнивание.
+func section[T any](data []byte, name string, offset, count int64) ([]T, error) {
+	var t T
+	size, align := int64(unsafe.Sizeof(t)), uintptr(unsafe.Alignof(t))
+	if count < 0 || count > int64(len(data))/size {
+		return nil, fmt.Errorf("%w: секция %s: недопустимое число элементов %d", ErrCorruptDictionary, name, count)
+	}
+	b, err := byteSection(data, name, offset, count*size)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > 0 && uintptr(unsafe.Pointer(&b[0]))%align != 0 {
+		return nil, fmt.Errorf("%w: секция %s: смещение %d не выровнено по %d байт", ErrCorruptDictionary, name, offset, align)
+	}
+	return bytesToSlice[T](b), nil
+}
+
+// bytesToSlice - "небезопасная" функция, которая создает срез,
+// указывающий на область байт, без копирования самих данных.
+// Границы и выравнивание проверяет вызывающая сторона (см. `section`).
+func bytesToSlice[T any](b []byte) []T {
+	if len(b) == 0 {
+		return nil
+	}
+	var t T
+	return unsafe.Slice((*T)(unsafe.Pointer(&b[0])), len(b)/int(unsafe.Sizeof(t)))
+}
+
+// validate проверяет ссылки между секциями: индексы ребер, payload'ов, узлов и пулов строк,
+// а также отсутствие циклов в графах (иначе обход `dfsVisit` никогда не завершится).
+func (a *MorphAnalyzer) validate() error {
+	if len(a.nodes) == 0 {
+		return fmt.Errorf("%w: основной словарь не содержит узлов", ErrCorruptDictionary)
+	}
+	if len(a.predictNodes) == 0 {
+		return fmt.Errorf("%w: предсказатель не содержит узлов", ErrCorruptDictionary)
+	}
+	if err := validateGraph("nodes", a.nodes, a.edges, len(a.payloads)); err != nil {
+		return err
+	}
+	if err := validateGraph("predict nodes", a.predictNodes, a.predictEdges, len(a.predictPayloads)); err != nil {
+		return err
+	}
+
+	lemmas, tags := uint32(len(a.LemmaPool)), uint32(len(a.tagsPool))
+	for i, p := range a.payloads {
+		if p.LemmaID >= lemmas || p.TagsID >= tags {
+			return fmt.Errorf("%w: payload %d ссылается на несуществующую лемму или теги", ErrCorruptDictionary, i)
+		}
+	}
+	for i, p := range a.predictPayloads {
+		if p.TagsID >= tags {
+			return fmt.Errorf("%w: payload предсказателя %d ссылается на несуществующие теги", ErrCorruptDictionary, i)
+		}
+	}
+	for pID, stems := range a.paradigms {
+		for _, stem := range stems {
+			if int(stem.NodeID) >= len(a.nodes) {
+				return fmt.Errorf("%w: основа '%s' парадигмы %d ссылается на несуществующий узел", ErrCorruptDictionary, stem.Stem, pID)
+			}
+		}
+	}
+	for pID, lemmaID := range a.paradigmToLemmaID {
+		if lemmaID >= lemmas {
+			return fmt.Errorf("%w: парадигма %d ссылается на несуществующую лемму", ErrCorruptDictionary, pID)
+		}
+	}
+	return nil
+}
+
+// validateGraph проверяет, что окна ребер и payload'ов каждого узла лежат в пределах массивов,
+// ребра ведут в существующие узлы, а граф ацикличен.
+func validateGraph(name string, nodes []FlatNode, edges []FlatEdge, payloads int) error {
+	for i, n := range nodes {
+		if uint64(n.EdgesIdx)+uint64(n.EdgesLen) > uint64(len(edges)) {
+			return fmt.Errorf("%w: %s: ребра узла %d выходят за пределы массива", ErrCorruptDictionary, name, i)
+		}
+		if uint64(n.PayloadIdx)+uint64(n.PayloadLen) > uint64(payloads) {
+			return fmt.Errorf("%w: %s: payload'ы узла %d выходят за пределы массива", ErrCorruptDictionary, name, i)
+		}
+	}
+	for i, e := range edges {
+		if int(e.NodeID) >= len(nodes) {
+			return fmt.Errorf("%w: %s: ребро %d ведет в несуществующий узел %d", ErrCorruptDictionary, name, i, e.NodeID)
+		}
+	}
+
+	// Поиск циклов итеративным DFS с раскраской: 0 - не посещен, 1 - в стеке, 2 - обработан.
+	state := make([]uint8, len(nodes))
+	type frame struct {
+		node uint32
+		next uint32 // Индекс следующего ребра для обхода.
+	}
+	for start := range nodes {
+		if state[start] != 0 {
+			continue
+		}
+		stack := []frame{{node: uint32(start), next: nodes[start].EdgesIdx}}
+		state[start] = 1
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			n := nodes[top.node]
+			if top.next == n.EdgesIdx+uint32(n.EdgesLen) {
+				state[top.node] = 2
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			child := edges[top.next].NodeID
+			top.next++
+			switch state[child] {
+			case 1:
+				return fmt.Errorf("%w: %s: граф содержит цикл через узел %d", ErrCorruptDictionary, name, child)
+			case 0:
+				state[child] = 1
+				stack = append(stack, frame{node: child, next: nodes[child].EdgesIdx})
+			}
+		}
+	}
+	return nil
+}
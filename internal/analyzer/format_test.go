@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fuzzWords - слова, которыми проверяется анализатор, загруженный из поврежденного словаря.
+var fuzzWords = []string{"дома", "стали", "рублей", "сбербанк", "псевдодом", "гомами", "в", ""}
+
+func FuzzNewAnalyzer(f *testing.F) {
+	data := compileFixture(f, readFixture(f, "lexemes.tsv"))
+	f.Add(data)
+	f.Add(legacyImage(data))
+	for _, n := range []int{0, 3, legacyHeaderSize, headerSize, headerSize + 1, len(data) / 2, len(data) - 1} {
+		f.Add(append([]byte(nil), data[:n]...))
+	}
+	for _, pos := range []int{4, 12, 20, headerSize, headerSize + 40, len(data) / 2, len(data) - 8} {
+		for _, bit := range []byte{0x01, 0x80} {
+			d := append([]byte(nil), data...)
+			d[pos] ^= bit
+			f.Add(d)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Контрольная сумма при загрузке не проверяется: изменения доходят до проверки секций и графов.
+		checkLoad(t, data)
+		if err := verifyChecksum(data); err != nil && !errors.Is(err, ErrCorruptDictionary) {
+			t.Fatalf("ошибка проверки суммы не оборачивает ErrCorruptDictionary: %v", err)
+		}
+	})
+}
+
+// checkLoad загружает образ словаря: загрузка должна вернуть ErrCorruptDictionary
+// или анализатор, который разбирает и склоняет слова без паники.
+func checkLoad(t *testing.T, data []byte) {
+	a, err := LoadMorphAnalyzerFromBytes(append([]byte(nil), data...))
+	if err != nil {
+		if !errors.Is(err, ErrCorruptDictionary) {
+			t.Fatalf("ошибка загрузки не оборачивает ErrCorruptDictionary: %v", err)
+		}
+		return
+	}
+	defer a.Close()
+	for _, w := range fuzzWords {
+		a.Analyze(w)
+		a.ParsePredicted(w)
+		a.InflectTo(w, GrammemeSet{"Родительный": {}})
+		a.AgreeWithNumber(w, 5)
+	}
+}
+
+// legacyImage переписывает образ словаря в старый формат DAW7 (без версии и контрольной суммы),
+// который загрузчик принимает без проверки CRC.
+func legacyImage(data []byte) []byte {
+	var h Header
+	if _, err := binary.Decode(data[:headerSize], binary.LittleEndian, &h); err != nil {
+		panic(err)
+	}
+	d := append([]byte(nil), data...)
+	lh := legacyHeader{Sections: h.Sections}
+	copy(lh.Magic[:], legacyMagic)
+	if _, err := binary.Encode(d[:legacyHeaderSize], binary.LittleEndian, lh); err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := compileFixture(t, readFixture(t, "lexemes.tsv"))
+	if err := verifyChecksum(data); err != nil {
+		t.Fatalf("целый словарь: %v", err)
+	}
+	// Ищем изменение, которое проверка секций не замечает: его ловит только контрольная сумма
+	var flipped []byte
+	for pos := len(data) - 1; pos >= headerSize && flipped == nil; pos-- {
+		d := append([]byte(nil), data...)
+		d[pos] ^= 0x01
+		if a, err := LoadMorphAnalyzerFromBytes(d); err == nil {
+			a.Close()
+			flipped = d
+		}
+	}
+	if flipped == nil {
+		t.Skip("нет изменения, незаметного для проверки секций")
+	}
+	if err := verifyChecksum(flipped); !errors.Is(err, ErrCorruptDictionary) {
+		t.Errorf("verifyChecksum = %v, ожидается ErrCorruptDictionary", err)
+	}
+	if err := verifyChecksum(legacyImage(data)); err != nil {
+		t.Errorf("словарь DAW7 без суммы: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "morph.dawg")
+	if err := os.WriteFile(path, flipped, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyDictionary(path); !errors.Is(err, ErrCorruptDictionary) {
+		t.Errorf("VerifyDictionary = %v, ожидается ErrCorruptDictionary", err)
+	}
+	a, err := LoadMorphAnalyzerFrom(path)
+	if err != nil {
+		t.Fatalf("загрузка без проверки суммы: %v", err)
+	}
+	a.Close()
+	t.Setenv(EnvDictVerify, "1")
+	if _, err := LoadMorphAnalyzerFrom(path); !errors.Is(err, ErrCorruptDictionary) {
+		t.Errorf("загрузка с %s: %v, ожидается ErrCorruptDictionary", EnvDictVerify, err)
+	}
+}
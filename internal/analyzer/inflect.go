@@ -0,0 +1,293 @@
+// inflect.go реализует постановку слова в заданную грамматическую форму
+// (аналог `inflect` и `make_agree_with_number` из pymorphy).
+// Используется при генерации подсказок для грамматических исправлений
+// и в шаблонизаторе, которому нужно согласовать существительное с числом.
+package analyzer
+
+import (
+	"strconv"
+	"strings"
+)
+
+// lexemeForms - все словоформы одной лексемы, к которой относится исходное слово.
+type lexemeForms struct {
+	lemma  string      // Лемма лексемы.
+	source GrammemeSet // Граммемы исходной словоформы внутри этой лексемы.
+	forms  []*Parsed   // Все словоформы лексемы, включая омонимичные.
+}
+
+// findNode проходит по графу от корня и возвращает узел, в котором заканчивается слово.
+func (a *MorphAnalyzer) findNode(word string, nodes []FlatNode, edges []FlatEdge) (uint32, bool) {
+	currentNodeIndex := uint32(0)
+	for _, char := range word {
+		childNodeIndex, found := a.findChildGeneral(currentNodeIndex, char, nodes, edges)
+		if !found {
+			return 0, false
+		}
+		currentNodeIndex = childNodeIndex
+	}
+	return currentNodeIndex, true
+}
+
+// lexemesOf собирает лексемы, к которым может относиться слово.
+// Для словарных слов лексемы перечисляются в порядке payload'ов узла
+// (после лексем пользовательского лексикона), для несловарных используется лучшее предсказание.
+func (a *MorphAnalyzer) lexemesOf(word string) []lexemeForms {
+	lowerWord := strings.ToLower(word)
+	// Лексемы пользовательского лексикона идут первыми.
+	lexemes := a.overlay.lexemesOf(lowerWord)
+	nodeIndex, found := a.findNode(lowerWord, a.nodes, a.edges)
+	if found && a.nodes[nodeIndex].IsFinal {
+		node := a.nodes[nodeIndex]
+		seen := make(map[uint32]bool)
+		for _, info := range a.payloads[node.PayloadIdx : node.PayloadIdx+uint32(node.PayloadLen)] {
+			if seen[info.ParadigmID] {
+				continue
+			}
+			seen[info.ParadigmID] = true
+
+			lf := lexemeForms{lemma: a.LemmaPool[info.LemmaID], source: a.tags[info.TagsID].grammemes}
+			// Разные основы одной парадигмы могут давать пересекающиеся поддеревья,
+			// поэтому отсекаем повторы пары (форма, теги).
+			unique := make(map[string]bool)
+			for _, pInfo := range a.paradigms[info.ParadigmID] {
+				a.dfsVisit(pInfo.NodeID, []rune(pInfo.Stem), info.ParadigmID, func(form string, tagsID uint32) {
+					key := form + "\x00" + strconv.FormatUint(uint64(tagsID), 10)
+					if unique[key] {
+						return
+					}
+					unique[key] = true
+					lf.forms = append(lf.forms, newParsedTag(form, lf.lemma, &a.tags[tagsID], OriginDictionary))
+				})
+			}
+			lexemes = append(lexemes, lf)
+		}
+		return lexemes
+	}
+	if len(lexemes) > 0 {
+		return lexemes
+	}
+
+	// Несловарное слово: берем парадигму лучшего предсказания.
+	predicted := a.ParsePredicted(word)
+	if len(predicted) == 0 {
+		return nil
+	}
+	forms := a.Predict(word, predicted[0].Lemma)
+	if len(forms) == 0 {
+		return nil
+	}
+	return []lexemeForms{{lemma: predicted[0].Lemma, source: predicted[0].grammemes, forms: forms}}
+}
+
+// InflectTo ставит слово в форму, содержащую все граммемы из `required`
+// (например, {"Множественное число", "Родительный"} для "дом" → "домов").
+// Если подходящих форм несколько, выбирается та, что сохраняет больше граммем
+// исходной словоформы и вносит меньше посторонних изменений: "красивый" в
+// родительном падеже даст "красивого", а не "красивой".
+// Возвращает nil, если слово не удалось разобрать или такой формы у него нет.
+func (a *MorphAnalyzer) InflectTo(word string, required GrammemeSet) *Parsed {
+	var best *Parsed
+	bestScore := 0
+	for _, lf := range a.lexemesOf(word) {
+		for _, form := range lf.forms {
+			grammemes := form.grammemes
+			if !containsAll(grammemes, required) {
+				continue
+			}
+			// +1 за каждую сохраненную граммему исходной формы,
+			// -1 за каждую новую граммему, которую никто не просил.
+			score := 0
+			for g := range grammemes {
+				if inMap(g, lf.source) {
+					score++
+				} else if !inMap(g, required) {
+					score--
+				}
+			}
+			// Строгое сравнение: при равенстве остается более ранняя лексема/форма.
+			if best == nil || score > bestScore {
+				best, bestScore = form, score
+			}
+		}
+	}
+	return best
+}
+
+// NumeralAgreement возвращает граммемы, в которые нужно поставить словоформу `p`,
+// чтобы согласовать ее с числом `n`: "1 рубль", "2 рубля", "5 рублей".
+// Правила повторяют pymorphy: склоняются только существительные, прилагательные
+// и причастия; в косвенных падежах меняется лишь число. Для прочих частей речи возвращает nil.
+func NumeralAgreement(p *Parsed, n int) GrammemeSet {
+	if n < 0 {
+		n = -n
+	}
+
+	// 0 - "один", 1 - "два/три/четыре", 2 - "пять и больше" (а также 11–14).
+	var index int
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		index = 0
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 10 || n%100 >= 20):
+		index = 1
+	default:
+		index = 2
+	}
+
+	switch p.PartOfSpeech {
+	case "Существительное", "Прилагательное", "Причастие":
+	default:
+		return nil
+	}
+
+	nominative := p.Case == "" || p.Case == "Именительный" || p.Case == "Винительный"
+	switch {
+	case p.PartOfSpeech == "Существительное" && !nominative:
+		if index == 0 {
+			return GrammemeSet{"Единственное число": {}, p.Case: {}}
+		}
+		return GrammemeSet{"Множественное число": {}, p.Case: {}}
+	case index == 0:
+		if p.Case == "" || p.Case == "Именительный" {
+			return GrammemeSet{"Единственное число": {}, "Именительный": {}}
+		}
+		return GrammemeSet{"Единственное число": {}, p.Case: {}}
+	case p.PartOfSpeech == "Существительное" && index == 1:
+		return GrammemeSet{"Единственное число": {}, "Родительный": {}}
+	case p.Gender == "Женский" && index == 1:
+		// "две красивые", но "два красивых".
+		return GrammemeSet{"Множественное число": {}, "Именительный": {}}
+	default:
+		return GrammemeSet{"Множественное число": {}, "Родительный": {}}
+	}
+}
+
+// AgreeWithNumber согласует слово с числом `n` ("рубль", 5 → "рублей").
+// Если слово не склоняется по числу, возвращается его собственный разбор.
+func (a *MorphAnalyzer) AgreeWithNumber(word string, n int) *Parsed {
+	return a.InflectWithNumber(word, n, nil)
+}
+
+// InflectWithNumber согласует слово с числом `n` и ставит его в форму с граммемами
+// `required` за одну постановку внутри той же лексемы: "рубль", 2, {"Дательный"} → "рублям".
+// Падеж из `required` заменяет падеж исходной формы при согласовании ("двум рублям"),
+// прочие граммемы `required` добавляются к граммемам согласования и вытесняют их
+// в своей категории. Если слово не склоняется по числу, оно только ставится в `required`
+// (без `required` возвращается его собственный разбор).
+func (a *MorphAnalyzer) InflectWithNumber(word string, n int, required GrammemeSet) *Parsed {
+	parses := a.Parse(word)
+	if len(parses) == 0 {
+		parses = a.ParsePredicted(word)
+	}
+	if len(parses) == 0 {
+		return nil
+	}
+
+	requiredCase := ""
+	for g := range required {
+		if inMap(g, caseTags) {
+			requiredCase = g
+		}
+	}
+	// Предпочитаем разбор, который вообще можно согласовать с числом.
+	for _, p := range parses {
+		if requiredCase != "" && p.Case != requiredCase {
+			tag := *p.Tag
+			tag.Case = requiredCase
+			p = &Parsed{Word: p.Word, Lemma: p.Lemma, Tag: &tag}
+		}
+		agreement := NumeralAgreement(p, n)
+		if agreement == nil {
+			continue
+		}
+		if form := a.InflectTo(word, mergeGrammemes(agreement, required)); form != nil {
+			return form
+		}
+	}
+	if required != nil {
+		return a.InflectTo(word, required)
+	}
+	return parses[0]
+}
+
+// mergeGrammemes добавляет к граммемам согласования запрошенные граммемы. Запрошенное
+// число заменяет число согласования; падеж согласования уже выведен из запрошенного
+// ("пять рублей" в винительном - родительный падеж) и сохраняется.
+func mergeGrammemes(agreement, required GrammemeSet) GrammemeSet {
+	out := make(GrammemeSet, len(agreement)+len(required))
+	for g := range agreement {
+		out[g] = struct{}{}
+	}
+	for g := range required {
+		switch {
+		case inMap(g, caseTags):
+			continue
+		case inMap(g, numberTags):
+			for n := range numberTags {
+				delete(out, n)
+			}
+		}
+		out[g] = struct{}{}
+	}
+	return out
+}
+
+// containsAll проверяет, что множество `set` содержит все граммемы из `required`.
+func containsAll(set, required GrammemeSet) bool {
+	for g := range required {
+		if !inMap(g, set) {
+			return false
+		}
+	}
+	return true
+}
+
+// InflectLike строит парадигму слова `lemma` по образцу слова `like`: "сбербанк" как "банк"
+// даст "сбербанка", "сбербанку", ... Образец разбирается словарем (или предсказателем),
+// и его окончания переносятся на основу новой леммы. Если образец не задан,
+// парадигма берется из словаря (для словарной леммы) или предсказывается.
+// Возвращает nil, если лемма не согласуется с образцом или парадигму получить не удалось.
+func (a *MorphAnalyzer) InflectLike(lemma, like string) []*Parsed {
+	lemma = strings.ToLower(strings.TrimSpace(lemma))
+	like = strings.ToLower(strings.TrimSpace(like))
+	if lemma == "" {
+		return nil
+	}
+	if like == "" {
+		like = lemma
+	}
+
+	// Предпочитаем лексему, в которой образец является леммой ("банк" - существительное, а не форма).
+	lexemes := a.lexemesOf(like)
+	if len(lexemes) == 0 {
+		return nil
+	}
+	lf := lexemes[0]
+	for _, l := range lexemes {
+		if l.lemma == like {
+			lf = l
+			break
+		}
+	}
+	if like == lemma && lf.lemma == lemma {
+		return lf.forms
+	}
+
+	// Основа образца - общее начало его леммы и всех форм; остальное - окончания.
+	words := []string{lf.lemma}
+	for _, f := range lf.forms {
+		words = append(words, f.Word)
+	}
+	stem := commonPrefix(words)
+	lemmaEnding := strings.TrimPrefix(lf.lemma, stem)
+	newStem, ok := strings.CutSuffix(lemma, lemmaEnding)
+	if !ok {
+		return nil
+	}
+
+	forms := make([]*Parsed, 0, len(lf.forms))
+	for _, f := range lf.forms {
+		forms = append(forms, newParsedTag(newStem+strings.TrimPrefix(f.Word, stem), lemma, f.Tag, OriginPredicted))
+	}
+	return forms
+}
@@ -0,0 +1,147 @@
+package analyzer
+
+import "testing"
+
+func TestInflectTo(t *testing.T) {
+	a := loadFixture(t, "lexemes.tsv")
+	tests := []struct {
+		word     string
+		required []string
+		want     string // "" - формы нет
+	}{
+		{"дом", []string{"Множественное число", "Родительный"}, "домов"},
+		{"дома", []string{"Множественное число", "Дательный"}, "домам"},
+		{"рубль", []string{"Творительный"}, "рублём"},
+		{"рублей", []string{"Единственное число", "Именительный"}, "рубль"},
+		// Сохраняется род исходной формы: красивого, а не красивой
+		{"красивый", []string{"Родительный"}, "красивого"},
+		{"красивая", []string{"Родительный"}, "красивой"},
+		{"дом", []string{"Прошедшее"}, ""},
+		{"в", []string{"Родительный"}, ""},
+	}
+	for _, tt := range tests {
+		form := a.InflectTo(tt.word, grammemes(tt.required...))
+		if got := formWord(form); got != tt.want {
+			t.Errorf("InflectTo(%q, %v) = %q, ожидается %q", tt.word, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestNumeralAgreement(t *testing.T) {
+	a := loadFixture(t, "lexemes.tsv")
+	noun := firstParse(t, a, "рубль", "Именительный")
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{1, []string{"Единственное число", "Именительный"}},
+		{2, []string{"Единственное число", "Родительный"}},
+		{5, []string{"Множественное число", "Родительный"}},
+		{11, []string{"Множественное число", "Родительный"}},
+		{21, []string{"Единственное число", "Именительный"}},
+		{22, []string{"Единственное число", "Родительный"}},
+		{-3, []string{"Единственное число", "Родительный"}},
+	}
+	for _, tt := range tests {
+		if got := NumeralAgreement(noun, tt.n); !sameGrammemes(got, grammemes(tt.want...)) {
+			t.Errorf("NumeralAgreement(рубль, %d) = %v, ожидается %v", tt.n, got, tt.want)
+		}
+	}
+	// В косвенном падеже меняется только число
+	dative := firstParse(t, a, "рублю", "Дательный")
+	if got := NumeralAgreement(dative, 5); !sameGrammemes(got, grammemes("Множественное число", "Дательный")) {
+		t.Errorf("NumeralAgreement(рублю, 5) = %v", got)
+	}
+	if got := NumeralAgreement(firstParse(t, a, "идти", ""), 5); got != nil {
+		t.Errorf("NumeralAgreement(идти, 5) = %v, ожидается nil", got)
+	}
+}
+
+func TestAgreeWithNumber(t *testing.T) {
+	a := loadFixture(t, "lexemes.tsv")
+	tests := []struct {
+		word string
+		n    int
+		want string
+	}{
+		{"рубль", 1, "рубль"},
+		{"рубль", 2, "рубля"},
+		{"рубль", 5, "рублей"},
+		{"рубль", 11, "рублей"},
+		{"рубль", 21, "рубль"},
+		{"рубль", 22, "рубля"},
+		{"дом", 5, "домов"},
+		{"рублю", 5, "рублям"},
+		{"красивый", 2, "красивых"},
+		{"красивая", 2, "красивые"},
+		// Не склоняется по числу: возвращается собственный разбор
+		{"идти", 5, "идти"},
+	}
+	for _, tt := range tests {
+		if got := formWord(a.AgreeWithNumber(tt.word, tt.n)); got != tt.want {
+			t.Errorf("AgreeWithNumber(%q, %d) = %q, ожидается %q", tt.word, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestInflectWithNumber(t *testing.T) {
+	a := loadFixture(t, "lexemes.tsv")
+	tests := []struct {
+		word     string
+		n        int
+		required []string
+		want     string
+	}{
+		{"рубль", 2, []string{"Дательный"}, "рублям"},
+		{"рубль", 1, []string{"Дательный"}, "рублю"},
+		{"рубль", 21, []string{"Творительный"}, "рублём"},
+		{"рубль", 5, []string{"Предложный"}, "рублях"},
+		// В винительном числительное управляет родительным падежом: "пять рублей"
+		{"рубль", 5, []string{"Винительный"}, "рублей"},
+		{"рублей", 1, []string{"Именительный"}, "рубль"},
+		{"рубль", 5, nil, "рублей"},
+		// Запрошенное число вытесняет число согласования
+		{"рубль", 5, []string{"Единственное число", "Дательный"}, "рублю"},
+		{"идти", 5, nil, "идти"},
+	}
+	for _, tt := range tests {
+		var required GrammemeSet
+		if tt.required != nil {
+			required = grammemes(tt.required...)
+		}
+		if got := formWord(a.InflectWithNumber(tt.word, tt.n, required)); got != tt.want {
+			t.Errorf("InflectWithNumber(%q, %d, %v) = %q, ожидается %q", tt.word, tt.n, tt.required, got, tt.want)
+		}
+	}
+}
+
+func grammemes(gs ...string) GrammemeSet {
+	set := make(GrammemeSet, len(gs))
+	for _, g := range gs {
+		set[g] = struct{}{}
+	}
+	return set
+}
+
+func sameGrammemes(a, b GrammemeSet) bool {
+	return len(a) == len(b) && containsAll(a, b)
+}
+
+func formWord(p *Parsed) string {
+	if p == nil {
+		return ""
+	}
+	return p.Word
+}
+
+// firstParse возвращает разбор слова в падеже c (пустой падеж - первый разбор).
+func firstParse(tb testing.TB, a *MorphAnalyzer, word, c string) *Parsed {
+	tb.Helper()
+	for _, p := range a.Parse(word) {
+		if c == "" || p.Case == c {
+			return p
+		}
+	}
+	tb.Fatalf("нет разбора %q в падеже %q", word, c)
+	return nil
+}
@@ -0,0 +1,53 @@
+// lemmatize.go содержит лемматизацию произвольного текста:
+// текст разбивается на слова, и для каждого слова возвращается его лемма
+// вместе с позицией в исходной строке.
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// wordRe выделяет слова, включая написанные через дефис ("кто-то", "интернет-магазин").
+var wordRe = regexp.MustCompile(`[А-Яа-яЁёA-Za-z]+(?:-[А-Яа-яЁёA-Za-z]+)*`)
+
+// LemmaToken - лемма одного слова текста.
+// Смещения Start/End считаются в символах (рунах), а не в байтах.
+type LemmaToken struct {
+	Token  string `json:"token"`            // Слово в том виде, в каком оно встретилось в тексте.
+	Start  int    `json:"start"`            // Смещение начала слова.
+	End    int    `json:"end"`              // Смещение конца слова (не включительно).
+	Lemma  string `json:"lemma"`            // Нормальная форма лучшего разбора.
+	Origin string `json:"origin,omitempty"` // Происхождение разбора; пусто, если слово разобрать не удалось.
+}
+
+// Lemmatize разбивает текст на слова и возвращает лемму для каждого из них.
+// Для слов, которые не удалось ни найти в словаре, ни предсказать,
+// леммой считается само слово в нижнем регистре.
+func (a *MorphAnalyzer) Lemmatize(text string) []LemmaToken {
+	matches := wordRe.FindAllStringIndex(text, -1)
+	tokens := make([]LemmaToken, 0, len(matches))
+
+	// Переводим байтовые смещения в рунные за один проход по тексту.
+	runePos, bytePos := 0, 0
+	for _, m := range matches {
+		runePos += utf8.RuneCountInString(text[bytePos:m[0]])
+		start := runePos
+		runePos += utf8.RuneCountInString(text[m[0]:m[1]])
+		bytePos = m[1]
+
+		word := text[m[0]:m[1]]
+		tok := LemmaToken{Token: word, Start: start, End: runePos, Lemma: strings.ToLower(word)}
+		parses := a.Parse(word)
+		if len(parses) == 0 {
+			parses = a.ParsePredicted(word)
+		}
+		if len(parses) > 0 {
+			tok.Lemma = parses[0].Lemma
+			tok.Origin = parses[0].Origin
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
@@ -0,0 +1,197 @@
+// opencorpora.go описывает соответствие граммем OpenCorpora (NOUN, nomn, sing, ...)
+// названиям граммем, которые используются в строках тегов словаря morph.dawg
+// ("Существительное", "Именительный", "Единственное число", ...).
+// Таблица нужна компилятору словаря, чтобы собирать теги в привычном анализатору формате.
+package analyzer
+
+import "strings"
+
+// openCorporaPOS - части речи OpenCorpora. Некоторые из них в словаре выражаются
+// частью речи и дополнительной граммемой (краткое прилагательное, инфинитив и т.д.).
+var openCorporaPOS = map[string][]string{
+	"NOUN": {"Существительное"},
+	"ADJF": {"Прилагательное"},
+	"ADJS": {"Прилагательное", "Краткая форма"},
+	"COMP": {"Прилагательное", "Сравнительная степень"},
+	"VERB": {"Глагол"},
+	"INFN": {"Глагол", "Инфинитив"},
+	"PRTF": {"Причастие"},
+	"PRTS": {"Причастие", "Краткая форма"},
+	"GRND": {"Деепричастие"},
+	"NUMR": {"Числительное"},
+	"ADVB": {"Наречие"},
+	"NPRO": {"Местоимение"},
+	"PRED": {"Наречие", "Предикатив"},
+	"PREP": {"Предлог"},
+	"CONJ": {"Союз"},
+	"PRCL": {"Частица"},
+	"INTJ": {"Междометие"},
+}
+
+// openCorporaGrammemes - остальные граммемы OpenCorpora.
+// Пустое значение означает, что граммема в словаре не выражается и отбрасывается
+// (например, изъявительное наклонение, которое подразумевается по умолчанию).
+var openCorporaGrammemes = map[string]string{
+	// Одушевленность
+	"anim": "Одушевленное",
+	"inan": "Неодушевленное",
+	"Inmx": "одушевленное и неодушевленное",
+	// Вид
+	"perf": "Совершенный",
+	"impf": "Несовершенный",
+	// Падеж
+	"nomn": "Именительный",
+	"gent": "Родительный",
+	"datv": "Дательный",
+	"accs": "Винительный",
+	"ablt": "Творительный",
+	"loct": "Предложный",
+	"voct": "Звательный",
+	"gen2": "Партитивный",
+	"acc2": "Ждательный",
+	"loc2": "Местный",
+	"Coun": "Счетный",
+	"Fixd": "Несклоняемый",
+	// Род
+	"masc": "Мужской",
+	"femn": "Женский",
+	"neut": "Средний",
+	"Ms-f": "Общий",
+	"GNdr": "Парный",
+	// Наклонение
+	"impr": "Повелительное",
+	"indc": "",
+	// Число
+	"sing": "Единственное число",
+	"plur": "Множественное число",
+	"Sgtm": "Только единственное",
+	"Pltm": "Только множественное",
+	// Лицо
+	"1per": "1-е лицо",
+	"2per": "2-е лицо",
+	"3per": "3-е лицо",
+	// Время
+	"pres": "Настоящее",
+	"past": "Прошедшее",
+	"futr": "Будущее",
+	// Переходность
+	"tran": "Переходный",
+	"intr": "Непереходный",
+	// Залог
+	"actv": "Действительный",
+	"pssv": "Страдательный",
+	// Прочие
+	"incl": "Совместное",
+	"excl": "Несовместное",
+	"Supr": "Превосходная степень",
+	"Qual": "Качественное",
+	"Apro": "Местоименное",
+	"Anum": "Порядковое",
+	"Poss": "Притяжательное",
+	"Name": "Имя",
+	"Surn": "Фамилия",
+	"Patr": "Отчество",
+	"Geox": "Топоним",
+	"Orgn": "Организация",
+	"Trad": "Торговая марка",
+	"Abbr": "Аббревиатура",
+	"Init": "Инициал",
+	"Prnt": "Вводное слово",
+	"Infr": "Разговорное",
+	"Slng": "Жаргонное",
+	"Arch": "Устаревшее",
+	"Litr": "Литературное",
+	"Erro": "Опечатка",
+	"Dist": "Искажение",
+	"Impe": "Безличный",
+	"Impx": "Возможно безличный",
+	"Mult": "Многократный",
+	"Refl": "Возвратный",
+	"Anph": "Анафорическое",
+	"Subx": "Возможна субстантивация",
+	"Prdx": "Возможен предикатив",
+	"Af-p": "Форма после предлога",
+	"Vpre": "Вариант предлога",
+	"Cmp2": "Сравнительная степень на по-",
+	"V-ey": "Форма на -ею",
+	"V-oy": "Форма на -ою",
+	"V-ej": "Форма на -ей",
+	"V-be": "Форма на -ье",
+	"V-en": "Форма на -енен",
+	"V-ie": "Форма на -ие",
+	"V-bi": "Форма на -ьи",
+	"V-sh": "Деепричастие на -ши",
+	"Fimp": "Деепричастие от глагола несовершенного вида",
+	"Ques": "Вопросительное",
+	"Dmns": "Указательное",
+	"Adjx": "Возможно прилагательное",
+	"Ordx": "Возможно порядковое",
+	"Coll": "Собирательное",
+	"Hypo": "Гипотетическое",
+}
+
+// FromOpenCorpora переводит набор граммем OpenCorpora в строку тегов словаря.
+// Часть речи ставится первой, как того ожидает `newParsed`; повторы отбрасываются.
+// Неизвестные коды (и граммемы, уже записанные в формате словаря) переносятся без изменений.
+func FromOpenCorpora(grammemes []string) string {
+	var pos, rest []string
+	seen := make(map[string]bool)
+	add := func(dst *[]string, g string) {
+		if g == "" || seen[g] {
+			return
+		}
+		seen[g] = true
+		*dst = append(*dst, g)
+	}
+
+	for _, code := range grammemes {
+		code = strings.TrimSpace(code)
+		if names, ok := openCorporaPOS[code]; ok {
+			add(&pos, names[0])
+			for _, extra := range names[1:] {
+				add(&rest, extra)
+			}
+			continue
+		}
+		if inMap(code, posTags) {
+			add(&pos, code)
+			continue
+		}
+		if name, ok := openCorporaGrammemes[code]; ok {
+			add(&rest, name)
+			continue
+		}
+		add(&rest, code)
+	}
+	return strings.Join(append(pos, rest...), ",")
+}
+
+// NormalizeTags переводит строку тегов из внешнего источника (размеченного корпуса,
+// TSV-словаря) в формат словаря. Граммемы разделяются запятыми; допускается и запись
+// pymorphy ("NOUN,inan,masc sing,nomn"), где часть граммем разделена пробелом.
+// Названия граммем словаря сами содержат пробелы ("Единственное число"), поэтому
+// пробел считается разделителем только в частях без кириллицы.
+func NormalizeTags(tags string) string {
+	var grammemes []string
+	for _, part := range strings.Split(tags, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if strings.Contains(part, " ") && !hasCyrillic(part) {
+			grammemes = append(grammemes, strings.Fields(part)...)
+			continue
+		}
+		grammemes = append(grammemes, part)
+	}
+	return FromOpenCorpora(grammemes)
+}
+
+func hasCyrillic(s string) bool {
+	for _, r := range s {
+		if r >= 'А' && r <= 'я' || r == 'ё' || r == 'Ё' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+// overlay.go содержит пользовательский лексикон поверх словаря: лексемы (бренды, термины,
+// жаргон), которые добавляются во время работы вместе с формами и тегами.
+// Словарь morph.dawg отображен в память только для чтения, поэтому такие лексемы хранятся
+// отдельно в памяти и просматриваются раньше словаря в Parse, Inflect и Analyze.
+// Сохранение лексикона между перезапусками - забота вызывающей стороны (см. customdict).
+// Lexicon - такой же лексикон без словаря, для лексем, видимых не всем пользователям анализатора.
+package analyzer
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OriginUser - значение `Parsed.Origin` для разборов из пользовательского лексикона.
+const OriginUser = "user"
+
+// overlayForm - словоформа пользовательской лексемы с разобранным тегом.
+type overlayForm struct {
+	word, lemma string
+	tag         *Tag
+}
+
+// overlay - изменяемый пользовательский лексикон. Все методы безопасны для конкурентного вызова.
+type overlay struct {
+	mu      sync.RWMutex
+	lexemes map[string]Lexeme         // Лемма -> исходная лексема (для выгрузки).
+	byLemma map[string][]overlayForm  // Лемма -> все формы лексемы.
+	byForm  map[string][]*overlayForm // Словоформа -> формы всех лексем, где она встречается.
+}
+
+// AddLexeme добавляет лексему в пользовательский лексикон, заменяя лексему с той же леммой.
+// Теги форм могут быть записаны в формате словаря или кодами OpenCorpora; часть речи обязательна.
+// Замена атомарна: конкурентный Parse видит либо старые, либо новые формы;
+// повторное добавление той же лексемы ничего не меняет.
+func (a *MorphAnalyzer) AddLexeme(lx Lexeme) error {
+	return a.overlay.add(lx)
+}
+
+// RemoveLexeme удаляет лексему из пользовательского лексикона.
+// Возвращает false, если лексемы с такой леммой не было.
+func (a *MorphAnalyzer) RemoveLexeme(lemma string) bool {
+	return a.overlay.remove(lemma)
+}
+
+// UserLexemes возвращает все лексемы пользовательского лексикона, отсортированные по лемме.
+func (a *MorphAnalyzer) UserLexemes() []Lexeme {
+	return a.overlay.list()
+}
+
+// Lexicon - пользовательский лексикон отдельно от анализатора: например, лексемы одного
+// тенанта, которые не должны попадать в разборы остальных. Его разборы возвращает только
+// Lexicon.Parse, Parse и Inflect анализатора их не видят. Нулевое значение готово к работе.
+type Lexicon struct {
+	o overlay
+}
+
+// NewLexicon создает пустой пользовательский лексикон.
+func NewLexicon() *Lexicon {
+	return &Lexicon{}
+}
+
+// AddLexeme добавляет лексему, заменяя лексему с той же леммой (см. MorphAnalyzer.AddLexeme).
+func (l *Lexicon) AddLexeme(lx Lexeme) error {
+	return l.o.add(lx)
+}
+
+// RemoveLexeme удаляет лексему; возвращает false, если лексемы с такой леммой не было.
+func (l *Lexicon) RemoveLexeme(lemma string) bool {
+	return l.o.remove(lemma)
+}
+
+// Parse возвращает разборы слова из лексикона (Origin = OriginUser) или nil.
+func (l *Lexicon) Parse(word string) []*Parsed {
+	return l.o.parse(word, strings.ToLower(word))
+}
+
+func (o *overlay) add(lx Lexeme) error {
+	lemma := strings.ToLower(strings.TrimSpace(lx.Lemma))
+	if lemma == "" || len(lx.Forms) == 0 {
+		return fmt.Errorf("лексема должна содержать лемму и хотя бы одну форму")
+	}
+	norm := Lexeme{Lemma: lemma, Forms: make([]LexemeForm, 0, len(lx.Forms))}
+	forms := make([]overlayForm, 0, len(lx.Forms))
+	for _, f := range lx.Forms {
+		word := strings.ToLower(strings.TrimSpace(f.Word))
+		tags := NormalizeTags(f.Tags)
+		if word == "" {
+			return fmt.Errorf("лексема %q: пустая словоформа", lemma)
+		}
+		tag := decodeTag(tags)
+		if tag.PartOfSpeech == "" {
+			return fmt.Errorf("лексема %q: у формы %q не указана часть речи", lemma, word)
+		}
+		norm.Forms = append(norm.Forms, LexemeForm{Word: word, Tags: tags})
+		forms = append(forms, overlayForm{word: word, lemma: lemma, tag: tag})
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.lexemes == nil {
+		o.lexemes = make(map[string]Lexeme)
+		o.byLemma = make(map[string][]overlayForm)
+		o.byForm = make(map[string][]*overlayForm)
+	}
+	if prev, ok := o.lexemes[lemma]; ok && slices.Equal(prev.Forms, norm.Forms) {
+		return nil
+	}
+	o.removeLocked(lemma)
+	o.lexemes[lemma] = norm
+	o.byLemma[lemma] = forms
+	for i := range forms {
+		f := &forms[i]
+		o.byForm[f.word] = append(o.byForm[f.word], f)
+	}
+	return nil
+}
+
+func (o *overlay) remove(lemma string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.removeLocked(strings.ToLower(strings.TrimSpace(lemma)))
+}
+
+func (o *overlay) list() []Lexeme {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	out := make([]Lexeme, 0, len(o.lexemes))
+	for _, lx := range o.lexemes {
+		out = append(out, lx)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Lemma < out[j].Lemma })
+	return out
+}
+
+func (o *overlay) removeLocked(lemma string) bool {
+	forms, ok := o.byLemma[lemma]
+	if !ok {
+		return false
+	}
+	for _, f := range forms {
+		rest := o.byForm[f.word][:0]
+		for _, g := range o.byForm[f.word] {
+			if g.lemma != lemma {
+				rest = append(rest, g)
+			}
+		}
+		if len(rest) == 0 {
+			delete(o.byForm, f.word)
+		} else {
+			o.byForm[f.word] = rest
+		}
+	}
+	delete(o.byLemma, lemma)
+	delete(o.lexemes, lemma)
+	return true
+}
+
+// parse возвращает разборы слова из пользовательского лексикона.
+func (o *overlay) parse(word, lowerWord string) []*Parsed {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	entries := o.byForm[lowerWord]
+	if len(entries) == 0 {
+		return nil
+	}
+	results := make([]*Parsed, 0, len(entries))
+	for _, f := range entries {
+		results = append(results, newParsedTag(word, f.lemma, f.tag, OriginUser))
+	}
+	return results
+}
+
+// lexemesOf возвращает все формы пользовательских лексем, в которые входит слово.
+func (o *overlay) lexemesOf(lowerWord string) []lexemeForms {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var out []lexemeForms
+	seen := make(map[string]bool)
+	for _, entry := range o.byForm[lowerWord] {
+		if seen[entry.lemma] {
+			continue
+		}
+		seen[entry.lemma] = true
+		lf := lexemeForms{lemma: entry.lemma, source: entry.tag.grammemes}
+		for _, f := range o.byLemma[entry.lemma] {
+			lf.forms = append(lf.forms, newParsedTag(f.word, f.lemma, f.tag, OriginUser))
+		}
+		out = append(out, lf)
+	}
+	return out
+}
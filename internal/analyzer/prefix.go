@@ -0,0 +1,133 @@
+// prefix.go содержит предсказание несловарных слов, которые раскладываются
+// на известную часть и словарное слово (как KnownPrefixAnalyzer и
+// HyphenSeparatedParticleAnalyzer в pymorphy):
+//   - продуктивная приставка + словарное слово: "псевдонаучный" = "псевдо" + "научный";
+//   - слово через дефис разбирается по последней части: "интернет-магазина" = "интернет-" + "магазина";
+//   - слово с частицей через дефис разбирается по первой части: "кого-нибудь" = "кого" + "-нибудь".
+//
+// Неизменяемая часть переносится в словоформу и лемму, а теги берутся у разбора словарной части.
+package analyzer
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// knownPrefixes - продуктивные приставки и первые части сложных слов,
+// которые присоединяются к слову без изменения его словоизменения.
+var knownPrefixes = []string{
+	"авиа", "авто", "агро", "анти", "архи", "аудио", "аэро", "био", "вело", "видео",
+	"вице", "гео", "гидро", "гипер", "евро", "зоо", "интер", "квази", "кибер", "кино",
+	"контр", "макро", "мега", "медиа", "метео", "микро", "мини", "мото", "мульти", "нано",
+	"нео", "недо", "около", "пост", "пра", "псевдо", "радио", "сверх", "спорт", "стерео",
+	"супер", "теле", "термо", "транс", "турбо", "ультра", "фото", "экзо", "эко", "экс",
+	"экстра", "электро", "энерго",
+}
+
+// hyphenParticles - частицы, которые пишутся через дефис и не меняют разбор слова.
+var hyphenParticles = []string{"то", "либо", "нибудь", "ка", "таки", "де", "с"}
+
+// minAffixRemainder - минимальная длина словарной части (в рунах), чтобы разложение
+// не подбирало случайные короткие слова ("антиквар" != "анти" + "квар").
+const minAffixRemainder = 3
+
+func init() {
+	// Длинные приставки проверяются первыми ("экстра" раньше "экс").
+	sort.Slice(knownPrefixes, func(i, j int) bool {
+		return utf8.RuneCountInString(knownPrefixes[i]) > utf8.RuneCountInString(knownPrefixes[j])
+	})
+}
+
+// affixSplit - разложение слова: head + rest + tail, где rest разбирается анализатором.
+type affixSplit struct {
+	head, rest, tail string
+	parses           []*Parsed // Разборы rest.
+}
+
+// splitAffix ищет первое подходящее разложение слова (в нижнем регистре).
+func (a *MorphAnalyzer) splitAffix(lowerWord string) (affixSplit, bool) {
+	if i := strings.LastIndex(lowerWord, "-"); i > 0 && i < len(lowerWord)-1 {
+		head, last := lowerWord[:i], lowerWord[i+1:]
+		// Частица: разбираем то, что стоит перед ней.
+		for _, particle := range hyphenParticles {
+			if last != particle || strings.Contains(head, "-") {
+				continue
+			}
+			parses := a.Parse(head)
+			if len(parses) == 0 {
+				parses = a.parseBySuffix(head)
+			}
+			if len(parses) > 0 {
+				return affixSplit{rest: head, tail: "-" + last, parses: parses}, true
+			}
+		}
+		// Сложное слово: изменяется только последняя часть.
+		parses := a.Parse(last)
+		if len(parses) == 0 {
+			parses = a.parseBySuffix(last)
+		}
+		if len(parses) > 0 {
+			return affixSplit{head: lowerWord[:i+1], rest: last, parses: parses}, true
+		}
+		return affixSplit{}, false
+	}
+
+	for _, prefix := range knownPrefixes {
+		rest, ok := strings.CutPrefix(lowerWord, prefix)
+		if !ok || utf8.RuneCountInString(rest) < minAffixRemainder {
+			continue
+		}
+		if parses := a.Parse(rest); len(parses) > 0 {
+			return affixSplit{head: prefix, rest: rest, parses: parses}, true
+		}
+	}
+	return affixSplit{}, false
+}
+
+// parseByAffix предсказывает разборы слова по разборам его словарной части.
+func (a *MorphAnalyzer) parseByAffix(word string) []*Parsed {
+	split, ok := a.splitAffix(strings.ToLower(word))
+	if !ok {
+		return nil
+	}
+	results := make([]*Parsed, 0, len(split.parses))
+	for _, p := range split.parses {
+		np := newParsedTag(word, split.head+p.Lemma+split.tail, p.Tag, OriginPredicted)
+		np.Score = p.Score
+		results = append(results, np)
+	}
+	return results
+}
+
+// predictByAffix генерирует словоформы слова по словоформам его словарной части.
+// Если у части несколько лексем, берутся формы той, что соответствует `lemma`.
+func (a *MorphAnalyzer) predictByAffix(word, lemma string) []*Parsed {
+	split, ok := a.splitAffix(strings.ToLower(word))
+	if !ok {
+		return nil
+	}
+	restLemma := strings.TrimSuffix(strings.TrimPrefix(lemma, split.head), split.tail)
+
+	var forms []*Parsed
+	if split.parses[0].Origin != OriginPredicted {
+		forms = a.Inflect(split.rest)
+	} else {
+		forms = a.predictBySuffix(split.rest, restLemma)
+	}
+	matching := forms[:0:0]
+	for _, f := range forms {
+		if f.Lemma == restLemma {
+			matching = append(matching, f)
+		}
+	}
+	if len(matching) == 0 {
+		matching = forms
+	}
+
+	results := make([]*Parsed, 0, len(matching))
+	for _, f := range matching {
+		results = append(results, newParsedTag(split.head+f.Word+split.tail, lemma, f.Tag, OriginPredicted))
+	}
+	return results
+}
@@ -0,0 +1,78 @@
+package analyzer
+
+import "testing"
+
+func TestParseByAffix(t *testing.T) {
+	a := loadFixture(t, "lexemes.tsv")
+	tests := []struct {
+		word  string
+		lemma string
+		c     string // Падеж, который должен быть среди разборов
+	}{
+		{"псевдодом", "псевдодом", "Именительный"},
+		{"псевдодома", "псевдодом", "Родительный"},
+		{"Псевдодомами", "псевдодом", "Творительный"},
+		// "экстра" проверяется раньше "экс"
+		{"экстрадому", "экстрадом", "Дательный"},
+		{"интернет-банка", "интернет-банк", "Родительный"},
+		{"интернет-банкам", "интернет-банк", "Дательный"},
+		{"мини-рублей", "мини-рубль", "Родительный"},
+		// Частица через дефис: разбирается часть перед ней
+		{"дома-то", "дом-то", "Родительный"},
+	}
+	for _, tt := range tests {
+		parses := a.ParsePredicted(tt.word)
+		found := false
+		for _, p := range parses {
+			if p.Word != tt.word || p.Origin != OriginPredicted {
+				t.Errorf("%q: разбор %+v", tt.word, p)
+			}
+			found = found || p.Lemma == tt.lemma && p.Case == tt.c
+		}
+		if !found {
+			t.Errorf("%q: нет разбора с леммой %q в падеже %q среди %d разборов", tt.word, tt.lemma, tt.c, len(parses))
+		}
+	}
+
+	// Разложение не срабатывает
+	for _, word := range []string{
+		"антиквар",  // "квар" - не словарное слово
+		"автов",     // словарная часть короче minAffixRemainder
+		"дом",       // нет приставки
+		"интернет-", // дефис в конце
+		"-дом",      // дефис в начале
+		"банкдом",   // "банк" - не приставка
+	} {
+		if parses := a.parseByAffix(word); len(parses) != 0 {
+			t.Errorf("parseByAffix(%q) = %s %s, ожидается пусто", word, parses[0].Lemma, parses[0].Tags)
+		}
+	}
+}
+
+func TestPredictByAffix(t *testing.T) {
+	a := loadFixture(t, "lexemes.tsv")
+	forms := map[string]bool{}
+	for _, f := range a.Predict("интернет-банка", "интернет-банк") {
+		if f.Lemma != "интернет-банк" {
+			t.Errorf("форма %q с леммой %q", f.Word, f.Lemma)
+		}
+		forms[f.Word] = true
+	}
+	for _, want := range []string{"интернет-банк", "интернет-банку", "интернет-банками"} {
+		if !forms[want] {
+			t.Errorf("нет формы %q среди %v", want, forms)
+		}
+	}
+	if f := a.Predict("псевдодом", "псевдодом"); len(f) == 0 || !containsWord(f, "псевдодомов") {
+		t.Errorf("формы псевдодом: %d", len(f))
+	}
+}
+
+func containsWord(forms []*Parsed, word string) bool {
+	for _, f := range forms {
+		if f.Word == word {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+// source.go читает исходные словари для Builder: XML-выгрузку OpenCorpora
+// (dict.opcorpora.xml) и простой TSV-формат лексем.
+package analyzer
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlLemma соответствует элементу <lemma> словаря OpenCorpora.
+type xmlLemma struct {
+	L xmlForm   `xml:"l"`
+	F []xmlForm `xml:"f"`
+}
+
+type xmlForm struct {
+	T string `xml:"t,attr"`
+	G []struct {
+		V string `xml:"v,attr"`
+	} `xml:"g"`
+}
+
+func (f xmlForm) grammemes() []string {
+	out := make([]string, len(f.G))
+	for i, g := range f.G {
+		out[i] = g.V
+	}
+	return out
+}
+
+// ReadOpenCorpora потоково читает dict.opcorpora.xml и добавляет лексемы в `b`:
+// граммемы леммы (<l>) общие для всех словоформ, граммемы формы (<f>) добавляются к ним.
+func ReadOpenCorpora(r io.Reader, b *Builder) error {
+	dec := xml.NewDecoder(bufio.NewReader(r))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "lemma" {
+			continue
+		}
+		var lemma xmlLemma
+		if err := dec.DecodeElement(&lemma, &start); err != nil {
+			return err
+		}
+		lex := Lexeme{Lemma: lemma.L.T}
+		common := lemma.L.grammemes()
+		for _, f := range lemma.F {
+			lex.Forms = append(lex.Forms, LexemeForm{
+				Word: f.T,
+				Tags: FromOpenCorpora(append(append([]string{}, common...), f.grammemes()...)),
+			})
+		}
+		if len(lex.Forms) == 0 {
+			continue
+		}
+		if err := b.Add(lex); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadTSV читает лексемы в TSV-формате и добавляет их в `b`. Лексемы разделяются
+// пустой строкой, каждая строка - словоформа и ее теги через табуляцию; первая строка
+// лексемы - лемма. Теги записываются в формате словаря или кодами OpenCorpora
+// (см. NormalizeTags). Строки, начинающиеся с '#', пропускаются.
+func ReadTSV(r io.Reader, b *Builder) error {
+	var lex Lexeme
+	flush := func() error {
+		if len(lex.Forms) == 0 {
+			return nil
+		}
+		err := b.Add(lex)
+		lex = Lexeme{}
+		return err
+	}
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for s.Scan() {
+		lineNo++
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line == "" {
+			if err := flush(); err != nil {
+				return fmt.Errorf("строка %d: %w", lineNo, err)
+			}
+			continue
+		}
+		word, tags, ok := strings.Cut(line, "\t")
+		if !ok {
+			return fmt.Errorf("строка %d: ожидается \"словоформа<TAB>теги\"", lineNo)
+		}
+		if lex.Lemma == "" {
+			lex.Lemma = word
+		}
+		lex.Forms = append(lex.Forms, LexemeForm{
+			Word: word,
+			Tags: NormalizeTags(tags),
+		})
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
@@ -0,0 +1,89 @@
+// stats.go содержит статистику тегов по размеченному корпусу, по которой
+// оцениваются вероятности словарных разборов P(теги | слово).
+package analyzer
+
+import "sort"
+
+// tagCount - сколько раз слово встретилось в корпусе с данным набором граммем.
+type tagCount struct {
+	grammemes GrammemeSet
+	count     float64
+}
+
+// TagStats - частоты тегов слов размеченного корпуса. После создания не изменяется.
+type TagStats struct {
+	words map[string][]tagCount
+}
+
+// NewTagStats собирает статистику тегов по предложениям размеченного корпуса
+// (см. ReadTaggedCorpus).
+func NewTagStats(sentences [][]TaggedToken) *TagStats {
+	index := make(map[string]map[string]int)
+	s := &TagStats{words: make(map[string][]tagCount)}
+	for _, sent := range sentences {
+		for _, tok := range sent {
+			byTags := index[tok.Word]
+			if byTags == nil {
+				byTags = make(map[string]int)
+				index[tok.Word] = byTags
+			}
+			i, ok := byTags[tok.Tags]
+			if !ok {
+				i = len(s.words[tok.Word])
+				byTags[tok.Tags] = i
+				s.words[tok.Word] = append(s.words[tok.Word], tagCount{grammemes: grammemesOf(tok.Tags)})
+			}
+			s.words[tok.Word][i].count++
+		}
+	}
+	return s
+}
+
+// SetTagStats подключает статистику корпуса к оценке разборов (nil отключает ее).
+// Безопасно вызывать конкурентно с разбором.
+func (a *MorphAnalyzer) SetTagStats(s *TagStats) {
+	a.stats.Store(s)
+}
+
+// scoreParses заполняет Score словарных разборов слова и сортирует их по убыванию.
+// Теги корпуса и словаря могут отличаться детализацией, поэтому каждое наблюдение
+// корпуса засчитывается разборам, с которыми у него больше всего общих граммем.
+// Оценка сглажена по Лапласу: слово без статистики получает равные оценки разборов.
+func (a *MorphAnalyzer) scoreParses(lowerWord string, parses []*Parsed) {
+	if len(parses) == 0 {
+		return
+	}
+	counts := make([]float64, len(parses))
+	var total float64
+	if s := a.stats.Load(); s != nil {
+		for _, tc := range s.words[lowerWord] {
+			best, matched := 0, []int(nil)
+			for i, p := range parses {
+				overlap := 0
+				for g := range tc.grammemes {
+					if _, ok := p.grammemes[g]; ok {
+						overlap++
+					}
+				}
+				switch {
+				case overlap > best:
+					best, matched = overlap, []int{i}
+				case overlap == best && overlap > 0:
+					matched = append(matched, i)
+				}
+			}
+			for _, i := range matched {
+				counts[i] += tc.count / float64(len(matched))
+			}
+			if len(matched) > 0 {
+				total += tc.count
+			}
+		}
+	}
+
+	const smoothing = 1.0
+	for i, p := range parses {
+		p.Score = (counts[i] + smoothing) / (total + smoothing*float64(len(parses)))
+	}
+	sort.SliceStable(parses, func(i, j int) bool { return parses[i].Score > parses[j].Score })
+}
@@ -0,0 +1,312 @@
+// tagger.go содержит легкий статистический теггер (скрытая марковская модель по частям речи),
+// который снимает омонимию разборов с учетом контекста предложения.
+// Модель обучается по размеченному корпусу: вероятности переходов между частями речи
+// и распределения частей речи для известных слов. Для каждого токена считаются
+// апостериорные вероятности частей речи алгоритмом forward-backward,
+// и они переносятся на разборы (`Parsed.Probability`).
+package analyzer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// TaggedToken - слово размеченного корпуса с его тегами в формате словаря.
+type TaggedToken struct {
+	Word string
+	Tags string
+}
+
+// ReadTaggedCorpus читает размеченный корпус: по токену на строке ("слово<TAB>теги"),
+// предложения разделены пустой строкой, строки с '#' в начале пропускаются.
+// Теги могут быть записаны как в формате словаря, так и кодами OpenCorpora;
+// значимы только первые две колонки.
+func ReadTaggedCorpus(r io.Reader) ([][]TaggedToken, error) {
+	var sentences [][]TaggedToken
+	var cur []TaggedToken
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for s.Scan() {
+		lineNo++
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line == "" {
+			if len(cur) > 0 {
+				sentences = append(sentences, cur)
+				cur = nil
+			}
+			continue
+		}
+		cols := strings.Split(line, "\t")
+		if len(cols) < 2 {
+			return nil, fmt.Errorf("строка %d: ожидается \"слово<TAB>теги\"", lineNo)
+		}
+		cur = append(cur, TaggedToken{Word: strings.ToLower(cols[0]), Tags: NormalizeTags(cols[1])})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(cur) > 0 {
+		sentences = append(sentences, cur)
+	}
+	return sentences, nil
+}
+
+// Tagger - скрытая марковская модель первого порядка над частями речи.
+// После обучения модель не изменяется и безопасна для конкурентного использования.
+type Tagger struct {
+	states []string       // Части речи; последняя (пустая строка) - разборы без части речи.
+	index  map[string]int // Часть речи -> номер состояния.
+	start  []float64      // P(t | начало предложения)
+	trans  [][]float64    // P(t_j | t_i)
+	prior  []float64      // P(t)
+	// lexicon - сколько раз слово корпуса встретилось с каждой частью речи.
+	lexicon map[string][]float64
+}
+
+// taggerSmoothing - аддитивное сглаживание счетчиков модели.
+const taggerSmoothing = 1.0
+
+// TrainTagger обучает теггер по размеченным предложениям.
+func TrainTagger(sentences [][]TaggedToken) *Tagger {
+	t := &Tagger{index: make(map[string]int), lexicon: make(map[string][]float64)}
+	for pos := range posTags {
+		t.states = append(t.states, pos)
+	}
+	sort.Strings(t.states)
+	t.states = append(t.states, "")
+	for i, pos := range t.states {
+		t.index[pos] = i
+	}
+
+	n := len(t.states)
+	startCounts := make([]float64, n)
+	transCounts := make([][]float64, n)
+	for i := range transCounts {
+		transCounts[i] = make([]float64, n)
+	}
+	tagCounts := make([]float64, n)
+
+	for _, sent := range sentences {
+		prev := -1
+		for _, tok := range sent {
+			s := t.state(partOfSpeech(tok.Tags))
+			tagCounts[s]++
+			if prev < 0 {
+				startCounts[s]++
+			} else {
+				transCounts[prev][s]++
+			}
+			prev = s
+
+			counts := t.lexicon[tok.Word]
+			if counts == nil {
+				counts = make([]float64, n)
+				t.lexicon[tok.Word] = counts
+			}
+			counts[s]++
+		}
+	}
+
+	t.start = normalize(startCounts, taggerSmoothing)
+	t.prior = normalize(tagCounts, taggerSmoothing)
+	t.trans = make([][]float64, n)
+	for i := range transCounts {
+		t.trans[i] = normalize(transCounts[i], taggerSmoothing)
+	}
+	return t
+}
+
+// Disambiguate ранжирует разборы токенов предложения с учетом контекста.
+// sentence[i] - все разборы i-го слова (например, результат `Parse`).
+// Возвращаются копии разборов с заполненным полем Probability,
+// отсортированные по убыванию вероятности; исходные объекты не изменяются,
+// так что разборы из кэшей можно передавать как есть.
+// Токены без разборов разрывают цепочку: контекст по обе стороны от них считается независимым.
+func (t *Tagger) Disambiguate(sentence [][]*Parsed) [][]*Parsed {
+	out := make([][]*Parsed, len(sentence))
+	for lo := 0; lo < len(sentence); {
+		if len(sentence[lo]) == 0 {
+			out[lo] = []*Parsed{}
+			lo++
+			continue
+		}
+		hi := lo
+		for hi < len(sentence) && len(sentence[hi]) > 0 {
+			hi++
+		}
+		t.disambiguateSpan(sentence[lo:hi], out[lo:hi])
+		lo = hi
+	}
+	return out
+}
+
+// disambiguateSpan выполняет forward-backward для непрерывной цепочки разобранных токенов.
+// Состояниями каждого токена являются только части речи его разборов.
+func (t *Tagger) disambiguateSpan(span [][]*Parsed, out [][]*Parsed) {
+	cands := make([][]int, len(span))
+	emit := make([][]float64, len(span))
+	for i, parses := range span {
+		cands[i] = t.candidates(parses)
+		emit[i] = t.emission(parses[0].Word, cands[i])
+	}
+
+	// Прямой проход с нормировкой на каждом шаге (чтобы не уходить в ноль на длинных предложениях).
+	alpha := make([][]float64, len(span))
+	for i := range span {
+		alpha[i] = make([]float64, len(cands[i]))
+		for j, s := range cands[i] {
+			var p float64
+			if i == 0 {
+				p = t.start[s]
+			} else {
+				for k, prev := range cands[i-1] {
+					p += alpha[i-1][k] * t.trans[prev][s]
+				}
+			}
+			alpha[i][j] = p * emit[i][j]
+		}
+		scale(alpha[i])
+	}
+
+	// Обратный проход.
+	beta := make([][]float64, len(span))
+	for i := len(span) - 1; i >= 0; i-- {
+		beta[i] = make([]float64, len(cands[i]))
+		for j, s := range cands[i] {
+			if i == len(span)-1 {
+				beta[i][j] = 1
+				continue
+			}
+			for k, next := range cands[i+1] {
+				beta[i][j] += t.trans[s][next] * emit[i+1][k] * beta[i+1][k]
+			}
+		}
+		scale(beta[i])
+	}
+
+	for i, parses := range span {
+		post := make(map[int]float64, len(cands[i]))
+		for j, s := range cands[i] {
+			post[s] = alpha[i][j] * beta[i][j]
+		}
+		// Вероятность части речи делится между ее разборами (например, падежами)
+		// пропорционально их Score, а без оценок - поровну.
+		perState := make(map[int]float64)
+		for _, p := range parses {
+			perState[t.state(p.PartOfSpeech)] += parseWeight(p)
+		}
+
+		var total float64
+		for _, v := range post {
+			total += v
+		}
+		ranked := make([]*Parsed, len(parses))
+		for j, p := range parses {
+			cp := *p
+			s := t.state(p.PartOfSpeech)
+			if total > 0 {
+				cp.Probability = post[s] / total * parseWeight(p) / perState[s]
+			}
+			ranked[j] = &cp
+		}
+		sort.SliceStable(ranked, func(a, b int) bool { return ranked[a].Probability > ranked[b].Probability })
+		out[i] = ranked
+	}
+}
+
+// parseWeight - вес разбора внутри своей части речи.
+func parseWeight(p *Parsed) float64 {
+	if p.Score > 0 {
+		return p.Score
+	}
+	return 1
+}
+
+// candidates возвращает различные состояния (части речи) разборов токена.
+func (t *Tagger) candidates(parses []*Parsed) []int {
+	var states []int
+	seen := make(map[int]bool)
+	for _, p := range parses {
+		s := t.state(p.PartOfSpeech)
+		if !seen[s] {
+			seen[s] = true
+			states = append(states, s)
+		}
+	}
+	return states
+}
+
+// emission оценивает P(слово | t) с точностью до множителя через P(t | слово) / P(t).
+// P(t | слово) берется из корпуса, а для неизвестных слов - равномерно по частям речи разборов.
+func (t *Tagger) emission(word string, cands []int) []float64 {
+	counts := t.lexicon[strings.ToLower(word)]
+	var total float64
+	for _, s := range cands {
+		if counts != nil {
+			total += counts[s]
+		}
+	}
+	out := make([]float64, len(cands))
+	for j, s := range cands {
+		var c float64
+		if counts != nil {
+			c = counts[s]
+		}
+		pTagGivenWord := (c + taggerSmoothing/float64(len(cands))) / (total + taggerSmoothing)
+		out[j] = pTagGivenWord / t.prior[s]
+	}
+	return out
+}
+
+// state возвращает номер состояния для части речи; неизвестные части речи
+// сводятся к общему "пустому" состоянию.
+func (t *Tagger) state(pos string) int {
+	if s, ok := t.index[pos]; ok {
+		return s
+	}
+	return t.index[""]
+}
+
+// partOfSpeech возвращает часть речи из строки тегов (она всегда идет первой).
+func partOfSpeech(tags string) string {
+	pos, _, _ := strings.Cut(tags, ",")
+	if inMap(pos, posTags) {
+		return pos
+	}
+	return ""
+}
+
+// normalize превращает счетчики в распределение со сглаживанием.
+func normalize(counts []float64, smoothing float64) []float64 {
+	var total float64
+	for _, c := range counts {
+		total += c + smoothing
+	}
+	out := make([]float64, len(counts))
+	for i, c := range counts {
+		out[i] = (c + smoothing) / total
+	}
+	return out
+}
+
+// scale нормирует вектор на сумму (если она положительна).
+func scale(v []float64) {
+	var total float64
+	for _, x := range v {
+		total += x
+	}
+	if total <= 0 {
+		return
+	}
+	for i := range v {
+		v[i] /= total
+	}
+}
@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// taggerCorpus - маленький размеченный корпус: "стали" встречается и глаголом, и
+// существительным, так что часть речи определяют соседние слова.
+const taggerCorpus = `# слово	теги
+она	NPRO,femn,3per,Anph,sing,nomn
+стала	VERB,perf,intr,femn,sing,past,indc
+красивой	ADJF,Qual,femn,sing,ablt
+
+они	NPRO,3per,Anph,plur,nomn
+стали	VERB,perf,intr,plur,past,indc
+идти	INFN,impf,intr
+
+мы	NPRO,1per,plur,nomn
+шли	VERB,impf,intr,plur,past,indc
+в	PREP
+дом	NOUN,inan,masc,sing,accs
+
+нож	NOUN,inan,masc,sing,nomn
+из	PREP
+стали	NOUN,inan,femn,sing,gent
+
+в	PREP
+банке	NOUN,inan,masc,sing,loct
+
+она	NPRO,femn,3per,Anph,sing,nomn
+шла	VERB,impf,intr,femn,sing,past,indc
+в	PREP
+банк	NOUN,inan,masc,sing,accs
+`
+
+func trainTestTagger(t *testing.T) *Tagger {
+	t.Helper()
+	sentences, err := ReadTaggedCorpus(strings.NewReader(taggerCorpus))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sentences) != 6 || len(sentences[0]) != 3 {
+		t.Fatalf("прочитано предложений: %d", len(sentences))
+	}
+	if got := sentences[0][1].Tags; !strings.HasPrefix(got, "Глагол,") {
+		t.Fatalf("теги OpenCorpora не переведены в формат словаря: %q", got)
+	}
+	return TrainTagger(sentences)
+}
+
+func TestTaggerDisambiguate(t *testing.T) {
+	a := loadFixture(t, "lexemes.tsv")
+	tagger := trainTestTagger(t)
+	parse := func(words ...string) [][]*Parsed {
+		sentence := make([][]*Parsed, len(words))
+		for i, w := range words {
+			sentence[i] = a.Parse(w)
+		}
+		return sentence
+	}
+
+	tests := []struct {
+		words []string
+		at    int
+		pos   string
+	}{
+		// После местоимения "стали" - глагол, после предлога - существительное
+		{[]string{"она", "стали", "идти"}, 1, "Глагол"},
+		{[]string{"в", "стали"}, 1, "Существительное"},
+		{[]string{"стали", "в", "дома"}, 2, "Существительное"},
+	}
+	for _, tt := range tests {
+		sentence := parse(tt.words...)
+		out := tagger.Disambiguate(sentence)
+		best := out[tt.at][0]
+		if best.PartOfSpeech != tt.pos {
+			t.Errorf("%q: лучший разбор %q - %s (%.3f), ожидается %s",
+				tt.words, tt.words[tt.at], best.PartOfSpeech, best.Probability, tt.pos)
+		}
+		for i, parses := range out {
+			sum := 0.0
+			for j, p := range parses {
+				sum += p.Probability
+				if j > 0 && p.Probability > parses[j-1].Probability {
+					t.Errorf("%q: разборы %q не отсортированы по вероятности", tt.words, tt.words[i])
+				}
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Errorf("%q: сумма вероятностей разборов %q = %v", tt.words, tt.words[i], sum)
+			}
+		}
+		// Исходные разборы (в том числе из кэшей) не изменяются
+		for _, parses := range sentence {
+			for _, p := range parses {
+				if p.Probability != 0 {
+					t.Fatalf("Disambiguate изменил исходный разбор %s %s", p.Word, p.Tags)
+				}
+			}
+		}
+	}
+
+	// Токен без разборов разрывает цепочку, но не мешает разобрать остальные
+	out := tagger.Disambiguate([][]*Parsed{a.Parse("в"), nil, a.Parse("стали")})
+	if len(out[1]) != 0 || len(out[2]) == 0 || out[2][0].Probability <= 0 {
+		t.Errorf("предложение с неразобранным токеном: %v", out)
+	}
+}
+
+func TestReadTaggedCorpusErrors(t *testing.T) {
+	if _, err := ReadTaggedCorpus(strings.NewReader("слово без тегов\n")); err == nil {
+		t.Error("строка без тегов принята")
+	}
+}
@@ -11,10 +11,10 @@ import (
 // GrammemeSet - это множество для хранения грамматических тегов.
 type GrammemeSet map[string]struct{}
 
-// Parsed - это объект для хранения полного морфологического разбора.
-type Parsed struct {
-	Word         string      `json:"word"`           // Исходное слово
-	Lemma        string      `json:"lemma"`          // Нормальная форма (лемма)
+// Tag - разобранная строка тегов. Для каждого элемента пула тегов словаря
+// Tag создается один раз при загрузке и далее только читается,
+// поэтому один и тот же Tag разделяется всеми разборами с этими тегами.
+type Tag struct {
 	Tags         string      `json:"tags"`           // Полная строка тегов для отладки
 	PartOfSpeech string      `json:"part_of_speech"` // Часть речи
 	Animacy      string      `json:"animacy"`        // Одушевленность
@@ -28,8 +28,27 @@ type Parsed struct {
 	Transitivity string      `json:"transitivity"`   // Переходность
 	Voice        string      `json:"voice"`          // Залог
 	OtherTags    GrammemeSet `json:"other_tags"`     // Остальные теги, не вошедшие в основные категории
+
+	grammemes GrammemeSet // Все граммемы строки тегов.
+}
+
+// Parsed - это объект для хранения полного морфологического разбора.
+// Грамматические поля берутся из разделяемого *Tag, который менять нельзя.
+type Parsed struct {
+	Word  string `json:"word"`  // Исходное слово
+	Lemma string `json:"lemma"` // Нормальная форма (лемма)
+	*Tag
+	Origin      string  `json:"origin"`                // Происхождение разбора: словарь или предсказание
+	Score       float64 `json:"score"`                 // Вероятность разбора без учета контекста: P(теги | слово)
+	Probability float64 `json:"probability,omitempty"` // Вероятность разбора в контексте (см. Tagger); 0, если не оценивалась
 }
 
+// Возможные значения поля `Parsed.Origin`.
+const (
+	OriginDictionary = "dictionary" // Слово найдено в словаре.
+	OriginPredicted  = "predicted"  // Разбор предсказан по суффиксу несловарного слова.
+)
+
 // Глобальные переменные, содержащие множества всех возможных граммем для каждой категории.
 // Они используются функцией `newParsed` для быстрой проверки, к какой категории относится тот или иной тег.
 var (
@@ -131,11 +150,21 @@ var (
 )
 
 // newParsed - это конструктор-фабрика для объекта `Parsed`.
-// Он принимает "сырые" данные (слово, лемму и строку тегов) и возвращает
-// полностью заполненный, структурированный объект.
-func newParsed(word, lemma, tagString string) *Parsed {
-	// Создаем базовый объект с основными данными.
-	p := &Parsed{Word: word, Lemma: lemma, Tags: tagString, OtherTags: make(GrammemeSet)}
+// Он принимает "сырые" данные (слово, лемму, строку тегов и происхождение разбора)
+// и возвращает полностью заполненный, структурированный объект.
+// Для тегов из пула словаря дешевле `newParsedTag` с уже разобранным тегом.
+func newParsed(word, lemma, tagString, origin string) *Parsed {
+	return newParsedTag(word, lemma, decodeTag(tagString), origin)
+}
+
+// newParsedTag создает разбор с уже разобранным (разделяемым) тегом.
+func newParsedTag(word, lemma string, tag *Tag, origin string) *Parsed {
+	return &Parsed{Word: word, Lemma: lemma, Tag: tag, Origin: origin}
+}
+
+// decodeTag раскладывает строку тегов по грамматическим категориям.
+func decodeTag(tagString string) *Tag {
+	t := &Tag{Tags: tagString, OtherTags: make(GrammemeSet), grammemes: grammemesOf(tagString)}
 
 	// Разбиваем строку тегов на отдельные граммемы.
 	grammemes := strings.Split(tagString, ",")
@@ -143,44 +172,64 @@ func newParsed(word, lemma, tagString string) *Parsed {
 	// Обрабатываем `Часть Речи` отдельно, так как она всегда идет первой.
 	if len(grammemes) > 0 {
 		if _, ok := posTags[grammemes[0]]; ok {
-			p.PartOfSpeech = grammemes[0]
+			t.PartOfSpeech = grammemes[0]
 		}
 	}
 
-	// Проходим по всем граммемам и раскладываем их по соответствующим полям структуры `Parsed`.
+	// Проходим по всем граммемам и раскладываем их по соответствующим полям структуры `Tag`.
 	for _, g := range grammemes {
 		switch {
-		case g == p.PartOfSpeech: // пропускаем, так как уже обработали.
+		case g == t.PartOfSpeech: // пропускаем, так как уже обработали.
 		case inMap(g, animacyTags):
-			p.Animacy = g
+			t.Animacy = g
 		case inMap(g, aspectTags):
-			p.Aspect = g
+			t.Aspect = g
 		case inMap(g, caseTags):
-			p.Case = g
+			t.Case = g
 		case inMap(g, genderTags):
-			p.Gender = g
+			t.Gender = g
 		case inMap(g, moodTags):
-			p.Mood = g
+			t.Mood = g
 		case inMap(g, numberTags):
-			p.Number = g
+			t.Number = g
 		case inMap(g, personTags):
-			p.Person = g
+			t.Person = g
 		case inMap(g, tenseTags):
-			p.Tense = g
+			t.Tense = g
 		case inMap(g, transTags):
-			p.Transitivity = g
+			t.Transitivity = g
 		case inMap(g, voiceTags):
-			p.Voice = g
+			t.Voice = g
 		default:
 			// Если тег не подошел ни к одной из основных категорий,
 			// мы помещаем его в "корзину" OtherTags.
-			p.OtherTags[g] = struct{}{}
+			t.OtherTags[g] = struct{}{}
 		}
 	}
-	return p
+	return t
+}
+
+// decodeTags разбирает весь пул тегов словаря.
+func decodeTags(pool []string) []Tag {
+	tags := make([]Tag, len(pool))
+	for i, s := range pool {
+		tags[i] = *decodeTag(s)
+	}
+	return tags
 }
 
 func inMap(key string, set GrammemeSet) bool {
 	_, ok := set[key]
 	return ok
 }
+
+// grammemesOf раскладывает строку тегов в множество граммем.
+func grammemesOf(tagString string) GrammemeSet {
+	set := make(GrammemeSet)
+	for _, g := range strings.Split(tagString, ",") {
+		if g != "" {
+			set[g] = struct{}{}
+		}
+	}
+	return set
+}
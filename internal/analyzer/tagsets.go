@@ -0,0 +1,520 @@
+// tagsets.go позволяет выводить теги разборов во внешних системах обозначений:
+// кодами граммем OpenCorpora (NOUN,inan,masc,sing,gent) и в формате
+// Universal Dependencies (UPOS + FEATS: NOUN, Animacy=Inan|Case=Gen|Gender=Masc|Number=Sing).
+// Внутри анализатора теги по-прежнему хранятся в формате словаря.
+package analyzer
+
+import (
+	"sort"
+	"strings"
+)
+
+// TagSet - система обозначений, в которой выводятся теги разбора.
+type TagSet interface {
+	// Name возвращает имя системы, по которому ее выбирают в API.
+	Name() string
+	// Render возвращает часть речи и строку граммем разбора в этой системе.
+	Render(t *Tag) (pos, tags string)
+	// Unmapped возвращает граммемы тега, для которых в системе нет правила
+	// (ни соответствия, ни явного указания пропустить граммему).
+	Unmapped(t *Tag) []string
+	// ParseGrammemes переводит граммемы, записанные в этой системе, в граммемы словаря.
+	// Коды, которые система не распознала, возвращаются вторым значением.
+	ParseGrammemes(codes []string) (GrammemeSet, []string)
+}
+
+// Имена поддерживаемых систем обозначений.
+const (
+	TagSetNameNative      = "native"
+	TagSetNameOpenCorpora = "opencorpora"
+	TagSetNameUD          = "ud"
+)
+
+var (
+	// NativeTagSet выводит теги в формате словаря без изменений.
+	NativeTagSet TagSet = nativeTagSet{}
+	// OpenCorporaTagSet выводит теги кодами граммем OpenCorpora.
+	OpenCorporaTagSet TagSet = openCorporaTagSet{}
+	// UDTagSet выводит теги в формате Universal Dependencies.
+	UDTagSet TagSet = udTagSet{}
+)
+
+// TagSetByName возвращает систему обозначений по имени; пустое имя означает формат словаря.
+func TagSetByName(name string) (TagSet, bool) {
+	switch strings.ToLower(name) {
+	case "", TagSetNameNative:
+		return NativeTagSet, true
+	case TagSetNameOpenCorpora:
+		return OpenCorporaTagSet, true
+	case TagSetNameUD:
+		return UDTagSet, true
+	}
+	return nil, false
+}
+
+// RenderedParse - разбор с тегами во внешней системе обозначений.
+type RenderedParse struct {
+	Word        string  `json:"word"`
+	Lemma       string  `json:"lemma"`
+	TagSet      string  `json:"tagset"`
+	POS         string  `json:"pos"`  // Часть речи (UPOS для UD).
+	Tags        string  `json:"tags"` // Граммемы (FEATS для UD).
+	Origin      string  `json:"origin"`
+	Score       float64 `json:"score"`
+	Probability float64 `json:"probability,omitempty"`
+}
+
+// Render представляет разбор в заданной системе обозначений.
+func (p *Parsed) Render(ts TagSet) RenderedParse {
+	pos, tags := ts.Render(p.Tag)
+	return RenderedParse{
+		Word:        p.Word,
+		Lemma:       p.Lemma,
+		TagSet:      ts.Name(),
+		POS:         pos,
+		Tags:        tags,
+		Origin:      p.Origin,
+		Score:       p.Score,
+		Probability: p.Probability,
+	}
+}
+
+// UnmappedGrammemes проверяет все теги словаря и возвращает отсортированный список граммем,
+// которые система обозначений не умеет выводить. Пустой список означает полное покрытие.
+func (a *MorphAnalyzer) UnmappedGrammemes(ts TagSet) []string {
+	seen := make(map[string]bool)
+	for i := range a.tags {
+		for _, g := range ts.Unmapped(&a.tags[i]) {
+			seen[g] = true
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for g := range seen {
+		out = append(out, g)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// --- Формат словаря ---
+
+type nativeTagSet struct{}
+
+func (nativeTagSet) Name() string { return TagSetNameNative }
+
+func (nativeTagSet) Render(t *Tag) (string, string) { return t.PartOfSpeech, t.Tags }
+
+func (nativeTagSet) Unmapped(*Tag) []string { return nil }
+
+func (nativeTagSet) ParseGrammemes(codes []string) (GrammemeSet, []string) {
+	set := make(GrammemeSet, len(codes))
+	var unknown []string
+	for _, g := range codes {
+		g = strings.TrimSpace(g)
+		if !knownGrammeme(g) {
+			unknown = append(unknown, g)
+			continue
+		}
+		set[g] = struct{}{}
+	}
+	return set, unknown
+}
+
+// knownGrammeme сообщает, есть ли у граммемы словаря правило вывода в других системах,
+// то есть известна ли она анализатору (см. TestUnmappedGrammemes).
+func knownGrammeme(g string) bool {
+	_, oc := nativeToOpenCorpora[g]
+	_, ud := nativeToUDFeats[g]
+	return oc || ud || inMap(g, posTags)
+}
+
+// --- OpenCorpora ---
+
+// nativeToOpenCorpora - код OpenCorpora для каждой граммемы словаря.
+// Строится обращением таблиц opencorpora.go; пустой код - граммема не выводится.
+var nativeToOpenCorpora = map[string]string{
+	// Части речи без однозначной пары в openCorporaPOS.
+	"Вводное слово": "CONJ",
+	// Граммемы, которые выражаются составной частью речи (ADJS, COMP, INFN, PRTS, PRED).
+	"Краткая форма":         "",
+	"Сравнительная степень": "",
+	"Инфинитив":             "",
+	"Предикатив":            "",
+	// Граммемы словаря, которых нет в OpenCorpora.
+	"Будущее аналитическое": "futr",
+	"Двувидовой":            "",
+	"Лабильный":             "",
+	"нет лица":              "",
+	"Несклоняемый":          "Fixd",
+}
+
+func init() {
+	for code, names := range openCorporaPOS {
+		if len(names) == 1 {
+			nativeToOpenCorpora[names[0]] = code
+		}
+	}
+	for code, name := range openCorporaGrammemes {
+		if _, ok := nativeToOpenCorpora[name]; name != "" && !ok {
+			nativeToOpenCorpora[name] = code
+		}
+	}
+}
+
+// openCorporaComposite - части речи OpenCorpora, которые в словаре записаны
+// частью речи и уточняющей граммемой.
+var openCorporaComposite = []struct{ pos, grammeme, code string }{
+	{"Прилагательное", "Краткая форма", "ADJS"},
+	{"Прилагательное", "Сравнительная степень", "COMP"},
+	{"Глагол", "Инфинитив", "INFN"},
+	{"Причастие", "Краткая форма", "PRTS"},
+	{"Наречие", "Предикатив", "PRED"},
+}
+
+type openCorporaTagSet struct{}
+
+func (openCorporaTagSet) Name() string { return TagSetNameOpenCorpora }
+
+func (openCorporaTagSet) Render(t *Tag) (string, string) {
+	pos := nativeToOpenCorpora[t.PartOfSpeech]
+	consumed := ""
+	for _, c := range openCorporaComposite {
+		if _, ok := t.grammemes[c.grammeme]; ok && c.pos == t.PartOfSpeech {
+			pos, consumed = c.code, c.grammeme
+			break
+		}
+	}
+	if pos == "" {
+		pos = "UNKN"
+	}
+
+	codes := []string{pos}
+	if t.PartOfSpeech == "Вводное слово" {
+		codes = append(codes, "Prnt")
+	}
+	for _, g := range strings.Split(t.Tags, ",") {
+		if g == "" || g == t.PartOfSpeech || g == consumed {
+			continue
+		}
+		code, ok := nativeToOpenCorpora[g]
+		if !ok {
+			code = g // Неизвестную граммему лучше показать как есть, чем потерять.
+		}
+		if code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return pos, strings.Join(codes, ",")
+}
+
+// ParseGrammemes переводит коды OpenCorpora так же, как NormalizeTags при чтении
+// корпуса: составная часть речи (INFN) дает часть речи и граммему, а коды, которые
+// в словаре не выражаются (indc), принимаются без ограничения.
+func (openCorporaTagSet) ParseGrammemes(codes []string) (GrammemeSet, []string) {
+	set := make(GrammemeSet, len(codes))
+	var unknown []string
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		if _, ok := openCorporaGrammemes[code]; !ok && openCorporaPOS[code] == nil && !knownGrammeme(code) {
+			unknown = append(unknown, code)
+			continue
+		}
+		for g := range grammemesOf(NormalizeTags(code)) {
+			set[g] = struct{}{}
+		}
+	}
+	return set, unknown
+}
+
+func (openCorporaTagSet) Unmapped(t *Tag) []string {
+	var out []string
+	for g := range t.grammemes {
+		if _, ok := nativeToOpenCorpora[g]; !ok {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// --- Universal Dependencies ---
+
+// nativeToUPOS - UPOS для частей речи словаря.
+var nativeToUPOS = map[string]string{
+	"Существительное": "NOUN",
+	"Прилагательное":  "ADJ",
+	"Глагол":          "VERB",
+	"Причастие":       "VERB",
+	"Деепричастие":    "VERB",
+	"Наречие":         "ADV",
+	"Местоимение":     "PRON",
+	"Числительное":    "NUM",
+	"Предлог":         "ADP",
+	"Союз":            "CCONJ",
+	"Частица":         "PART",
+	"Междометие":      "INTJ",
+	"Вводное слово":   "ADV",
+}
+
+// properNounGrammemes - граммемы, при которых существительное считается собственным (PROPN).
+var properNounGrammemes = []string{"Имя", "Фамилия", "Отчество", "Топоним", "Организация", "Торговая марка"}
+
+// nativeToUDFeats - признаки UD для граммем словаря. Пустой срез означает,
+// что граммема в UD не выражается (лексические пометы, переходность и т.п.).
+var nativeToUDFeats = map[string][]string{
+	// Одушевленность
+	"Одушевленное":                  {"Animacy=Anim"},
+	"Неодушевленное":                {"Animacy=Inan"},
+	"одушевленное и неодушевленное": {"Animacy=Anim", "Animacy=Inan"},
+	// Вид
+	"Совершенный":   {"Aspect=Perf"},
+	"Несовершенный": {"Aspect=Imp"},
+	"Двувидовой":    {"Aspect=Imp", "Aspect=Perf"},
+	// Падеж
+	"Именительный": {"Case=Nom"},
+	"Родительный":  {"Case=Gen"},
+	"Дательный":    {"Case=Dat"},
+	"Винительный":  {"Case=Acc"},
+	"Творительный": {"Case=Ins"},
+	"Предложный":   {"Case=Loc"},
+	"Звательный":   {"Case=Voc"},
+	"Партитивный":  {"Case=Par"},
+	"Местный":      {"Case=Loc"},
+	"Счетный":      {"Case=Gen"},
+	"Ждательный":   {"Case=Acc"},
+	"Несклоняемый": {},
+	// Род
+	"Мужской": {"Gender=Masc"},
+	"Женский": {"Gender=Fem"},
+	"Средний": {"Gender=Neut"},
+	"Общий":   {"Gender=Fem", "Gender=Masc"},
+	"Парный":  {},
+	// Наклонение и форма глагола
+	"Повелительное": {"Mood=Imp", "VerbForm=Fin"},
+	"Инфинитив":     {"VerbForm=Inf"},
+	// Число
+	"Единственное число":   {"Number=Sing"},
+	"Множественное число":  {"Number=Plur"},
+	"Только единственное":  {},
+	"Только множественное": {},
+	// Лицо
+	"1-е лицо": {"Person=1"},
+	"2-е лицо": {"Person=2"},
+	"3-е лицо": {"Person=3"},
+	"нет лица": {},
+	// Время
+	"Прошедшее":             {"Tense=Past"},
+	"Настоящее":             {"Tense=Pres"},
+	"Будущее":               {"Tense=Fut"},
+	"Будущее аналитическое": {"Tense=Fut"},
+	// Переходность в UD не выражается
+	"Переходный":   {},
+	"Непереходный": {},
+	"Лабильный":    {},
+	// Залог
+	"Действительный": {"Voice=Act"},
+	"Страдательный":  {"Voice=Pass"},
+	// Степень и краткость
+	"Сравнительная степень":        {"Degree=Cmp"},
+	"Сравнительная степень на по-": {"Degree=Cmp"},
+	"Превосходная степень":         {"Degree=Sup"},
+	"Краткая форма":                {"Variant=Short"},
+	"Предикатив":                   {},
+	// Прочие граммемы
+	"Аббревиатура":            {"Abbr=Yes"},
+	"Опечатка":                {"Typo=Yes"},
+	"Вопросительное":          {"PronType=Int"},
+	"Указательное":            {"PronType=Dem"},
+	"Притяжательное":          {"Poss=Yes"},
+	"Порядковое":              {"NumType=Ord"},
+	"Собирательное":           {"NumType=Sets"},
+	"Возвратный":              {"Reflex=Yes"},
+	"Имя":                     {},
+	"Фамилия":                 {},
+	"Отчество":                {},
+	"Топоним":                 {},
+	"Организация":             {},
+	"Торговая марка":          {},
+	"Инициал":                 {},
+	"Вводное слово":           {},
+	"Разговорное":             {},
+	"Жаргонное":               {},
+	"Устаревшее":              {},
+	"Литературное":            {},
+	"Искажение":               {},
+	"Безличный":               {},
+	"Возможно безличный":      {},
+	"Многократный":            {},
+	"Анафорическое":           {},
+	"Качественное":            {},
+	"Местоименное":            {},
+	"Совместное":              {},
+	"Несовместное":            {},
+	"Гипотетическое":          {},
+	"Возможна субстантивация": {},
+	"Возможен предикатив":     {},
+	"Возможно прилагательное": {},
+	"Возможно порядковое":     {},
+	"Форма после предлога":    {},
+	"Вариант предлога":        {},
+	"Форма на -ею":            {},
+	"Форма на -ою":            {},
+	"Форма на -ей":            {},
+	"Форма на -ье":            {},
+	"Форма на -енен":          {},
+	"Форма на -ие":            {},
+	"Форма на -ьи":            {},
+	"Деепричастие на -ши":     {},
+	"Деепричастие от глагола несовершенного вида": {},
+}
+
+// udToNative - граммемы словаря для UPOS и признаков UD. Однозначные признаки
+// получаются обращением nativeToUDFeats (см. init), здесь - части речи и признаки,
+// которые выводятся из нескольких граммем словаря. Пустое значение - признак
+// принимается, но ничего не требует (Mood=Ind подразумевается по умолчанию).
+var udToNative = map[string][]string{
+	"NOUN":  {"Существительное"},
+	"PROPN": {"Существительное"},
+	"ADJ":   {"Прилагательное"},
+	"VERB":  {"Глагол"},
+	"ADV":   {"Наречие"},
+	"PRON":  {"Местоимение"},
+	"NUM":   {"Числительное"},
+	"ADP":   {"Предлог"},
+	"CCONJ": {"Союз"},
+	"PART":  {"Частица"},
+	"INTJ":  {"Междометие"},
+
+	"Case=Gen":      {"Родительный"},
+	"Case=Acc":      {"Винительный"},
+	"Case=Loc":      {"Предложный"},
+	"Tense=Fut":     {"Будущее"},
+	"Degree=Cmp":    {"Сравнительная степень"},
+	"Mood=Imp":      {"Повелительное"},
+	"Mood=Ind":      {},
+	"VerbForm=Fin":  {},
+	"VerbForm=Inf":  {"Глагол", "Инфинитив"},
+	"VerbForm=Part": {"Причастие"},
+	"VerbForm=Conv": {"Деепричастие"},
+}
+
+func init() {
+	// Признак, который выражает ровно одна граммема словаря, переводится в нее.
+	// Признаки нескольких граммем (Case=Loc - Предложный и Местный) заданы в udToNative явно.
+	natives := make(map[string][]string)
+	for g, feats := range nativeToUDFeats {
+		if len(feats) == 1 {
+			natives[feats[0]] = append(natives[feats[0]], g)
+		}
+	}
+	for feat, gs := range natives {
+		if _, ok := udToNative[feat]; !ok && len(gs) == 1 {
+			udToNative[feat] = gs
+		}
+	}
+}
+
+type udTagSet struct{}
+
+func (udTagSet) Name() string { return TagSetNameUD }
+
+func (udTagSet) Render(t *Tag) (string, string) {
+	upos, ok := nativeToUPOS[t.PartOfSpeech]
+	if !ok {
+		upos = "X"
+	}
+	if upos == "NOUN" {
+		for _, g := range properNounGrammemes {
+			if _, ok := t.grammemes[g]; ok {
+				upos = "PROPN"
+				break
+			}
+		}
+	}
+
+	feats := make(map[string][]string)
+	add := func(f string) {
+		name, value, _ := strings.Cut(f, "=")
+		for _, v := range feats[name] {
+			if v == value {
+				return
+			}
+		}
+		feats[name] = append(feats[name], value)
+	}
+	switch t.PartOfSpeech {
+	case "Причастие":
+		add("VerbForm=Part")
+	case "Деепричастие":
+		add("VerbForm=Conv")
+	case "Глагол":
+		// Личные формы изъявительного наклонения в словаре не помечены явно.
+		if t.Tense != "" && t.Mood == "" {
+			add("Mood=Ind")
+			add("VerbForm=Fin")
+		}
+	}
+	for g := range t.grammemes {
+		if g == t.PartOfSpeech {
+			continue
+		}
+		for _, f := range nativeToUDFeats[g] {
+			add(f)
+		}
+	}
+
+	names := make([]string, 0, len(feats))
+	for name := range feats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		values := feats[name]
+		sort.Strings(values)
+		parts = append(parts, name+"="+strings.Join(values, ","))
+	}
+	if len(parts) == 0 {
+		return upos, "_"
+	}
+	return upos, strings.Join(parts, "|")
+}
+
+// ParseGrammemes принимает UPOS и признаки FEATS: по одному в элементе
+// или несколько через "|" ("Case=Dat|Number=Plur").
+func (udTagSet) ParseGrammemes(codes []string) (GrammemeSet, []string) {
+	set := make(GrammemeSet, len(codes))
+	var unknown []string
+	for _, code := range codes {
+		for _, f := range strings.Split(code, "|") {
+			f = strings.TrimSpace(f)
+			if f == "_" {
+				continue
+			}
+			gs, ok := udToNative[f]
+			if !ok {
+				unknown = append(unknown, f)
+				continue
+			}
+			for _, g := range gs {
+				set[g] = struct{}{}
+			}
+		}
+	}
+	return set, unknown
+}
+
+func (udTagSet) Unmapped(t *Tag) []string {
+	var out []string
+	for g := range t.grammemes {
+		if g == t.PartOfSpeech {
+			if _, ok := nativeToUPOS[g]; !ok {
+				out = append(out, g)
+			}
+			continue
+		}
+		if _, ok := nativeToUDFeats[g]; !ok {
+			out = append(out, g)
+		}
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+package analyzer
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// allGrammemesDictionary собирает словарь, в пуле тегов которого есть каждая граммема,
+// известная анализатору: категории tagset.go и граммемы, в которые переводятся коды OpenCorpora.
+func allGrammemesDictionary(tb testing.TB) *MorphAnalyzer {
+	tb.Helper()
+	sets := []GrammemeSet{posTags, animacyTags, aspectTags, caseTags, genderTags, moodTags,
+		numberTags, personTags, tenseTags, transTags, voiceTags}
+	grammemes := make(GrammemeSet)
+	for _, set := range sets {
+		for g := range set {
+			grammemes[g] = struct{}{}
+		}
+	}
+	for _, names := range openCorporaPOS {
+		for _, g := range names {
+			grammemes[g] = struct{}{}
+		}
+	}
+	for _, g := range openCorporaGrammemes {
+		if g != "" {
+			grammemes[g] = struct{}{}
+		}
+	}
+
+	b := NewBuilder()
+	for g := range grammemes {
+		tags := "Существительное," + g
+		if _, ok := posTags[g]; ok {
+			tags = g
+		}
+		if err := b.Add(Lexeme{Lemma: "слово", Forms: []LexemeForm{{Word: "слово", Tags: tags}}}); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		tb.Fatal(err)
+	}
+	a, err := LoadMorphAnalyzerFromBytes(buf.Bytes())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { a.Close() })
+	return a
+}
+
+func TestUnmappedGrammemes(t *testing.T) {
+	dicts := map[string]*MorphAnalyzer{
+		"lexemes.tsv":     loadFixture(t, "lexemes.tsv"),
+		"opencorpora.xml": loadFixture(t, "opencorpora.xml"),
+		"all grammemes":   allGrammemesDictionary(t),
+	}
+	for name, a := range dicts {
+		for _, ts := range []TagSet{OpenCorporaTagSet, UDTagSet} {
+			if unmapped := a.UnmappedGrammemes(ts); len(unmapped) > 0 {
+				t.Errorf("%s: %s: нет соответствия для граммем %q", name, ts.Name(), unmapped)
+			}
+		}
+	}
+}
+
+func TestRenderTagSets(t *testing.T) {
+	tests := []struct {
+		tags           string
+		ocPOS, ocTags  string
+		udPOS, udFeats string
+	}{
+		{
+			tags:  "Существительное,Неодушевленное,Мужской,Единственное число,Родительный",
+			ocPOS: "NOUN", ocTags: "NOUN,inan,masc,sing,gent",
+			udPOS: "NOUN", udFeats: "Animacy=Inan|Case=Gen|Gender=Masc|Number=Sing",
+		},
+		{
+			tags:  "Существительное,Одушевленное,Мужской,Имя,Единственное число,Именительный",
+			ocPOS: "NOUN", ocTags: "NOUN,anim,masc,Name,sing,nomn",
+			udPOS: "PROPN", udFeats: "Animacy=Anim|Case=Nom|Gender=Masc|Number=Sing",
+		},
+		{
+			tags:  "Глагол,Несовершенный,Непереходный,Единственное число,1-е лицо,Настоящее",
+			ocPOS: "VERB", ocTags: "VERB,impf,intr,sing,1per,pres",
+			udPOS: "VERB", udFeats: "Aspect=Imp|Mood=Ind|Number=Sing|Person=1|Tense=Pres|VerbForm=Fin",
+		},
+		{
+			tags:  "Глагол,Несовершенный,Непереходный,Инфинитив",
+			ocPOS: "INFN", ocTags: "INFN,impf,intr",
+			udPOS: "VERB", udFeats: "Aspect=Imp|VerbForm=Inf",
+		},
+		{
+			tags:  "Прилагательное,Краткая форма,Женский,Единственное число",
+			ocPOS: "ADJS", ocTags: "ADJS,femn,sing",
+			udPOS: "ADJ", udFeats: "Gender=Fem|Number=Sing|Variant=Short",
+		},
+		{
+			tags:  "Предлог",
+			ocPOS: "PREP", ocTags: "PREP",
+			udPOS: "ADP", udFeats: "_",
+		},
+	}
+	for _, tt := range tests {
+		tag := decodeTag(tt.tags)
+		if pos, tags := OpenCorporaTagSet.Render(tag); pos != tt.ocPOS || tags != tt.ocTags {
+			t.Errorf("OpenCorpora(%s) = %s %s, ожидается %s %s", tt.tags, pos, tags, tt.ocPOS, tt.ocTags)
+		}
+		if pos, feats := UDTagSet.Render(tag); pos != tt.udPOS || feats != tt.udFeats {
+			t.Errorf("UD(%s) = %s %s, ожидается %s %s", tt.tags, pos, feats, tt.udPOS, tt.udFeats)
+		}
+	}
+}
+
+func TestParseGrammemes(t *testing.T) {
+	tests := []struct {
+		ts      TagSet
+		codes   []string
+		want    []string
+		unknown []string
+	}{
+		{NativeTagSet, []string{"Родительный", "Множественное число"}, []string{"Родительный", "Множественное число"}, nil},
+		{NativeTagSet, []string{"Родительный", "gent"}, []string{"Родительный"}, []string{"gent"}},
+		{OpenCorporaTagSet, []string{"gent", "plur"}, []string{"Родительный", "Множественное число"}, nil},
+		{OpenCorporaTagSet, []string{"INFN"}, []string{"Глагол", "Инфинитив"}, nil},
+		// Изъявительное наклонение в словаре не выражается и ничего не требует
+		{OpenCorporaTagSet, []string{"indc"}, nil, nil},
+		{OpenCorporaTagSet, []string{"datv", "Case=Dat"}, []string{"Дательный"}, []string{"Case=Dat"}},
+		{UDTagSet, []string{"Case=Gen", "Number=Plur"}, []string{"Родительный", "Множественное число"}, nil},
+		{UDTagSet, []string{"Case=Dat|Number=Plur"}, []string{"Дательный", "Множественное число"}, nil},
+		{UDTagSet, []string{"Case=Loc"}, []string{"Предложный"}, nil},
+		{UDTagSet, []string{"VERB", "VerbForm=Inf"}, []string{"Глагол", "Инфинитив"}, nil},
+		{UDTagSet, []string{"Mood=Ind|VerbForm=Fin", "_"}, nil, nil},
+		{UDTagSet, []string{"Case=Xyz", "gent", "Animacy=Anim,Inan"}, nil, []string{"Case=Xyz", "gent", "Animacy=Anim,Inan"}},
+	}
+	for _, tt := range tests {
+		got, unknown := tt.ts.ParseGrammemes(tt.codes)
+		if !sameGrammemes(got, grammemes(tt.want...)) || !slices.Equal(unknown, tt.unknown) {
+			t.Errorf("%s: ParseGrammemes(%q) = %v, %q, ожидается %q, %q", tt.ts.Name(), tt.codes, got, unknown, tt.want, tt.unknown)
+		}
+	}
+
+	// Всё, что выводит Render, принимается обратно
+	for g, code := range nativeToOpenCorpora {
+		if _, unknown := OpenCorporaTagSet.ParseGrammemes([]string{code}); code != "" && len(unknown) > 0 {
+			t.Errorf("OpenCorpora: код %s (%s) не распознан", code, g)
+		}
+	}
+	for _, c := range openCorporaComposite {
+		if _, unknown := OpenCorporaTagSet.ParseGrammemes([]string{c.code}); len(unknown) > 0 {
+			t.Errorf("OpenCorpora: код %s не распознан", c.code)
+		}
+	}
+	var udCodes []string
+	for _, upos := range nativeToUPOS {
+		udCodes = append(udCodes, upos)
+	}
+	for _, feats := range nativeToUDFeats {
+		udCodes = append(udCodes, feats...)
+	}
+	udCodes = append(udCodes, "PROPN", "Mood=Ind", "VerbForm=Fin", "VerbForm=Part", "VerbForm=Conv")
+	if _, unknown := UDTagSet.ParseGrammemes(udCodes); len(unknown) > 0 {
+		t.Errorf("UD: не распознаны %q", unknown)
+	}
+}
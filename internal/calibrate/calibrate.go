@@ -0,0 +1,221 @@
+// Package calibrate подбирает температуру, с которой корректор переводит скоры кандидатов
+// в вероятности (corrector.Softmax), на отложенном размеченном корпусе и оценивает
+// калибровку: Brier score по всем кандидатам и диаграмму надежности уверенности
+// лучшего кандидата. Калибровку подобранной температуры честно оценивает только
+// CrossValidate: на тех же словах, по которым она подобрана, оценка завышена.
+package calibrate
+
+import (
+	"math"
+
+	sc "corrector/internal/corrector"
+	"corrector/internal/eval"
+)
+
+// Example - кандидаты одного проверенного слова.
+type Example struct {
+	Scores []float64
+	Gold   int // индекс правильного кандидата; -1, если его нет среди кандидатов
+}
+
+// Examples сопоставляет кандидатов слов результата исправления с правильным текстом.
+// Слова выравниваются так же, как в eval; слово, которому в правильном тексте соответствует
+// несколько слов или ни одного, дает пример без правильного кандидата.
+func Examples(res sc.CorrectionResult, expected string) []Example {
+	words := eval.Words(res.Original)
+	gold := eval.Align(words, eval.Words(expected))
+	var out []Example
+	k := 0
+	for _, ts := range res.Tokens {
+		// Слова корректора - подпоследовательность слов eval (без чисел)
+		for k < len(words) && words[k] != ts.Token {
+			k++
+		}
+		if k == len(words) {
+			break
+		}
+		ex := Example{Scores: make([]float64, len(ts.Candidates)), Gold: -1}
+		for i, c := range ts.Candidates {
+			ex.Scores[i] = c.Score
+			if c.Term == gold[k] {
+				ex.Gold = i
+			}
+		}
+		out = append(out, ex)
+		k++
+	}
+	return out
+}
+
+// Границы поиска температуры.
+const (
+	MinTemperature = 0.05
+	MaxTemperature = 20
+)
+
+// Fit возвращает температуру из [MinTemperature, MaxTemperature] с наименьшим Brier score.
+// Brier, в отличие от логарифмической потери, учитывает и слова, правильной формы которых
+// нет среди кандидатов: за них штрафуется любая уверенность в кандидатах.
+func Fit(examples []Example) float64 {
+	lo, hi := math.Log(MinTemperature), math.Log(MaxTemperature)
+	loss := func(x float64) float64 { return Brier(examples, math.Exp(x)) }
+
+	// Грубая сетка по логарифму температуры, затем золотое сечение между соседями лучшего узла
+	const steps = 100
+	step := (hi - lo) / steps
+	best, bestLoss := lo, loss(lo)
+	for i := 1; i <= steps; i++ {
+		if l := loss(lo + float64(i)*step); l < bestLoss {
+			best, bestLoss = lo+float64(i)*step, l
+		}
+	}
+	a, b := math.Max(lo, best-step), math.Min(hi, best+step)
+	g := (math.Sqrt(5) - 1) / 2
+	c, d := b-g*(b-a), a+g*(b-a)
+	lc, ld := loss(c), loss(d)
+	for b-a > 1e-4 {
+		if lc < ld {
+			b, d, ld = d, c, lc
+			c = b - g*(b-a)
+			lc = loss(c)
+		} else {
+			a, c, lc = c, d, ld
+			d = a + g*(b-a)
+			ld = loss(d)
+		}
+	}
+	if x := (a + b) / 2; loss(x) < bestLoss {
+		best = x
+	}
+	return math.Exp(best)
+}
+
+// Brier возвращает средний по словам Brier score вероятностей кандидатов при температуре t.
+func Brier(examples []Example, t float64) float64 {
+	if len(examples) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, ex := range examples {
+		total += brier(ex, t)
+	}
+	return total / float64(len(examples))
+}
+
+// brier - Brier score вероятностей кандидатов одного слова.
+func brier(ex Example, t float64) float64 {
+	total := 0.0
+	for i, p := range sc.Softmax(ex.Scores, t) {
+		y := 0.0
+		if i == ex.Gold {
+			y = 1
+		}
+		total += (p - y) * (p - y)
+	}
+	return total
+}
+
+// Bin - интервал диаграммы надежности: средняя уверенность лучшего кандидата
+// и доля слов, для которых он правильный.
+type Bin struct {
+	Min        float64 `json:"min"`
+	Max        float64 `json:"max"`
+	Words      int     `json:"words"`
+	Confidence float64 `json:"confidence"`
+	Accuracy   float64 `json:"accuracy"`
+}
+
+// Report - калибровка уверенности при одной температуре (у CrossValidate - при температурах блоков).
+type Report struct {
+	// Temperature - температура оценки; у CrossValidate - средняя по блокам.
+	Temperature float64 `json:"temperature"`
+	// Folds - число блоков кросс-валидации (0 для Evaluate).
+	Folds int `json:"folds,omitempty"`
+	Words int `json:"words"`
+	// Covered - слова, правильная форма которых есть среди кандидатов.
+	Covered int     `json:"covered"`
+	Brier   float64 `json:"brier"`
+	// ECE - взвешенное по числу слов расхождение уверенности и точности интервалов.
+	ECE  float64 `json:"ece"`
+	Bins []Bin   `json:"bins"`
+}
+
+// Evaluate оценивает калибровку при температуре t, деля уверенность на bins равных интервалов.
+func Evaluate(examples []Example, t float64, bins int) Report {
+	return evaluate(examples, func(int) float64 { return t }, bins)
+}
+
+// CrossValidate оценивает калибровку температуры, подобранной Fit, k-блочной
+// кросс-валидацией: слова делятся на folds блоков (i-е слово - в блок i mod folds),
+// температура блока подбирается по остальным блокам, и каждое слово оценивается
+// при температуре, подобранной без него. При folds больше числа слов каждое слово - блок;
+// при одном блоке отложенных слов нет, и температура подбирается по всем словам.
+func CrossValidate(examples []Example, folds, bins int) Report {
+	folds = max(1, min(folds, len(examples)))
+	temps := make([]float64, folds)
+	for f := range temps {
+		var train []Example
+		for i, ex := range examples {
+			if folds == 1 || i%folds != f {
+				train = append(train, ex)
+			}
+		}
+		temps[f] = Fit(train)
+	}
+	rep := evaluate(examples, func(i int) float64 { return temps[i%folds] }, bins)
+	mean := 0.0
+	for _, t := range temps {
+		mean += t / float64(folds)
+	}
+	rep.Temperature, rep.Folds = round(mean), folds
+	return rep
+}
+
+// evaluate оценивает калибровку, когда i-е слово оценивается при температуре temp(i).
+func evaluate(examples []Example, temp func(i int) float64, bins int) Report {
+	if bins < 1 {
+		bins = 1
+	}
+	rep := Report{Temperature: round(temp(0)), Words: len(examples)}
+	rep.Bins = make([]Bin, bins)
+	for i := range rep.Bins {
+		rep.Bins[i].Min = round(float64(i) / float64(bins))
+		rep.Bins[i].Max = round(float64(i+1) / float64(bins))
+	}
+	for i, ex := range examples {
+		t := temp(i)
+		rep.Brier += brier(ex, t) / float64(len(examples))
+		if ex.Gold >= 0 {
+			rep.Covered++
+		}
+		if len(ex.Scores) == 0 {
+			continue
+		}
+		probs := sc.Softmax(ex.Scores, t)
+		top := 0
+		for i, p := range probs {
+			if p > probs[top] {
+				top = i
+			}
+		}
+		b := &rep.Bins[min(int(probs[top]*float64(bins)), bins-1)]
+		b.Words++
+		b.Confidence += probs[top]
+		if top == ex.Gold {
+			b.Accuracy++
+		}
+	}
+	for i := range rep.Bins {
+		b := &rep.Bins[i]
+		if b.Words == 0 {
+			continue
+		}
+		rep.ECE += math.Abs(b.Confidence-b.Accuracy) / float64(rep.Words)
+		b.Confidence = round(b.Confidence / float64(b.Words))
+		b.Accuracy = round(b.Accuracy / float64(b.Words))
+	}
+	rep.Brier, rep.ECE = round(rep.Brier), round(rep.ECE)
+	return rep
+}
+
+func round(x float64) float64 { return math.Round(x*1e4) / 1e4 }
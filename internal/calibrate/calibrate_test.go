@@ -0,0 +1,130 @@
+package calibrate
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	sc "corrector/internal/corrector"
+)
+
+func TestSoftmax(t *testing.T) {
+	probs := sc.Softmax([]float64{2, 1, 0}, 1)
+	sum := 0.0
+	for i, p := range probs {
+		sum += p
+		if i > 0 && p >= probs[i-1] {
+			t.Errorf("вероятности не убывают вместе со скорами: %v", probs)
+		}
+	}
+	if math.Abs(sum-1) > 1e-12 || math.Abs(probs[0]/probs[1]-math.E) > 1e-9 {
+		t.Errorf("Softmax(2, 1, 0) = %v", probs)
+	}
+	// Температура сглаживает распределение; t <= 0 считается равной 1
+	if hot := sc.Softmax([]float64{2, 1, 0}, 4); hot[0] >= probs[0] {
+		t.Errorf("при t=4 вероятность лучшего %.4f не меньше, чем при t=1 (%.4f)", hot[0], probs[0])
+	}
+	if p := sc.Softmax([]float64{2, 1, 0}, 0); p[0] != probs[0] {
+		t.Errorf("Softmax при t=0: %v, ожидается %v", p, probs)
+	}
+	// Большие скоры не переполняют экспоненту
+	if p := sc.Softmax([]float64{1000, 1000}, 0.1); p[0] != 0.5 || p[1] != 0.5 {
+		t.Errorf("Softmax(1000, 1000) = %v", p)
+	}
+}
+
+func TestBrier(t *testing.T) {
+	examples := []Example{
+		{Scores: []float64{0, 0}, Gold: 0},  // (0.5-1)² + 0.5² = 0.5
+		{Scores: []float64{0, 0}, Gold: -1}, // 0.5² + 0.5² = 0.5
+		{Scores: []float64{50, 0}, Gold: 0}, // почти 0
+	}
+	if b := Brier(examples, 1); math.Abs(b-1.0/3) > 1e-9 {
+		t.Errorf("Brier = %v, ожидается 1/3", b)
+	}
+	if b := Brier(nil, 1); b != 0 {
+		t.Errorf("Brier без слов = %v", b)
+	}
+}
+
+// synthetic возвращает n слов, правильный кандидат которых выбран с вероятностями
+// Softmax(scores, temperature): при этой температуре уверенность откалибрована.
+func synthetic(n int, temperature float64, rng *rand.Rand) []Example {
+	examples := make([]Example, n)
+	for i := range examples {
+		scores := []float64{rng.Float64() * 3, rng.Float64() * 3, rng.Float64() * 3}
+		ex := Example{Scores: scores, Gold: len(scores) - 1}
+		r := rng.Float64()
+		for j, p := range sc.Softmax(scores, temperature) {
+			if r -= p; r < 0 {
+				ex.Gold = j
+				break
+			}
+		}
+		examples[i] = ex
+	}
+	return examples
+}
+
+func TestFit(t *testing.T) {
+	examples := synthetic(5000, 2, rand.New(rand.NewSource(1)))
+	temp := Fit(examples)
+	if temp < 1.6 || temp > 2.5 {
+		t.Errorf("Fit = %.3f, ожидается около 2", temp)
+	}
+	best := Brier(examples, temp)
+	for _, other := range []float64{MinTemperature, 0.5, 1, 1.5, 3, 5, MaxTemperature} {
+		if Brier(examples, other) < best {
+			t.Errorf("Brier при t=%g меньше, чем при подобранной %.3f", other, temp)
+		}
+	}
+	if temp := Fit(nil); temp < MinTemperature || temp > MaxTemperature {
+		t.Errorf("Fit без слов = %v", temp)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	// Уверенность лучшего кандидата 0.9, а прав он в половине случаев: ECE = 0.4
+	scores := []float64{math.Log(9), 0}
+	examples := []Example{
+		{Scores: scores, Gold: 0},
+		{Scores: scores, Gold: 1},
+		{Scores: scores, Gold: 0},
+		{Scores: scores, Gold: -1},
+		{Gold: -1}, // слово без кандидатов
+	}
+	rep := Evaluate(examples, 1, 10)
+	if rep.Words != 5 || rep.Covered != 3 || len(rep.Bins) != 10 {
+		t.Fatalf("Evaluate: %+v", rep)
+	}
+	if math.Abs(rep.ECE-0.4*4/5) > 1e-4 {
+		t.Errorf("ECE = %v, ожидается %v", rep.ECE, 0.4*4/5)
+	}
+	if b := rep.Bins[9]; b.Words != 4 || b.Confidence != 0.9 || b.Accuracy != 0.5 || b.Min != 0.9 || b.Max != 1 {
+		t.Errorf("интервал [0.9, 1]: %+v", b)
+	}
+	// Откалиброванная уверенность: ECE мал
+	calibrated := synthetic(5000, 1, rand.New(rand.NewSource(2)))
+	if rep := Evaluate(calibrated, 1, 10); rep.ECE > 0.03 {
+		t.Errorf("ECE откалиброванной уверенности = %v", rep.ECE)
+	}
+}
+
+func TestCrossValidate(t *testing.T) {
+	examples := synthetic(2000, 2, rand.New(rand.NewSource(3)))
+	rep := CrossValidate(examples, 5, 10)
+	fitted := Evaluate(examples, Fit(examples), 10)
+	if rep.Folds != 5 || rep.Words != len(examples) {
+		t.Errorf("CrossValidate: %d блоков, %d слов", rep.Folds, rep.Words)
+	}
+	if math.Abs(rep.Temperature-fitted.Temperature) > 0.3 {
+		t.Errorf("средняя температура блоков %.3f, по всем словам %.3f", rep.Temperature, fitted.Temperature)
+	}
+	// Оценка на отложенных словах не лучше оценки на словах подбора
+	if rep.Brier < fitted.Brier {
+		t.Errorf("Brier кросс-валидации %.4f меньше, чем на словах подбора (%.4f)", rep.Brier, fitted.Brier)
+	}
+	if rep := CrossValidate(examples[:3], 5, 10); rep.Folds != 3 {
+		t.Errorf("блоков при трех словах: %d", rep.Folds)
+	}
+}
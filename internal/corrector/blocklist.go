@@ -0,0 +1,56 @@
+package corrector
+
+import "strings"
+
+// =====================
+// Стоп-лист
+// =====================
+//
+// Стоп-лист тенанта - слова (брань, названия конкурентов, устаревшие формы), которые
+// остаются словарными, но никогда не предлагаются и не подставляются автозаменой.
+// Если такое слово набрано само, оно остается как есть: стоп-лист фильтрует только кандидатов.
+
+// BlockWord adds a word to the tenant's blocklist, so that it is never suggested
+// or applied as a correction for that tenant.
+func (sc *SpellCorrector) BlockWord(tenant, word string) error {
+	if !ValidTenant(tenant) {
+		return ErrInvalidTenant
+	}
+	lw, err := normalizeWord(word)
+	if err != nil {
+		return err
+	}
+	lex := sc.lexiconFor(tenant)
+	if sc.dict != nil {
+		if err := sc.dict.Block(tenant, lw); err != nil {
+			return err
+		}
+	}
+	lex.block(lw)
+	return nil
+}
+
+// UnblockWord removes a word from the tenant's blocklist and reports whether it was there.
+func (sc *SpellCorrector) UnblockWord(tenant, word string) (bool, error) {
+	if !ValidTenant(tenant) {
+		return false, ErrInvalidTenant
+	}
+	lw := strings.ToLower(strings.TrimSpace(word))
+	lex := sc.lexiconFor(tenant)
+	existed := lex.isBlocked(lw)
+	if sc.dict != nil {
+		if _, err := sc.dict.Unblock(tenant, lw); err != nil {
+			return false, err
+		}
+	}
+	lex.unblock(lw)
+	return existed, nil
+}
+
+// BlockedWords returns the tenant's blocklist, sorted.
+func (sc *SpellCorrector) BlockedWords(tenant string) ([]string, error) {
+	if !ValidTenant(tenant) {
+		return nil, ErrInvalidTenant
+	}
+	return sc.lexiconFor(tenant).blockedList(), nil
+}
@@ -0,0 +1,44 @@
+package corrector
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"corrector/internal/customdict"
+)
+
+func TestBlocklist(t *testing.T) {
+	sc := newTestCorrector(t, customdict.NewMemory())
+	if r := sc.CorrectText("кат", false); r.Corrected != "кот" {
+		t.Fatalf("CorrectText(кат) = %q, ожидается кот", r.Corrected)
+	}
+	if err := sc.BlockWord("acme", " Кот "); err != nil {
+		t.Fatal(err)
+	}
+	acme := Scope{Tenant: "acme"}
+	r := sc.CorrectTextFor(acme, "кат", false)
+	if r.Corrected == "кот" || slices.Contains(suggestions(r), "кот") {
+		t.Errorf("запрещенное слово предложено: %q %v", r.Corrected, suggestions(r))
+	}
+	// Набранное запрещенное слово не исправляется, другие тенанты стоп-лист не видят
+	if r := sc.CorrectTextFor(acme, "кот", false); r.Corrected != "кот" {
+		t.Errorf("CorrectTextFor(acme, кот) = %q", r.Corrected)
+	}
+	if r := sc.CorrectText("кат", false); r.Corrected != "кот" {
+		t.Errorf("стоп-лист acme применен к тенанту по умолчанию: %q", r.Corrected)
+	}
+	if blocked, _ := sc.BlockedWords("acme"); !slices.Equal(blocked, []string{"кот"}) {
+		t.Errorf("BlockedWords(acme) = %v", blocked)
+	}
+
+	if existed, err := sc.UnblockWord("acme", "кот"); err != nil || !existed {
+		t.Fatalf("UnblockWord(acme, кот) = %v, %v", existed, err)
+	}
+	if r := sc.CorrectTextFor(acme, "кат", false); r.Corrected != "кот" {
+		t.Errorf("после снятия запрета CorrectTextFor(acme, кат) = %q", r.Corrected)
+	}
+	if err := sc.BlockWord("acme", "два слова"); !errors.Is(err, ErrInvalidWord) {
+		t.Errorf("BlockWord(два слова) = %v, ожидается ErrInvalidWord", err)
+	}
+}
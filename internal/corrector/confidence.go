@@ -0,0 +1,58 @@
+package corrector
+
+import "math"
+
+// =====================
+// Калиброванная уверенность
+// =====================
+//
+// Скоры кандидатов - суммы логарифмов частот, штрафов и бонусов, несравнимые между словами.
+// Уверенность - softmax по всем кандидатам слова, включая исходное, с температурой
+// ConfidenceTemperature, подобранной на отложенном корпусе (cmd/calibrate): вероятность того,
+// что кандидат - правильная форма слова. Уверенность текста - произведение уверенностей
+// выбранных форм его слов (слова считаются независимыми).
+
+// TokenScores - скоры всех кандидатов проверенного слова.
+type TokenScores struct {
+	Pos    int    // индекс токена (как в DetailedSugs)
+	Token  string // слово в нижнем регистре
+	Chosen string // форма слова в исправленном тексте
+	// Candidates - кандидаты по убыванию скора, включая исходное слово; если его нет
+	// среди кандидатов (слово не из словаря), оно входит с базовым скором, с которым
+	// сравниваются исправления.
+	Candidates []Candidate
+}
+
+// Softmax переводит скоры в вероятности при температуре t (t <= 0 считается равной 1).
+func Softmax(scores []float64, t float64) []float64 {
+	if t <= 0 {
+		t = 1
+	}
+	probs := make([]float64, len(scores))
+	top := math.Inf(-1)
+	for _, s := range scores {
+		top = math.Max(top, s)
+	}
+	sum := 0.0
+	for i, s := range scores {
+		probs[i] = math.Exp((s - top) / t)
+		sum += probs[i]
+	}
+	for i := range probs {
+		probs[i] /= sum
+	}
+	return probs
+}
+
+// confidences возвращает вероятности кандидатов по их формам.
+func (sc *SpellCorrector) confidences(cands []Candidate) map[string]float64 {
+	scores := make([]float64, len(cands))
+	for i, c := range cands {
+		scores[i] = c.Score
+	}
+	out := make(map[string]float64, len(cands))
+	for i, p := range Softmax(scores, sc.config.ConfidenceTemperature) {
+		out[cands[i].Term] = p
+	}
+	return out
+}
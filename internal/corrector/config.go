@@ -1,5 +1,7 @@
 package corrector
 
+import "time"
+
 type CorrectorConfig struct {
 	MaxEditDistance  int
 	FreqTemperature  float64
@@ -17,6 +19,79 @@ type CorrectorConfig struct {
 	TransposeCost    float64
 	NeighborInsDel   float64
 	KeyboardNearSub  float64
+	// ErrorModelPath - файл модели ошибок (см. cmd/errtrain); если задан, стоимости замен,
+	// вставок, пропусков и перестановок берутся из него вместо ручных таблиц,
+	// а TransposeCost, NeighborInsDel и KeyboardNearSub не используются.
+	ErrorModelPath string
+	// MorphDictPath - путь к словарю morph.dawg; если пуст, словарь ищется
+	// по правилам analyzer.LoadMorphAnalyzer.
+	MorphDictPath string
+	// TaggerCorpusPath - размеченный корпус для обучения теггера (analyzer.Tagger)
+	// и оценки вероятностей разборов; если пуст, омонимия не снимается
+	// и согласование учитывает все разборы.
+	TaggerCorpusPath string
+	// MinParseProbability - минимальная контекстная вероятность разбора,
+	// при которой он участвует в согласовании (лучший разбор учитывается всегда).
+	MinParseProbability float64
+	// CustomWordFrequency - частота пользовательских слов, для которых она не указана
+	// (0 - прежнее значение 1e9, при котором пользовательское слово перевешивает любое словарное).
+	CustomWordFrequency float64
+	// MaxUserWords - наибольшее число слов в личном словаре пользователя (0 - без ограничения).
+	MaxUserWords int
+	// MaxTenantLexicons - сколько словарей тенантов держать в памяти (0 - без ограничения);
+	// при переполнении вытесняется словарь, к которому дольше всего не обращались.
+	MaxTenantLexicons int
+	// MaxUserLexicons - сколько личных словарей пользователей держать в памяти (0 - без ограничения).
+	MaxUserLexicons int
+	// LexiconIdleTimeout - через сколько без обращений словарь вытесняется из памяти
+	// (0 - не вытесняется); вытесненный словарь читается из хранилища заново.
+	// Без хранилища словари не вытесняются (этот параметр и ограничения числа словарей не действуют).
+	LexiconIdleTimeout time.Duration
+	// FeedbackWeight - наибольшая поправка скора кандидата по отзывам пользователей
+	// (принятым и отклоненным подсказкам); 0 - отзывы не влияют на исправления.
+	FeedbackWeight float64
+	// FeedbackMinUsers - сколько разных пользователей должны оценить пару
+	// "слово -> подсказка", чтобы отзывы начали влиять на скор (не меньше 1).
+	FeedbackMinUsers int
+	// FeedbackRatePerMinute - сколько отзывов в минуту принимается от одного клиента
+	// (0 - без ограничения).
+	FeedbackRatePerMinute int
+	// ConfidenceTemperature - температура softmax, переводящего скоры кандидатов
+	// в вероятности (см. confidence.go); подбирается на отложенном корпусе cmd/calibrate.
+	ConfidenceTemperature float64
+}
+
+// DefaultConfig возвращает настройки корректора по умолчанию (без путей к файлам),
+// общие для сервера и инструментов оценки и подбора параметров.
+func DefaultConfig() CorrectorConfig {
+	return CorrectorConfig{
+		MaxEditDistance:       2,
+		FreqTemperature:       2.0,
+		TopKSuggestions:       8,
+		BetaWeight:            1.0,
+		LambdaPenalty:         0.9,
+		GammaMorph:            1.05,
+		MarginThreshold:       0.25,
+		TauInVocab:            0.5,
+		TauOutVocab:           0.3,
+		UseSymSpell:           true,
+		UseMorphology:         true,
+		EnableContext:         true,
+		FilterShortWords:      true,
+		TransposeCost:         0.6,
+		NeighborInsDel:        0.9,
+		KeyboardNearSub:       0.6,
+		MinParseProbability:   0.2,
+		MaxUserWords:          1000,
+		MaxTenantLexicons:     1000,
+		MaxUserLexicons:       10_000,
+		LexiconIdleTimeout:    time.Hour,
+		CustomWordFrequency:   100_000,
+		FeedbackWeight:        1.0,
+		FeedbackMinUsers:      3,
+		FeedbackRatePerMinute: 30,
+		ConfidenceTemperature: 1.0,
+	}
 }
 
 type Candidate struct {
@@ -29,12 +104,19 @@ type Candidate struct {
 type ScoredSuggestion struct {
 	Text  string  `json:"text"`
 	Score float64 `json:"score"`
+	// Confidence - откалиброванная вероятность того, что вариант текста правильный.
+	Confidence float64 `json:"confidence"`
 }
 
 type SuggestionInfo struct {
 	Token       string   `json:"token"`
 	Suggestions []string `json:"suggestions"`
 	Decision    string   `json:"decision"`
+	// Confidence - вероятность того, что форма слова в исправленном тексте правильная
+	// (для hint_only - что правильно исходное слово).
+	Confidence float64 `json:"confidence"`
+	// Confidences - вероятности подсказок, в порядке Suggestions.
+	Confidences []float64 `json:"confidences"`
 }
 
 type CorrectionResult struct {
@@ -43,4 +125,8 @@ type CorrectionResult struct {
 	Suggestions  []ScoredSuggestion     `json:"suggestions,omitempty"` // ← НОВОЕ ПОЛЕ
 	Alternatives []string               `json:"alternatives,omitempty"`
 	DetailedSugs map[int]SuggestionInfo `json:"detailed_suggestions,omitempty"`
+	// Confidence - вероятность того, что исправленный текст правильный.
+	Confidence float64 `json:"confidence"`
+	// Tokens - скоры кандидатов каждого проверенного слова (для калибровки, см. cmd/calibrate).
+	Tokens []TokenScores `json:"-"`
 }
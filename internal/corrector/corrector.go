@@ -2,22 +2,26 @@ package corrector
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"os"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+	"unicode"
 
 	symspell "corrector/pkg"
-	"corrector/pkg/options"
 	"corrector/pkg/verbosity"
 
 	"corrector/internal/analyzer"
 	"corrector/internal/customdict"
+	"corrector/internal/errmodel"
 )
 
 // =====================
@@ -26,13 +30,20 @@ type SpellCorrector struct {
 	config      CorrectorConfig
 	symspell    symspell.SymSpell
 	morph       *analyzer.MorphAnalyzer
+	tagger      *analyzer.Tagger
 	frequencies map[string]float64
 	vocabSet    map[string]bool
-	customWords map[string]bool
-	dict        *customdict.CustomDict
-	parseCache  sync.Map // map[string][]*analyzer.Parsed
-	logpCache   sync.Map // map[string]float64
-	distCache   sync.Map // map[string]float64, ключ: a+"\u0000"+b
+	dict        customdict.Store
+	errModel    *errmodel.Model // обученные стоимости правок (nil - ручные таблицы)
+	// tenants и users - словари тенантов и личные словари пользователей поверх базового
+	// (см. lexicon.go и lexcache.go).
+	tenants    *lexiconCache
+	users      *lexiconCache // ключ: тенант+"\x00"+пользователь
+	userMu     sync.Mutex    // упорядочивает проверку лимита и добавление личных слов
+	votes      voteLimiter   // частота отзывов от одного клиента (см. feedback.go)
+	parseCache sync.Map      // map[string][]*analyzer.Parsed
+	logpCache  sync.Map      // map[string]float64
+	distCache  sync.Map      // map[string]float64, ключ: a+"\u0000"+b
 }
 
 // Взвешенный Дамерау–Левенштейн с кэшированием
@@ -41,30 +52,35 @@ func (sc *SpellCorrector) weightedDL(a, b string) float64 {
 	if v, ok := sc.distCache.Load(key); ok {
 		return v.(float64)
 	}
+	ra := []rune(a)
+	rb := []rune(b)
+	la, lb := len(ra), len(rb)
 	// быстрый путь для перестановки
 	if isOneAdjacentSwap(a, b) {
-		cost := sc.config.TransposeCost
+		k := 0
+		for ra[k] == rb[k] {
+			k++
+		}
+		cost := sc.transpositionCost(rb[k], rb[k+1])
 		sc.distCache.Store(key, cost)
 		return cost
 	}
-	insBase, delBase := sc.config.NeighborInsDel, sc.config.NeighborInsDel
-	ra := []rune(a)
-	rb := []rune(b)
-	la, lb := len(ra), len(rb)
-	if la == 0 {
-		return float64(lb) * insBase
-	}
-	if lb == 0 {
-		return float64(la) * delBase
+	// a - набранное слово, b - кандидат: лишний символ a - вставка, недостающий символ b - пропуск.
+	// Контекст вставок и пропусков - предыдущий символ кандидата.
+	prevOf := func(j int) rune {
+		if j == 0 {
+			return errmodel.Start
+		}
+		return rb[j-1]
 	}
 	// Две «скользящие» строки DP — экономим память
 	prev := make([]float64, lb+1)
 	curr := make([]float64, lb+1)
 	for j := 1; j <= lb; j++ {
-		prev[j] = float64(j) * insBase
+		prev[j] = prev[j-1] + sc.deletionCost(prevOf(j-1), rb[j-1])
 	}
 	for i := 1; i <= la; i++ {
-		curr[0] = float64(i) * delBase
+		curr[0] += sc.insertionCost(errmodel.Start, ra[i-1])
 		for j := 1; j <= lb; j++ {
 			var sub float64
 			if ra[i-1] == rb[j-1] {
@@ -73,12 +89,12 @@ func (sc *SpellCorrector) weightedDL(a, b string) float64 {
 				sub = sc.substitutionCost(ra[i-1], rb[j-1])
 			}
 			best := minf(
-				prev[j]+delBase,
-				minf(curr[j-1]+insBase, prev[j-1]+sub),
+				prev[j]+sc.insertionCost(prevOf(j), ra[i-1]),
+				minf(curr[j-1]+sc.deletionCost(prevOf(j-1), rb[j-1]), prev[j-1]+sub),
 			)
 			// транспозиция
 			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
-				best = math.Min(best, prev[j-2]+sc.config.TransposeCost)
+				best = math.Min(best, prev[j-2]+sc.transpositionCost(rb[j-2], rb[j-1]))
 			}
 			curr[j] = best
 		}
@@ -93,12 +109,22 @@ func (sc *SpellCorrector) weightedDL(a, b string) float64 {
 // Морфология: бонусы согласования
 // =====================
 
-func (sc *SpellCorrector) analyzeCached(word string) []*analyzer.Parsed {
+// analyzeCached возвращает разборы слова: из лексем словарей lex или морфоанализатора.
+// Лексемы тенантов видны только их запросам, поэтому их разборы не кэшируются.
+func (sc *SpellCorrector) analyzeCached(word string, lex lexicons) []*analyzer.Parsed {
 	lw := strings.ToLower(word)
+	if parses := lex.parse(lw); len(parses) > 0 {
+		return parses
+	}
 	if v, ok := sc.parseCache.Load(lw); ok {
 		return v.([]*analyzer.Parsed)
 	}
-	parses, _ := sc.morph.Analyze(lw)
+	// Словоформы лексемы (второй результат Analyze) для согласования не нужны,
+	// поэтому разбираем без генерации парадигмы.
+	parses := sc.morph.Parse(lw)
+	if len(parses) == 0 {
+		parses = sc.morph.ParsePredicted(lw)
+	}
 	if parses == nil {
 		parses = []*analyzer.Parsed{}
 	}
@@ -106,6 +132,51 @@ func (sc *SpellCorrector) analyzeCached(word string) []*analyzer.Parsed {
 	return parses
 }
 
+// contextParses возвращает доступ к разборам токенов для проверки согласования кандидата.
+// Если подключен теггер, разборы слов в окне вокруг idx (с кандидатом на месте idx)
+// ранжируются по контексту и остаются только правдоподобные; иначе возвращаются все разборы.
+func (sc *SpellCorrector) contextParses(candidate string, tokens []string, idx int, lex lexicons) func(i int) []*analyzer.Parsed {
+	at := func(i int) []*analyzer.Parsed {
+		if i == idx {
+			return sc.analyzeCached(candidate, lex)
+		}
+		return sc.analyzeCached(tokens[i], lex)
+	}
+	if sc.tagger == nil {
+		return at
+	}
+
+	// Окно покрывает все позиции, которые смотрит morphAgreementBonus (токены включают пробелы).
+	lo, hi := max(0, idx-10), min(len(tokens), idx+4)
+	var positions []int
+	var sentence [][]*analyzer.Parsed
+	for i := lo; i < hi; i++ {
+		if i != idx && !isWord(tokens[i]) {
+			continue
+		}
+		positions = append(positions, i)
+		sentence = append(sentence, at(i))
+	}
+	ranked := sc.tagger.Disambiguate(sentence)
+
+	likely := make(map[int][]*analyzer.Parsed, len(positions))
+	for j, i := range positions {
+		var keep []*analyzer.Parsed
+		for k, p := range ranked[j] {
+			if k == 0 || p.Probability >= sc.config.MinParseProbability {
+				keep = append(keep, p)
+			}
+		}
+		likely[i] = keep
+	}
+	return func(i int) []*analyzer.Parsed {
+		if ps, ok := likely[i]; ok {
+			return ps
+		}
+		return at(i)
+	}
+}
+
 func (sc *SpellCorrector) logPrior(word string) float64 {
 	lw := strings.ToLower(word)
 	if v, ok := sc.logpCache.Load(lw); ok {
@@ -121,6 +192,20 @@ func (sc *SpellCorrector) logPrior(word string) float64 {
 	return lp
 }
 
+// prior - логарифм априорной вероятности слова с учетом пользовательских словарей.
+// Слово из них получает свою частоту, а без нее - частоту по умолчанию
+// (но не меньше частоты в базовом словаре).
+func (sc *SpellCorrector) prior(word string, lex lexicons) float64 {
+	f, explicit, ok := lex.frequency(word)
+	if !ok {
+		return sc.logPrior(word)
+	}
+	if !explicit {
+		f = math.Max(f, sc.frequencies[word])
+	}
+	return math.Log(math.Pow(f, 1.0/sc.config.FreqTemperature))
+}
+
 // Мэппинг требований управления по предлогам (упрощённо)
 var prepCases = map[string]map[string]bool{
 	"к":  {"Дательный": true},
@@ -135,11 +220,12 @@ var prepCases = map[string]map[string]bool{
 	"на":    {"Винительный": true, "Предложный": true},
 }
 
-func (sc *SpellCorrector) morphAgreementBonus(candidate string, tokens []string, idx int) float64 {
+func (sc *SpellCorrector) morphAgreementBonus(candidate string, tokens []string, idx int, lex lexicons) float64 {
 	if !sc.config.EnableContext || !sc.config.UseMorphology || sc.morph == nil {
 		return 0
 	}
-	parses := sc.analyzeCached(candidate)
+	parsesAt := sc.contextParses(candidate, tokens, idx, lex)
+	parses := parsesAt(idx)
 	if len(parses) == 0 {
 		return 0
 	}
@@ -186,7 +272,7 @@ func (sc *SpellCorrector) morphAgreementBonus(candidate string, tokens []string,
 			if !isWord(tokens[i]) {
 				continue
 			}
-			vp := sc.analyzeCached(tokens[i])
+			vp := parsesAt(i)
 			for _, v := range vp {
 				if v.PartOfSpeech == "Глагол" {
 					// согласование по числу и (если есть) роду
@@ -232,7 +318,7 @@ func (sc *SpellCorrector) morphAgreementBonus(candidate string, tokens []string,
 	}
 	// налево
 	if idx-1 >= 0 && isWord(tokens[idx-1]) {
-		lp := sc.analyzeCached(tokens[idx-1])
+		lp := parsesAt(idx - 1)
 		for _, pL := range lp {
 			for _, pC := range parses {
 				if (pL.PartOfSpeech == "Прилагательное" && pC.PartOfSpeech == "Существительное" && agreeAdjNoun(pL, pC)) ||
@@ -245,7 +331,7 @@ func (sc *SpellCorrector) morphAgreementBonus(candidate string, tokens []string,
 	}
 	// направо
 	if idx+1 < len(tokens) && isWord(tokens[idx+1]) {
-		rp := sc.analyzeCached(tokens[idx+1])
+		rp := parsesAt(idx + 1)
 		for _, pR := range rp {
 			for _, pC := range parses {
 				if (pR.PartOfSpeech == "Прилагательное" && pC.PartOfSpeech == "Существительное" && agreeAdjNoun(pR, pC)) ||
@@ -275,8 +361,8 @@ func (sc *SpellCorrector) morphAgreementBonus(candidate string, tokens []string,
 		break
 	}
 
-	isCopula := func(w string) bool {
-		pp := sc.analyzeCached(strings.ToLower(w))
+	isCopula := func(i int) bool {
+		pp := parsesAt(i)
 		for _, p := range pp {
 			if p.PartOfSpeech == "Глагол" && (p.Lemma == "быть" || p.Lemma == "являться") {
 				return true
@@ -291,12 +377,12 @@ func (sc *SpellCorrector) morphAgreementBonus(candidate string, tokens []string,
 		if !isWord(tokens[j]) {
 			continue
 		}
-		if isCopula(tokens[j]) {
+		if isCopula(j) {
 			for k := j - 1; k >= max(0, j-4); k-- {
 				if !isWord(tokens[k]) {
 					continue
 				}
-				np := sc.analyzeCached(strings.ToLower(tokens[k]))
+				np := parsesAt(k)
 				for _, n := range np {
 					if n.PartOfSpeech != "Существительное" {
 						continue
@@ -340,12 +426,12 @@ func (sc *SpellCorrector) morphAgreementBonus(candidate string, tokens []string,
 // =====================
 
 var tokenRe = regexp.MustCompile(`[А-Яа-яЁёA-Za-z]+|\d+|\s+|[^\sA-Za-zА-Яа-яЁё0-9]`)
+var wordRe = regexp.MustCompile(`^[А-Яа-яЁёA-Za-z]+$`)
 
 func tokenize(text string) []string { return tokenRe.FindAllString(text, -1) }
 
 func isWord(tok string) bool {
-	ok, _ := regexp.MatchString(`^[А-Яа-яЁёA-Za-z]+$`, tok)
-	return ok
+	return wordRe.MatchString(tok)
 }
 
 func isTitle(s string) bool {
@@ -370,7 +456,7 @@ func title(s string) string {
 // Кандидаты
 // =====================
 
-func (sc *SpellCorrector) getCandidates(token string, maxDist int) []string {
+func (sc *SpellCorrector) getCandidates(token string, maxDist int, lex lexicons) []string {
 	if !sc.config.UseSymSpell {
 		return []string{token}
 	}
@@ -379,13 +465,21 @@ func (sc *SpellCorrector) getCandidates(token string, maxDist int) []string {
 		return []string{token}
 	}
 	out := []string{token}
+	// Слова из стоп-листа не предлагаем; сам токен остается кандидатом, даже если запрещен
 	seen := map[string]bool{token: true}
 	for _, s := range suggs {
-		if !seen[s.Term] {
+		if !seen[s.Term] && !lex.blocked(s.Term) {
 			out = append(out, s.Term)
 			seen[s.Term] = true
 		}
 	}
+	// Пользовательские слова ищутся в индексах их словарей
+	for _, term := range lex.lookup(token, maxDist) {
+		if !seen[term] && !lex.blocked(term) {
+			out = append(out, term)
+			seen[term] = true
+		}
+	}
 	// Сгенерируем ещё 1-hop кандидатов: одна перестановка соседних букв
 	r := []rune(token)
 	for i := 0; i+1 < len(r); i++ {
@@ -393,7 +487,7 @@ func (sc *SpellCorrector) getCandidates(token string, maxDist int) []string {
 		copy(sw, r)
 		sw[i], sw[i+1] = sw[i+1], sw[i]
 		cand := string(sw)
-		if !seen[cand] {
+		if !seen[cand] && !lex.blocked(cand) {
 			out = append(out, cand)
 			seen[cand] = true
 		}
@@ -405,20 +499,31 @@ func (sc *SpellCorrector) getCandidates(token string, maxDist int) []string {
 // Основная логика коррекции
 // =====================
 
+// CorrectText corrects text against the base vocabulary and the default tenant's dictionary.
 func (sc *SpellCorrector) CorrectText(text string, debug bool) CorrectionResult {
-	// Локальная метрика числа правок (униформный Левенштейн, без весов).
+	return sc.CorrectTextFor(Scope{}, text, debug)
+}
 
+// CorrectTextFor corrects text against the base vocabulary, the scope tenant's dictionary
+// and, when scope.User is set, the user's personal dictionary; words of other tenants
+// and users are neither accepted nor suggested.
+func (sc *SpellCorrector) CorrectTextFor(scope Scope, text string, debug bool) CorrectionResult {
+	lex := sc.lexiconsFor(scope)
 	tokens := tokenize(text)
 	out := make([]string, len(tokens))
 	copy(out, tokens)
 	sugByPos := make(map[int]SuggestionInfo)
 
 	totalScore := 0.0
+	logConfidence := 0.0 // логарифм уверенности исправленного текста
+	var tokenScores []TokenScores
 	type altChoice struct {
 		idx         int
 		altTerm     string
 		altScore    float64
 		chosenScore float64
+		altProb     float64
+		chosenProb  float64
 	}
 	var altChoices []altChoice
 
@@ -430,6 +535,27 @@ func (sc *SpellCorrector) CorrectText(text string, debug bool) CorrectionResult
 		}
 	}
 
+	// Принудительные замены по правилам тенанта применяются до скоринга;
+	// слова замененных фраз дальше не исправляются
+	forced := make(map[int]bool)
+	for _, f := range lex.matchRules(tokens) {
+		for k := f.start; k < f.end; k++ {
+			forced[k] = true
+			out[k] = ""
+		}
+		out[f.start] = f.to
+		sugByPos[f.start] = SuggestionInfo{
+			Token:       strings.Join(tokens[f.start:f.end], ""),
+			Suggestions: []string{f.to},
+			Decision:    "forced_replace",
+			Confidence:  1,
+			Confidences: []float64{1},
+		}
+		if debug {
+			fmt.Printf("  Decision for '%s': forced_replace -> %s\n", strings.Join(tokens[f.start:f.end], ""), f.to)
+		}
+	}
+
 	// контекст в нижнем регистре
 	ctx := make([]string, len(tokens))
 	for i, t := range tokens {
@@ -441,46 +567,42 @@ func (sc *SpellCorrector) CorrectText(text string, debug bool) CorrectionResult
 	}
 
 	for _, idx := range positions {
+		if forced[idx] {
+			continue
+		}
 		x := tokens[idx]
 		xl := strings.ToLower(x)
 		if sc.config.FilterShortWords && len([]rune(xl)) <= 2 {
 			continue
 		}
-		inCustom := sc.customWords != nil && sc.customWords[xl]
+		inCustom := lex.has(xl)
 		inVocab := sc.vocabSet[xl] || inCustom
 
 		// кандидаты (из словаря / симспелла)
-		candTerms := sc.getCandidates(xl, sc.config.MaxEditDistance)
+		candTerms := sc.getCandidates(xl, sc.config.MaxEditDistance, lex)
 
-		type Candidate struct {
-			Term  string
-			Cost  float64
-			Score float64
-			edits int
-		}
 		var scored []Candidate
-		baseScore := sc.config.BetaWeight * sc.logPrior(xl)
+		baseScore := sc.config.BetaWeight * sc.prior(xl, lex)
 		hasOriginal := false
 
 		lx := len([]rune(xl))
 
 		for _, y := range candTerms {
-			// Разрешаем оригинал и слова из словаря
-			if y != xl && !sc.vocabSet[y] && !sc.customWords[y] {
+			// Разрешаем оригинал и слова из словаря, кроме запрещенных
+			if y != xl && (!sc.vocabSet[y] && !lex.has(y) || lex.blocked(y)) {
 				continue
 			}
-			morph := 0.0
-			if sc.vocabSet[y] && !sc.customWords[y] {
-				morph = sc.morphAgreementBonus(y, ctx, idx)
-			}
+			// Согласование проверяется для любого кандидата, который разбирает морфоанализатор,
+			// в том числе для пользовательских слов; без разборов бонус нулевой.
+			morph := sc.morphAgreementBonus(y, ctx, idx, lex)
 
 			if y == xl {
-				score := sc.config.BetaWeight*sc.logPrior(y) + sc.config.GammaMorph*morph
+				score := sc.config.BetaWeight*sc.prior(y, lex) + sc.config.GammaMorph*morph
 				hasOriginal = true
-				scored = append(scored, Candidate{Term: y, Cost: 0, Score: score, edits: 0})
+				scored = append(scored, Candidate{Term: y, Cost: 0, Score: score, Edits: 0})
 				if debug {
 					fmt.Printf("    Original '%s': score=%.3f (logprior=%.3f, morph=%.3f)\n",
-						y, score, sc.logPrior(y), morph)
+						y, score, sc.prior(y, lex), morph)
 				}
 				continue
 			}
@@ -491,9 +613,11 @@ func (sc *SpellCorrector) CorrectText(text string, debug bool) CorrectionResult
 			ly := len([]rune(y))
 
 			// Базовый скор
-			score := sc.config.BetaWeight*sc.logPrior(y) -
+			score := sc.config.BetaWeight*sc.prior(y, lex) -
 				sc.config.LambdaPenalty*cost +
 				sc.config.GammaMorph*morph
+			fb := sc.feedbackBonus(lex, xl, y)
+			score += fb
 
 			// ----- ОБНОВЛЁННАЯ эвристика бонуса за 1 правку -----
 			// Дифференцируем по типу: замена/транспозиция > вставка > удаление
@@ -525,10 +649,10 @@ func (sc *SpellCorrector) CorrectText(text string, debug bool) CorrectionResult
 
 			// (Старый хук: сильное укорочение у 2–3 букв уже покрыто выше.)
 
-			scored = append(scored, Candidate{Term: y, Cost: cost, Score: score, edits: ed})
+			scored = append(scored, Candidate{Term: y, Cost: cost, Score: score, Edits: ed})
 			if debug {
-				fmt.Printf("    Candidate '%s': score=%.3f (logprior=%.3f, cost=%.3f, morph=%.3f, ed=%d)\n",
-					y, score, sc.logPrior(y), cost, morph, ed)
+				fmt.Printf("    Candidate '%s': score=%.3f (logprior=%.3f, cost=%.3f, morph=%.3f, feedback=%.3f, ed=%d)\n",
+					y, score, sc.prior(y, lex), cost, morph, fb, ed)
 			}
 		}
 
@@ -553,9 +677,9 @@ func (sc *SpellCorrector) CorrectText(text string, debug bool) CorrectionResult
 		}
 
 		// Перераздача в пользу одноисправочных (как раньше).
-		if best.edits > 1 {
+		if best.Edits > 1 {
 			for k := 1; k < len(scored) && k < 3; k++ {
-				if scored[k].edits == 1 && (best.Score-scored[k].Score) <= 1.0 {
+				if scored[k].Edits == 1 && (best.Score-scored[k].Score) <= 1.0 {
 					best = scored[k]
 					break
 				}
@@ -600,20 +724,33 @@ func (sc *SpellCorrector) CorrectText(text string, debug bool) CorrectionResult
 			decision = "auto_replace"
 			chosen = best.Term
 		}
+
+		// уверенность: softmax по всем кандидатам, включая исходное слово
+		cands := scored
+		if !hasOriginal {
+			cands = append(append([]Candidate(nil), scored...), Candidate{Term: xl, Score: baseScore})
+			sort.SliceStable(cands, func(i, j int) bool { return cands[i].Score > cands[j].Score })
+		}
+		conf := sc.confidences(cands)
+		logConfidence += math.Log(conf[chosen])
+		tokenScores = append(tokenScores, TokenScores{Pos: idx, Token: xl, Chosen: chosen, Candidates: cands})
 		if debug {
-			fmt.Printf("  Decision for '%s': margin=%.3f, gain=%.3f, tau=%.3f -> %s (chosen: %s)\n",
-				xl, margin, gain, tau, decision, chosen)
+			fmt.Printf("  Decision for '%s': margin=%.3f, gain=%.3f, tau=%.3f -> %s (chosen: %s, confidence=%.3f)\n",
+				xl, margin, gain, tau, decision, chosen, conf[chosen])
 		}
 
 		// список предложений
 		var list []string
+		var listConf []float64
 		for _, c := range scored {
 			if c.Term != xl && c.Score >= baseScore+0.2 && len(list) < sc.config.TopKSuggestions {
 				list = append(list, c.Term)
+				listConf = append(listConf, conf[c.Term])
 			}
 		}
 		if len(list) > 0 {
-			sugByPos[idx] = SuggestionInfo{Token: x, Suggestions: list, Decision: decision}
+			sugByPos[idx] = SuggestionInfo{Token: x, Suggestions: list, Decision: decision,
+				Confidence: conf[chosen], Confidences: listConf}
 		}
 
 		chosenScore := baseScore
@@ -628,7 +765,8 @@ func (sc *SpellCorrector) CorrectText(text string, debug bool) CorrectionResult
 		if chosen != xl {
 			for _, c := range scored {
 				if c.Term != chosen {
-					altChoices = append(altChoices, altChoice{idx: idx, altTerm: c.Term, altScore: c.Score, chosenScore: chosenScore})
+					altChoices = append(altChoices, altChoice{idx: idx, altTerm: c.Term, altScore: c.Score, chosenScore: chosenScore,
+						altProb: conf[c.Term], chosenProb: conf[chosen]})
 					break
 				}
 			}
@@ -647,8 +785,9 @@ func (sc *SpellCorrector) CorrectText(text string, debug bool) CorrectionResult
 	}
 
 	type altVariant struct {
-		text  string
-		score float64
+		text       string
+		score      float64
+		confidence float64
 	}
 	var alternatives []altVariant
 	for _, ch := range altChoices {
@@ -663,19 +802,27 @@ func (sc *SpellCorrector) CorrectText(text string, debug bool) CorrectionResult
 		altOut[ch.idx] = altTok
 		altText := strings.Join(altOut, "")
 		altScore := totalScore - ch.chosenScore + ch.altScore
-		alternatives = append(alternatives, altVariant{text: altText, score: altScore})
+		// Вариант отличается от исправленного текста одним словом
+		altConfidence := 0.0
+		if ch.chosenProb > 0 {
+			altConfidence = math.Exp(logConfidence) * ch.altProb / ch.chosenProb
+		}
+		alternatives = append(alternatives, altVariant{text: altText, score: altScore, confidence: altConfidence})
 	}
 	sort.Slice(alternatives, func(i, j int) bool { return alternatives[i].score > alternatives[j].score })
 
 	scoredSuggestions := make([]ScoredSuggestion, len(alternatives))
 	for i, a := range alternatives {
-		scoredSuggestions[i] = ScoredSuggestion{Text: a.text, Score: a.score}
+		scoredSuggestions[i] = ScoredSuggestion{Text: a.text, Score: a.score, Confidence: a.confidence}
 	}
 
 	return CorrectionResult{
-		Original:    text,
-		Corrected:   strings.Join(out, ""),
-		Suggestions: scoredSuggestions,
+		Original:     text,
+		Corrected:    strings.Join(out, ""),
+		Suggestions:  scoredSuggestions,
+		DetailedSugs: sugByPos,
+		Confidence:   math.Exp(logConfidence),
+		Tokens:       tokenScores,
 	}
 }
 
@@ -683,17 +830,21 @@ func (sc *SpellCorrector) CorrectText(text string, debug bool) CorrectionResult
 // Инициализация
 // =====================
 
-func NewSpellCorrector(cfg CorrectorConfig, dictionaryPath string, dict *customdict.CustomDict) (*SpellCorrector, error) {
-	sc := &SpellCorrector{config: cfg, dict: dict, customWords: make(map[string]bool)}
+func NewSpellCorrector(cfg CorrectorConfig, dictionaryPath string, dict customdict.Store) (*SpellCorrector, error) {
+	maxTenants, maxUsers, idle := cfg.MaxTenantLexicons, cfg.MaxUserLexicons, cfg.LexiconIdleTimeout
+	if dict == nil {
+		// Без хранилища словари есть только в памяти: вытесненный словарь не восстановить
+		maxTenants, maxUsers, idle = 0, 0, 0
+	}
+	sc := &SpellCorrector{
+		config:  cfg,
+		dict:    dict,
+		tenants: newLexiconCache(maxTenants, idle),
+		users:   newLexiconCache(maxUsers, idle),
+	}
 	// SymSpell
 	if cfg.UseSymSpell {
-		sc.symspell = symspell.NewSymSpell(
-			options.WithMaxDictionaryEditDistance(cfg.MaxEditDistance),
-			options.WithPrefixLength(7),
-			options.WithCountThreshold(1),
-			options.WithFrequencyThreshold(10),
-			options.WithFrequencyMultiplier(20),
-		)
+		sc.symspell = newIndex(cfg.MaxEditDistance)
 		var ok = true
 		var err error = nil
 		if err != nil {
@@ -705,7 +856,13 @@ func NewSpellCorrector(cfg CorrectorConfig, dictionaryPath string, dict *customd
 	}
 	// Морфология
 	if cfg.UseMorphology {
-		m, err := analyzer.LoadMorphAnalyzer()
+		var m *analyzer.MorphAnalyzer
+		var err error
+		if cfg.MorphDictPath != "" {
+			m, err = analyzer.LoadMorphAnalyzerFrom(cfg.MorphDictPath)
+		} else {
+			m, err = analyzer.LoadMorphAnalyzer()
+		}
 		if err != nil {
 			log.Printf("Предупреждение: не удалось загрузить морфоанализатор: %v", err)
 			sc.config.UseMorphology = false
@@ -713,14 +870,59 @@ func NewSpellCorrector(cfg CorrectorConfig, dictionaryPath string, dict *customd
 			sc.morph = m
 		}
 	}
+	// Размеченный корпус: теггер для снятия омонимии и статистика тегов для оценки разборов
+	if sc.morph != nil && cfg.TaggerCorpusPath != "" {
+		if err := sc.loadTaggedCorpus(cfg.TaggerCorpusPath); err != nil {
+			log.Printf("Предупреждение: не удалось загрузить размеченный корпус: %v", err)
+		}
+	}
+	// Модель ошибок вместо ручных таблиц стоимостей
+	if cfg.ErrorModelPath != "" {
+		m, err := errmodel.Load(cfg.ErrorModelPath)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки модели ошибок: %v", err)
+		}
+		sc.errModel = m
+	}
 	// Частоты
 	if err := sc.loadFrequencies(dictionaryPath); err != nil {
 		return nil, fmt.Errorf("ошибка загрузки частот: %v", err)
 	}
-	sc.loadCustomWords()
+	// Общий словарь загружаем сразу, словари остальных тенантов - при первом обращении
+	sc.lexiconFor(DefaultTenant)
 	return sc, nil
 }
 
+// Morph returns the morphological analyzer used by the corrector,
+// or nil when morphology is disabled or failed to load.
+func (sc *SpellCorrector) Morph() *analyzer.MorphAnalyzer {
+	return sc.morph
+}
+
+// Tagger returns the contextual parse disambiguator,
+// or nil when no tagged corpus is configured.
+func (sc *SpellCorrector) Tagger() *analyzer.Tagger {
+	return sc.tagger
+}
+
+func (sc *SpellCorrector) loadTaggedCorpus(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sentences, err := analyzer.ReadTaggedCorpus(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(sentences) == 0 {
+		return fmt.Errorf("%s: корпус не содержит предложений", path)
+	}
+	sc.tagger = analyzer.TrainTagger(sentences)
+	sc.morph.SetTagStats(analyzer.NewTagStats(sentences))
+	return nil
+}
+
 func (sc *SpellCorrector) loadFrequencies(path string) error {
 	f, err := os.Open(path)
 	if err != nil {
@@ -757,55 +959,360 @@ func (sc *SpellCorrector) loadFrequencies(path string) error {
 	return s.Err()
 }
 
-func (sc *SpellCorrector) loadCustomWords() {
-	if sc.dict == nil {
+// ErrNoParadigm возвращается AddCustomLexeme, если парадигму слова построить не удалось.
+var ErrNoParadigm = errors.New("не удалось построить парадигму")
+
+// ErrInvalidWord возвращается для пустого слова, слова с пробелами или отрицательной частоты.
+var ErrInvalidWord = errors.New("некорректное слово")
+
+// normalizeWord приводит отдельное слово (стоп-листа, личного словаря) к нижнему регистру
+// и проверяет его: слово не пустое и без пробелов.
+func normalizeWord(word string) (string, error) {
+	lw := strings.ToLower(strings.TrimSpace(word))
+	if lw == "" || strings.ContainsFunc(lw, unicode.IsSpace) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidWord, word)
+	}
+	return lw, nil
+}
+
+// defaultCustomFreq - частота пользовательских слов, если CustomWordFrequency не задана.
+const defaultCustomFreq = 1_000_000_000
+
+// customFrequency - частота пользовательских слов без собственной частоты.
+func (sc *SpellCorrector) customFrequency() float64 {
+	if sc.config.CustomWordFrequency > 0 {
+		return sc.config.CustomWordFrequency
+	}
+	return defaultCustomFreq
+}
+
+// normalizeEntry приводит слово записи к нижнему регистру и проставляет время изменения;
+// время создания сохраняется от прежней записи.
+func normalizeEntry(e customdict.Entry, lex *lexicon, now time.Time) (customdict.Entry, error) {
+	e.Word = strings.ToLower(strings.TrimSpace(e.Word))
+	if e.Word == "" || e.Frequency < 0 {
+		return customdict.Entry{}, fmt.Errorf("%w: %q", ErrInvalidWord, e.Word)
+	}
+	e.CreatedAt, e.UpdatedAt = now, now
+	if prev, ok := lex.entry(e.Word); ok && !prev.CreatedAt.IsZero() {
+		e.CreatedAt = prev.CreatedAt
+	}
+	return e, nil
+}
+
+// addLexemeForms регистрирует все формы лексемы в словаре тенанта, заменяя прежние формы той же леммы.
+// Формы с тегами участвуют в согласовании: лексемы общего словаря попадают в пользовательский
+// лексикон морфоанализатора (он один на всех), лексемы остальных тенантов - в лексикон
+// их словаря, который видят только запросы тенанта.
+func (sc *SpellCorrector) addLexemeForms(tenant string, lex *lexicon, lx customdict.Lexeme) {
+	lx.Forms = slices.Clone(lx.Forms)
+	forms := make([]string, 0, len(lx.Forms))
+	tagged := analyzer.Lexeme{Lemma: lx.Lemma}
+	for i, f := range lx.Forms {
+		lw := strings.ToLower(f.Word)
+		lx.Forms[i].Word = lw
+		forms = append(forms, lw)
+		if f.Tags != "" {
+			tagged.Forms = append(tagged.Forms, analyzer.LexemeForm{Word: lw, Tags: f.Tags})
+		}
+	}
+	removed := lex.setLexeme(lx)
+	if tenant != DefaultTenant {
+		replaceLexeme(lex.morph, tagged, tenant)
 		return
 	}
-	words, err := sc.dict.All()
-	if err != nil {
-		log.Printf("предупреждение: не удалось загрузить кастомные слова: %v", err)
+	if sc.morph == nil {
 		return
 	}
-	const freq = 1_000_000_000
-	for _, w := range words {
-		lw := strings.ToLower(w)
-		sc.customWords[lw] = true
-		sc.vocabSet[lw] = true
-		sc.frequencies[lw] = float64(freq)
-		if sc.config.UseSymSpell && sc.symspell != nil {
-			sc.symspell.CreateDictionaryEntry(lw, freq)
+	replaceLexeme(sc.morph, tagged, tenant)
+	for _, f := range append(forms, removed...) {
+		sc.parseCache.Delete(f)
+	}
+}
+
+// morphLexicon - то, куда попадают формы лексем с тегами: лексикон тенанта
+// (analyzer.Lexicon) или, для общего словаря, морфоанализатор.
+type morphLexicon interface {
+	AddLexeme(lx analyzer.Lexeme) error
+	RemoveLexeme(lemma string) bool
+}
+
+// replaceLexeme заменяет формы лексемы в морфологии тенанта.
+// AddLexeme заменяет лексему атомарно и не трогает неизменную, поэтому параллельные
+// разборы (и перечитывание словарей в Resync) не видят лексему пропавшей.
+// Лексема без тегов или с ошибочными тегами убирается, чтобы не остались старые формы.
+func replaceLexeme(m morphLexicon, tagged analyzer.Lexeme, tenant string) {
+	if len(tagged.Forms) == 0 {
+		m.RemoveLexeme(tagged.Lemma)
+		return
+	}
+	if err := m.AddLexeme(tagged); err != nil {
+		m.RemoveLexeme(tagged.Lemma)
+		log.Printf("предупреждение: лексема %q тенанта %q не добавлена в морфологию: %v", tagged.Lemma, tenant, err)
+	}
+}
+
+// AddCustomWord adds a word with optional frequency and metadata to the tenant's
+// dictionary and its store, or updates the entry of a word added before.
+// It returns the stored entry.
+func (sc *SpellCorrector) AddCustomWord(tenant string, e customdict.Entry) (customdict.Entry, error) {
+	if !ValidTenant(tenant) {
+		return customdict.Entry{}, ErrInvalidTenant
+	}
+	lex := sc.lexiconFor(tenant)
+	e, err := normalizeEntry(e, lex, time.Now().UTC())
+	if err != nil {
+		return customdict.Entry{}, err
+	}
+	if sc.dict != nil {
+		if err := sc.dict.Add(tenant, e); err != nil {
+			return customdict.Entry{}, err
+		}
+	}
+	lex.addWord(e)
+	return e, nil
+}
+
+// ImportCustomWords adds many words to the tenant's dictionary at once: they are
+// written to the store in one operation and become visible to correction together.
+// When a word occurs several times, its last entry wins.
+// It returns the number of words that were not in the dictionary before.
+func (sc *SpellCorrector) ImportCustomWords(tenant string, entries []customdict.Entry) (int, error) {
+	if !ValidTenant(tenant) {
+		return 0, ErrInvalidTenant
+	}
+	lex := sc.lexiconFor(tenant)
+	now := time.Now().UTC()
+	normalized := make([]customdict.Entry, 0, len(entries))
+	index := make(map[string]int, len(entries))
+	for _, e := range entries {
+		e, err := normalizeEntry(e, lex, now)
+		if err != nil {
+			return 0, err
+		}
+		if i, ok := index[e.Word]; ok {
+			normalized[i] = e
+			continue
+		}
+		index[e.Word] = len(normalized)
+		normalized = append(normalized, e)
+	}
+	if sc.dict != nil {
+		if err := sc.dict.AddAll(tenant, normalized); err != nil {
+			return 0, err
+		}
+	}
+	return lex.addWords(normalized), nil
+}
+
+// CustomWords returns the entries of the tenant's custom words, sorted.
+// Words added with a paradigm are not included; see CustomLexemes.
+func (sc *SpellCorrector) CustomWords(tenant string) ([]customdict.Entry, error) {
+	if !ValidTenant(tenant) {
+		return nil, ErrInvalidTenant
+	}
+	return sc.lexiconFor(tenant).entries(), nil
+}
+
+// CustomLexemes returns the tenant's custom lexemes with their forms and tags,
+// sorted by lemma. Passing them to AddLexeme restores them.
+func (sc *SpellCorrector) CustomLexemes(tenant string) ([]customdict.Lexeme, error) {
+	if !ValidTenant(tenant) {
+		return nil, ErrInvalidTenant
+	}
+	return sc.lexiconFor(tenant).lexemeList(), nil
+}
+
+// AddCustomLexeme adds the word lx.Lemma with all of its inflected forms to the tenant's
+// dictionary, keeping the frequency and metadata of lx (its Forms are ignored).
+// The paradigm is copied from the sample word `like` ("сбербанк" like "банк");
+// when `like` is empty it is taken from the dictionary or predicted.
+func (sc *SpellCorrector) AddCustomLexeme(tenant string, lx customdict.Lexeme, like string) (customdict.Lexeme, error) {
+	if !ValidTenant(tenant) {
+		return customdict.Lexeme{}, ErrInvalidTenant
+	}
+	lemma := strings.ToLower(strings.TrimSpace(lx.Lemma))
+	if lx.Frequency < 0 {
+		return customdict.Lexeme{}, fmt.Errorf("%w: %q", ErrInvalidWord, lemma)
+	}
+	if sc.morph == nil {
+		return customdict.Lexeme{}, fmt.Errorf("%w: морфология отключена", ErrNoParadigm)
+	}
+	parses := sc.morph.InflectLike(lemma, like)
+	if len(parses) == 0 {
+		return customdict.Lexeme{}, fmt.Errorf("%w для %q", ErrNoParadigm, lemma)
+	}
+
+	lx.Lemma, lx.Forms = lemma, []customdict.Form{{Word: lemma}}
+	seen := map[string]bool{lemma: true}
+	for _, p := range parses {
+		if p.Word == lemma && lx.Forms[0].Tags == "" {
+			lx.Forms[0].Tags = p.Tags
+		}
+		if seen[p.Word] {
+			continue
+		}
+		seen[p.Word] = true
+		lx.Forms = append(lx.Forms, customdict.Form{Word: p.Word, Tags: p.Tags})
+	}
+
+	return sc.saveLexeme(tenant, lx)
+}
+
+// AddLexeme adds a lexeme with explicitly given forms and tags to the tenant's dictionary.
+// Tags may use the dictionary format or OpenCorpora codes; tagged forms take part in
+// agreement checks for the tenant's requests (forms of the default tenant become
+// parses of the shared morphological analyzer and are seen by every tenant).
+func (sc *SpellCorrector) AddLexeme(tenant string, lx customdict.Lexeme) (customdict.Lexeme, error) {
+	if !ValidTenant(tenant) {
+		return customdict.Lexeme{}, ErrInvalidTenant
+	}
+	lx.Lemma = strings.ToLower(strings.TrimSpace(lx.Lemma))
+	if lx.Lemma == "" || len(lx.Forms) == 0 {
+		return customdict.Lexeme{}, fmt.Errorf("%w: нужны лемма и формы", ErrNoParadigm)
+	}
+	if lx.Frequency < 0 {
+		return customdict.Lexeme{}, fmt.Errorf("%w: %q", ErrInvalidWord, lx.Lemma)
+	}
+	forms := make([]customdict.Form, 0, len(lx.Forms))
+	for _, f := range lx.Forms {
+		word := strings.ToLower(strings.TrimSpace(f.Word))
+		if word == "" {
+			continue
+		}
+		tags := ""
+		if strings.TrimSpace(f.Tags) != "" {
+			tags = analyzer.NormalizeTags(f.Tags)
+		}
+		forms = append(forms, customdict.Form{Word: word, Tags: tags})
+	}
+	lx.Forms = forms
+	return sc.saveLexeme(tenant, lx)
+}
+
+// saveLexeme проставляет время изменения лексемы (время создания сохраняется
+// от прежней лексемы той же леммы), сохраняет ее в хранилище и регистрирует формы.
+func (sc *SpellCorrector) saveLexeme(tenant string, lx customdict.Lexeme) (customdict.Lexeme, error) {
+	lex := sc.lexiconFor(tenant)
+	now := time.Now().UTC()
+	lx.CreatedAt, lx.UpdatedAt = now, now
+	if prev, ok := lex.lexeme(lx.Lemma); ok && !prev.CreatedAt.IsZero() {
+		lx.CreatedAt = prev.CreatedAt
+	}
+	if sc.dict != nil {
+		if err := sc.dict.AddLexeme(tenant, lx); err != nil {
+			return customdict.Lexeme{}, err
 		}
 	}
+	sc.addLexemeForms(tenant, lex, lx)
+	return lx, nil
 }
 
-// AddCustomWord adds a custom word to the dictionary and Redis store.
-func (sc *SpellCorrector) AddCustomWord(word string) error {
+// RemoveCustomWord removes a word from the tenant's dictionary and its store.
+// If the word is the lemma of a custom lexeme, all of its forms are removed.
+func (sc *SpellCorrector) RemoveCustomWord(tenant, word string) error {
+	if !ValidTenant(tenant) {
+		return ErrInvalidTenant
+	}
 	lw := strings.ToLower(word)
+	lex := sc.lexiconFor(tenant)
 	if sc.dict != nil {
-		if err := sc.dict.Add(lw); err != nil {
+		if _, err := sc.dict.RemoveLexeme(tenant, lw); err != nil {
+			return err
+		}
+		if err := sc.dict.Remove(tenant, lw); err != nil {
 			return err
 		}
 	}
-	const freq = 1_000_000_000
-	sc.customWords[lw] = true
-	sc.vocabSet[lw] = true
-	sc.frequencies[lw] = float64(freq)
-	if sc.config.UseSymSpell && sc.symspell != nil {
-		sc.symspell.CreateDictionaryEntry(lw, freq)
-	}
+	lex.remove(lw)
+	sc.dropMorphLexeme(tenant, lex, lw)
 	return nil
 }
 
-// RemoveCustomWord removes a custom word from the dictionary and Redis store.
-func (sc *SpellCorrector) RemoveCustomWord(word string) error {
-	lw := strings.ToLower(word)
+// UserWords returns the personal dictionary of scope.User, sorted.
+func (sc *SpellCorrector) UserWords(scope Scope) ([]string, error) {
+	if !ValidTenant(scope.Tenant) {
+		return nil, ErrInvalidTenant
+	}
+	if !ValidUser(scope.User) {
+		return nil, ErrInvalidUser
+	}
+	if lex := sc.userLexiconFor(scope); lex != nil {
+		return lex.list(), nil
+	}
+	return []string{}, nil
+}
+
+// AddUserWord adds a word to the personal dictionary of scope.User.
+// It fails with ErrInvalidWord for an empty word or a word with spaces and with
+// ErrUserLimit when the dictionary already holds MaxUserWords words.
+func (sc *SpellCorrector) AddUserWord(scope Scope, word string) error {
+	if !ValidTenant(scope.Tenant) {
+		return ErrInvalidTenant
+	}
+	if !ValidUser(scope.User) {
+		return ErrInvalidUser
+	}
+	lw, err := normalizeWord(word)
+	if err != nil {
+		return err
+	}
+
+	sc.userMu.Lock()
+	defer sc.userMu.Unlock()
+	lex := sc.userLexiconFor(scope)
+	if lex != nil && lex.has(lw) {
+		return nil
+	}
+	if sc.config.MaxUserWords > 0 && lex != nil && lex.size() >= sc.config.MaxUserWords {
+		return fmt.Errorf("%w: не больше %d слов", ErrUserLimit, sc.config.MaxUserWords)
+	}
 	if sc.dict != nil {
-		if err := sc.dict.Remove(lw); err != nil {
+		if err := sc.dict.AddUserWord(scope.Tenant, scope.User, lw); err != nil {
 			return err
 		}
 	}
-	delete(sc.customWords, lw)
-	delete(sc.vocabSet, lw)
-	delete(sc.frequencies, lw)
+	if lex == nil {
+		if sc.dict != nil {
+			// Словаря не было (у пользователя не было слов или хранилище было недоступно):
+			// он прочитается из хранилища вместе с новым словом
+			sc.users.drop(userKey(scope))
+			return nil
+		}
+		lex = sc.newLexicon()
+		sc.users.set(userKey(scope), lex)
+	}
+	lex.addWord(customdict.Entry{Word: lw})
 	return nil
 }
+
+// RemoveUserWord removes a word from the personal dictionary of scope.User
+// and reports whether it was there.
+func (sc *SpellCorrector) RemoveUserWord(scope Scope, word string) (bool, error) {
+	if !ValidTenant(scope.Tenant) {
+		return false, ErrInvalidTenant
+	}
+	if !ValidUser(scope.User) {
+		return false, ErrInvalidUser
+	}
+	lw := strings.ToLower(strings.TrimSpace(word))
+
+	sc.userMu.Lock()
+	defer sc.userMu.Unlock()
+	lex := sc.userLexiconFor(scope)
+	existed := lex != nil && lex.has(lw)
+	if sc.dict != nil {
+		if _, err := sc.dict.RemoveUserWord(scope.Tenant, scope.User, lw); err != nil {
+			return false, err
+		}
+	}
+	if !existed {
+		return false, nil
+	}
+	lex.remove(lw)
+	if lex.size() == 0 {
+		// Последнее слово удалено: пустой словарь не храним
+		sc.users.set(userKey(scope), nil)
+	}
+	return true, nil
+}
@@ -0,0 +1,105 @@
+package corrector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"corrector/internal/analyzer"
+	"corrector/internal/customdict"
+)
+
+// newTestCorrector создает корректор с частотным словарем testdata/freq.txt
+// и морфологическим словарем, собранным из тестового словаря анализатора.
+func newTestCorrector(tb testing.TB, dict customdict.Store) *SpellCorrector {
+	tb.Helper()
+	cfg := DefaultConfig()
+	cfg.MorphDictPath = buildMorphDict(tb)
+	sc, err := NewSpellCorrector(cfg, filepath.Join("testdata", "freq.txt"), dict)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if sc.Morph() == nil {
+		tb.Fatal("морфологический словарь не загружен")
+	}
+	return sc
+}
+
+// buildMorphDict компилирует ../analyzer/testdata/lexemes.tsv во временный morph.dawg.
+func buildMorphDict(tb testing.TB) string {
+	tb.Helper()
+	src, err := os.Open(filepath.Join("..", "analyzer", "testdata", "lexemes.tsv"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer src.Close()
+	b := analyzer.NewBuilder()
+	if err := analyzer.ReadTSV(src, b); err != nil {
+		tb.Fatal(err)
+	}
+	path := filepath.Join(tb.TempDir(), "morph.dawg")
+	out, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := b.WriteTo(out); err != nil {
+		tb.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+// suggestions возвращает подсказки для первого слова результата коррекции.
+func suggestions(r CorrectionResult) []string {
+	return r.DetailedSugs[0].Suggestions
+}
+
+func TestTenantLexemeAgreement(t *testing.T) {
+	sc := newTestCorrector(t, customdict.NewMemory())
+	lx := customdict.Lexeme{Lemma: "вайбить", Forms: []customdict.Form{
+		{Word: "вайбить", Tags: "INFN,impf,intr"},
+		{Word: "вайбила", Tags: "VERB,impf,intr,femn,sing,past,indc"},
+	}}
+	if _, err := sc.AddLexeme("acme", lx); err != nil {
+		t.Fatal(err)
+	}
+	tokens := []string{"она", " ", "вайбила"}
+	acme := sc.lexiconsFor(Scope{Tenant: "acme"})
+	if bonus := sc.morphAgreementBonus("вайбила", tokens, 2, acme); bonus <= 0 {
+		t.Errorf("лексема тенанта не участвует в согласовании: бонус %.2f", bonus)
+	}
+	for _, tenant := range []string{DefaultTenant, "other"} {
+		for _, p := range sc.analyzeCached("вайбила", sc.lexiconsFor(Scope{Tenant: tenant})) {
+			if p.Origin == analyzer.OriginUser {
+				t.Errorf("лексема тенанта acme видна тенанту %q", tenant)
+			}
+		}
+	}
+
+	if err := sc.RemoveCustomWord("acme", "вайбить"); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range sc.analyzeCached("вайбила", acme) {
+		if p.Origin == analyzer.OriginUser {
+			t.Error("разбор удаленной лексемы остался в лексиконе тенанта")
+		}
+	}
+}
+
+func BenchmarkCorrectText(b *testing.B) {
+	sc := newTestCorrector(b, customdict.NewMemory())
+	texts := []string{
+		"Мама мыла раму, а кто сидит на стле?",
+		"Она шла в дмо, где стали красивые банки",
+		"малоко и карова, вобще привет",
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, t := range texts {
+			sc.CorrectText(t, false)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package corrector
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"corrector/pkg/verbosity"
+
+	"corrector/internal/customdict"
+)
+
+func TestCustomWords(t *testing.T) {
+	store := customdict.NewMemory()
+	sc := newTestCorrector(t, store)
+	acme := Scope{Tenant: "acme"}
+
+	if r := sc.CorrectTextFor(acme, "зиплаин", false); slices.Contains(suggestions(r), "зиплайн") {
+		t.Fatalf("подсказка %q до добавления слова", "зиплайн")
+	}
+	e, err := sc.AddCustomWord("acme", customdict.Entry{Word: " Зиплайн ", Frequency: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Word != "зиплайн" || e.CreatedAt.IsZero() {
+		t.Errorf("AddCustomWord = %+v", e)
+	}
+	if r := sc.CorrectTextFor(acme, "зиплаин", false); !slices.Contains(suggestions(r), "зиплайн") {
+		t.Errorf("CorrectTextFor(acme, зиплаин) = %q %v, ожидается подсказка зиплайн", r.Corrected, suggestions(r))
+	}
+	if r := sc.CorrectTextFor(acme, "Зиплайн", false); r.Corrected != "Зиплайн" || len(r.DetailedSugs) > 0 {
+		t.Errorf("слово тенанта исправлено: %q %v", r.Corrected, r.DetailedSugs)
+	}
+
+	// Новый корректор читает слово из хранилища
+	if r := newTestCorrector(t, store).CorrectTextFor(acme, "зиплаин", false); !slices.Contains(suggestions(r), "зиплайн") {
+		t.Errorf("слово тенанта не загружено из хранилища: %v", suggestions(r))
+	}
+	words, err := sc.CustomWords("acme")
+	if err != nil || len(words) != 1 || words[0].Word != "зиплайн" || words[0].Frequency != 500 {
+		t.Errorf("CustomWords(acme) = %+v, %v", words, err)
+	}
+
+	if err := sc.RemoveCustomWord("acme", "ЗИПЛАЙН"); err != nil {
+		t.Fatal(err)
+	}
+	if r := sc.CorrectTextFor(acme, "зиплаин", false); slices.Contains(suggestions(r), "зиплайн") {
+		t.Errorf("подсказка удаленного слова: %v", suggestions(r))
+	}
+	if words, _ := store.All("acme"); len(words) != 0 {
+		t.Errorf("слово осталось в хранилище: %+v", words)
+	}
+
+	if _, err := sc.AddCustomWord("acme", customdict.Entry{Word: "  "}); !errors.Is(err, ErrInvalidWord) {
+		t.Errorf("AddCustomWord(пустое слово) = %v, ожидается ErrInvalidWord", err)
+	}
+	if _, err := sc.AddCustomWord("a/b", customdict.Entry{Word: "слово"}); !errors.Is(err, ErrInvalidTenant) {
+		t.Errorf("AddCustomWord(a/b) = %v, ожидается ErrInvalidTenant", err)
+	}
+}
+
+// TestCustomLexemeMetadata проверяет, что частота и метаданные лексемы сохраняются
+// вместе с ее формами.
+func TestCustomLexemeMetadata(t *testing.T) {
+	store := customdict.NewMemory()
+	sc := newTestCorrector(t, store)
+	lx, err := sc.AddCustomLexeme("acme", customdict.Lexeme{Lemma: "Сбербанк", Frequency: 700, Note: "бренд", Author: "alice"}, "банк")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lx.Lemma != "сбербанк" || len(lx.Forms) < 2 || lx.Frequency != 700 || lx.Note != "бренд" || lx.Author != "alice" || lx.CreatedAt.IsZero() {
+		t.Errorf("AddCustomLexeme = %+v", lx)
+	}
+	stored, _ := store.Lexemes("acme")
+	if len(stored) != 1 || stored[0].Frequency != 700 || stored[0].Note != "бренд" || stored[0].Author != "alice" {
+		t.Errorf("лексема в хранилище: %+v", stored)
+	}
+	if got, _ := newTestCorrector(t, store).CustomLexemes("acme"); len(got) != 1 || got[0].Frequency != 700 || got[0].Note != "бренд" {
+		t.Errorf("лексема не загружена из хранилища с метаданными: %+v", got)
+	}
+
+	// Замена лексемы сохраняет время создания
+	again, err := sc.AddLexeme("acme", customdict.Lexeme{Lemma: "сбербанк", Forms: lx.Forms, Frequency: 900})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.CreatedAt.Equal(lx.CreatedAt) || again.Frequency != 900 || again.Note != "" {
+		t.Errorf("замена лексемы: %+v", again)
+	}
+	if _, err := sc.AddLexeme("acme", customdict.Lexeme{Lemma: "сбербанк", Forms: lx.Forms, Frequency: -1}); !errors.Is(err, ErrInvalidWord) {
+		t.Errorf("AddLexeme(частота -1) = %v, ожидается ErrInvalidWord", err)
+	}
+}
+
+// TestLexiconLexemeFrequency проверяет частоты форм лексем в индексе SymSpell:
+// собственная частота отдельного слова важнее частоты лексемы.
+func TestLexiconLexemeFrequency(t *testing.T) {
+	l := newLexicon(true, 2, 10)
+	l.setLexeme(customdict.Lexeme{Lemma: "кот", Forms: []customdict.Form{{Word: "кот"}, {Word: "кота"}}, Frequency: 500})
+	l.addWord(customdict.Entry{Word: "кота", Frequency: 50})
+	l.setLexeme(customdict.Lexeme{Lemma: "котик", Forms: []customdict.Form{{Word: "котик"}}})
+	// Частота уже известной формы меняется вместе с частотой лексемы
+	l.setLexeme(customdict.Lexeme{Lemma: "котяра", Forms: []customdict.Form{{Word: "котик"}}, Frequency: 300})
+
+	for word, want := range map[string]int{"кот": 500, "кота": 50, "котик": 300} {
+		suggs, err := l.index.Lookup(word, verbosity.Top, 0)
+		if err != nil || len(suggs) != 1 || suggs[0].Count != want {
+			t.Errorf("частота %q в индексе: %+v, %v, ожидается %d", word, suggs, err, want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package corrector
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"corrector/internal/customdict"
+)
+
+// =====================
+// Обучение на отзывах
+// =====================
+//
+// Клиенты показывают подсказки, пользователи их принимают или отклоняют. Отзывы
+// копятся по парам "слово -> подсказка" в словаре тенанта и дают поправку к скору
+// кандидата: принятые пары поднимаются, отклоненные опускаются.
+//
+// Защита от отравления:
+//   - отзывы тенанта влияют только на этот тенант;
+//   - голос принадлежит клиенту (customdict.Vote.Voter): адресу, с которого пришел
+//     отзыв, или личности, которую подставил аутентифицирующий прокси. Пользователь
+//     из тела запроса или X-User-ID не проверяется и голосом не считается, иначе
+//     один клиент выдал бы себя за FeedbackMinUsers пользователей;
+//   - у клиента один голос на пару (повторный отзыв заменяет прежний);
+//   - от клиента принимается не больше FeedbackRatePerMinute отзывов в минуту;
+//   - пара учитывается, только когда ее оценили FeedbackMinUsers разных клиентов;
+//   - поправка сглажена и ограничена FeedbackWeight, так что отзывы сдвигают выбор
+//     между близкими кандидатами, но не перебивают словарь и модель ошибок;
+//   - подсказка должна быть известным словом, а не произвольной строкой.
+//
+// Клиенты за общим адресом (NAT, прокси без передачи личности) голосуют как один.
+
+// ErrInvalidFeedback возвращается для отзыва с пустыми или совпадающими словами или неизвестной подсказкой.
+var ErrInvalidFeedback = errors.New("некорректный отзыв")
+
+// ErrFeedbackRateLimit возвращается, когда клиент превысил FeedbackRatePerMinute.
+var ErrFeedbackRateLimit = errors.New("слишком много отзывов")
+
+const (
+	// feedbackSmoothing - псевдоголоса в знаменателе поправки: при малом числе
+	// голосов поправка заметно меньше FeedbackWeight.
+	feedbackSmoothing = 2.0
+	// maxFeedbackContext - наибольшая длина сохраняемого контекста отзыва в символах.
+	maxFeedbackContext = 200
+	// voteWindow - окно, в котором считаются отзывы клиента.
+	voteWindow = time.Minute
+)
+
+// voteLimiter считает отзывы клиентов в окне фиксированной длины. Счетчики
+// сбрасываются целиком в начале окна, так что память ограничена числом клиентов за окно.
+type voteLimiter struct {
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+// allow учитывает отзыв клиента и сообщает, укладывается ли он в лимит.
+func (vl *voteLimiter) allow(client string, limit int, now time.Time) bool {
+	if limit <= 0 {
+		return true
+	}
+	vl.mu.Lock()
+	defer vl.mu.Unlock()
+	if vl.counts == nil || now.Sub(vl.start) >= voteWindow {
+		vl.start, vl.counts = now, make(map[string]int)
+	}
+	if vl.counts[client] >= limit {
+		return false
+	}
+	vl.counts[client]++
+	return true
+}
+
+// PairFeedback - сводка отзывов о подсказке Suggestion для слова Original.
+type PairFeedback struct {
+	Original   string `json:"original"`
+	Suggestion string `json:"suggestion"`
+	Accepted   int    `json:"accepted"`
+	Rejected   int    `json:"rejected"`
+	// Adjustment - текущая поправка скора кандидата (0, пока голосов недостаточно).
+	Adjustment float64 `json:"adjustment"`
+}
+
+// pairKey - ключ пары "слово -> подсказка".
+func pairKey(original, suggestion string) string {
+	return original + "\x00" + suggestion
+}
+
+// addVote учитывает голос, заменяя прежний голос того же клиента по той же паре.
+func (l *lexicon) addVote(v customdict.Vote) {
+	key := pairKey(strings.ToLower(v.Original), strings.ToLower(v.Suggestion))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	voters := l.votes[key]
+	if voters == nil {
+		voters = make(map[string]bool)
+		l.votes[key] = voters
+	}
+	voters[v.Voter()] = v.Accepted
+}
+
+// tally возвращает число клиентов, принявших и отклонивших пару.
+func (l *lexicon) tally(key string) (accepted, rejected int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, ok := range l.votes[key] {
+		if ok {
+			accepted++
+		} else {
+			rejected++
+		}
+	}
+	return accepted, rejected
+}
+
+// pairs возвращает все пары, по которым есть отзывы, в порядке ключей.
+func (l *lexicon) pairs() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	keys := make([]string, 0, len(l.votes))
+	for k := range l.votes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// tally суммирует голоса по паре во всех словарях.
+func (ls lexicons) tally(key string) (accepted, rejected int) {
+	for _, l := range ls {
+		a, r := l.tally(key)
+		accepted, rejected = accepted+a, rejected+r
+	}
+	return accepted, rejected
+}
+
+// feedbackAdjustment переводит голоса в поправку скора в пределах ±FeedbackWeight.
+func (sc *SpellCorrector) feedbackAdjustment(accepted, rejected int) float64 {
+	n := accepted + rejected
+	if sc.config.FeedbackWeight <= 0 || n == 0 || n < sc.config.FeedbackMinUsers {
+		return 0
+	}
+	return sc.config.FeedbackWeight * float64(accepted-rejected) / (float64(n) + feedbackSmoothing)
+}
+
+// feedbackBonus - поправка скора кандидата y для слова x по отзывам пользователей.
+func (sc *SpellCorrector) feedbackBonus(lex lexicons, x, y string) float64 {
+	if sc.config.FeedbackWeight <= 0 {
+		return 0
+	}
+	return sc.feedbackAdjustment(lex.tally(pairKey(x, y)))
+}
+
+// RecordFeedback stores a verdict on the suggestion v.Suggestion for v.Original
+// in the tenant's dictionary. v.Client identifies the sender: a later verdict of
+// the same client on the same pair replaces the earlier one, and a client sending
+// more than FeedbackRatePerMinute votes a minute gets ErrFeedbackRateLimit.
+// Without v.Client votes are told apart by scope.User. It returns the stored vote.
+func (sc *SpellCorrector) RecordFeedback(scope Scope, v customdict.Vote) (customdict.Vote, error) {
+	if !ValidTenant(scope.Tenant) {
+		return customdict.Vote{}, ErrInvalidTenant
+	}
+	if scope.User != "" && !ValidUser(scope.User) {
+		return customdict.Vote{}, ErrInvalidUser
+	}
+	v.Original = strings.ToLower(strings.TrimSpace(v.Original))
+	v.Suggestion = strings.ToLower(strings.TrimSpace(v.Suggestion))
+	if v.Original == "" || v.Suggestion == "" || v.Original == v.Suggestion ||
+		strings.ContainsFunc(v.Original, unicode.IsSpace) || strings.ContainsFunc(v.Suggestion, unicode.IsSpace) {
+		return customdict.Vote{}, fmt.Errorf("%w: %q -> %q", ErrInvalidFeedback, v.Original, v.Suggestion)
+	}
+	lex := sc.lexiconsFor(scope)
+	if !sc.vocabSet[v.Suggestion] && !lex.has(v.Suggestion) {
+		return customdict.Vote{}, fmt.Errorf("%w: неизвестное слово %q", ErrInvalidFeedback, v.Suggestion)
+	}
+	v.User = scope.User
+	if !sc.votes.allow(v.Voter(), sc.config.FeedbackRatePerMinute, time.Now()) {
+		return customdict.Vote{}, ErrFeedbackRateLimit
+	}
+	v.Context = strings.TrimSpace(v.Context)
+	if utf8.RuneCountInString(v.Context) > maxFeedbackContext {
+		v.Context = string([]rune(v.Context)[:maxFeedbackContext])
+	}
+	v.Time = time.Now().UTC()
+
+	tenantLex := sc.lexiconFor(scope.Tenant)
+	if sc.dict != nil {
+		if err := sc.dict.AddVote(scope.Tenant, v); err != nil {
+			return customdict.Vote{}, err
+		}
+	}
+	tenantLex.addVote(v)
+	return v, nil
+}
+
+// Feedback returns the per-pair summary of the tenant's feedback with the
+// score adjustments currently applied, sorted by pair.
+func (sc *SpellCorrector) Feedback(tenant string) ([]PairFeedback, error) {
+	if !ValidTenant(tenant) {
+		return nil, ErrInvalidTenant
+	}
+	lex := sc.lexiconFor(tenant)
+	keys := lex.pairs()
+	out := make([]PairFeedback, 0, len(keys))
+	for _, key := range keys {
+		original, suggestion, _ := strings.Cut(key, "\x00")
+		accepted, rejected := lex.tally(key)
+		out = append(out, PairFeedback{
+			Original:   original,
+			Suggestion: suggestion,
+			Accepted:   accepted,
+			Rejected:   rejected,
+			Adjustment: sc.feedbackAdjustment(accepted, rejected),
+		})
+	}
+	return out, nil
+}
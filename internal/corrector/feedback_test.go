@@ -0,0 +1,91 @@
+package corrector
+
+import (
+	"errors"
+	"testing"
+
+	"corrector/internal/customdict"
+)
+
+func TestFeedback(t *testing.T) {
+	store := customdict.NewMemory()
+	sc := newTestCorrector(t, store)
+	acme := Scope{Tenant: "acme"}
+	if s := suggestions(sc.CorrectTextFor(acme, "стле", false)); len(s) == 0 || s[0] != "стал" {
+		t.Fatalf("подсказки для стле = %v, ожидается первой стал", s)
+	}
+
+	vote := func(user string, accepted bool) {
+		t.Helper()
+		v := customdict.Vote{Original: "стле", Suggestion: "стол", Accepted: accepted}
+		if _, err := sc.RecordFeedback(Scope{Tenant: "acme", User: user}, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Повторные голоса одного пользователя заменяют прежний: голосов меньше FeedbackMinUsers
+	vote("alice", false)
+	vote("alice", true)
+	vote("bob", true)
+	vote("bob", true)
+	fb, _ := sc.Feedback("acme")
+	if len(fb) != 1 || fb[0].Accepted != 2 || fb[0].Rejected != 0 || fb[0].Adjustment != 0 {
+		t.Fatalf("Feedback(acme) = %+v, ожидается 2 голоса без поправки", fb)
+	}
+
+	vote("carol", true)
+	fb, _ = sc.Feedback("acme")
+	if len(fb) != 1 || fb[0].Accepted != 3 || fb[0].Adjustment <= 0 {
+		t.Fatalf("Feedback(acme) = %+v, ожидается положительная поправка", fb)
+	}
+	if s := suggestions(sc.CorrectTextFor(acme, "стле", false)); len(s) == 0 || s[0] != "стол" {
+		t.Errorf("подсказки acme для стле = %v, ожидается первой стол", s)
+	}
+	if s := suggestions(sc.CorrectText("стле", false)); len(s) == 0 || s[0] != "стал" {
+		t.Errorf("отзывы acme повлияли на тенант по умолчанию: %v", s)
+	}
+	if fb, _ := sc.Feedback("globex"); len(fb) != 0 {
+		t.Errorf("Feedback(globex) = %+v", fb)
+	}
+	// Голоса читаются из хранилища новым корректором
+	if fb, _ := newTestCorrector(t, store).Feedback("acme"); len(fb) != 1 || fb[0].Accepted != 3 {
+		t.Errorf("голоса не загружены из хранилища: %+v", fb)
+	}
+
+	for _, v := range []customdict.Vote{
+		{Original: "стле", Suggestion: "стле"},
+		{Original: "стле", Suggestion: "стлоо"},
+		{Original: "", Suggestion: "стол"},
+	} {
+		if _, err := sc.RecordFeedback(acme, v); !errors.Is(err, ErrInvalidFeedback) {
+			t.Errorf("RecordFeedback(%q -> %q) = %v, ожидается ErrInvalidFeedback", v.Original, v.Suggestion, err)
+		}
+	}
+}
+
+func TestFeedbackClients(t *testing.T) {
+	sc := newTestCorrector(t, customdict.NewMemory())
+	sc.config.FeedbackRatePerMinute = 4
+
+	// Клиент, подставляющий разных пользователей, голосует один раз
+	for _, user := range []string{"alice", "bob", "carol"} {
+		v := customdict.Vote{Original: "стле", Suggestion: "стол", Accepted: true, Client: "10.0.0.1"}
+		if _, err := sc.RecordFeedback(Scope{Tenant: "acme", User: user}, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fb, _ := sc.Feedback("acme"); len(fb) != 1 || fb[0].Accepted != 1 || fb[0].Adjustment != 0 {
+		t.Errorf("Feedback(acme) = %+v, ожидается один голос клиента", fb)
+	}
+
+	v := customdict.Vote{Original: "стле", Suggestion: "стал", Client: "10.0.0.1"}
+	if _, err := sc.RecordFeedback(Scope{Tenant: "acme"}, v); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sc.RecordFeedback(Scope{Tenant: "acme"}, v); !errors.Is(err, ErrFeedbackRateLimit) {
+		t.Errorf("пятый отзыв клиента за минуту: %v, ожидается ErrFeedbackRateLimit", err)
+	}
+	v.Client = "10.0.0.2"
+	if _, err := sc.RecordFeedback(Scope{Tenant: "acme"}, v); err != nil {
+		t.Errorf("лимит одного клиента задел другого: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package corrector
+
+import (
+	"path/filepath"
+	"testing"
+
+	"corrector/internal/eval"
+	"corrector/internal/eval/evaltest"
+)
+
+// TestGoldCorpus прогоняет размеченный корпус testdata/gold.tsv через CorrectText
+// и проверяет пороги качества. Пороги немного ниже текущих значений: тест ловит
+// заметное ухудшение, а не любое изменение скоринга.
+func TestGoldCorpus(t *testing.T) {
+	samples, err := eval.ReadCorpusFile(filepath.Join("testdata", "gold.tsv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := newTestCorrector(t, nil)
+	rep := eval.Evaluate(func(text string) string {
+		return sc.CorrectText(text, false).Corrected
+	}, samples, eval.Options{MaxExamples: len(samples)})
+	for _, ex := range rep.Examples {
+		t.Logf("%q -> %q, ожидается %q", ex.Original, ex.Corrected, ex.Expected)
+	}
+	evaltest.Check(t, rep, evaltest.Thresholds{
+		MinCorrectionF05:       0.9,
+		MinCorrectionPrecision: 0.9,
+		MinDetectionRecall:     0.8,
+		MaxFalsePositiveRate:   0.01,
+	})
+}
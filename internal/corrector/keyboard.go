@@ -34,9 +34,14 @@ func keyDistance(a, b rune) float64 {
 	return math.Sqrt(dr*dr + dc*dc)
 }
 
+// substitutionCost - стоимость набора a вместо b: по модели ошибок, если она загружена,
+// иначе по ручной таблице и расстоянию между клавишами.
 func (sc *SpellCorrector) substitutionCost(a, b rune) float64 {
 	a = []rune(strings.ToLower(string(a)))[0]
 	b = []rune(strings.ToLower(string(b)))[0]
+	if sc.errModel != nil {
+		return sc.errModel.Substitution(b, a)
+	}
 	special := map[[2]rune]float64{{'ё', 'е'}: 0.2, {'е', 'ё'}: 0.2, {'й', 'и'}: 0.3, {'и', 'й'}: 0.3, {'ь', 'ъ'}: 0.4, {'ъ', 'ь'}: 0.4, {'ц', 'й'}: 0.4, {'й', 'ц'}: 0.4}
 	if v, ok := special[[2]rune{a, b}]; ok {
 		return v
@@ -52,6 +57,30 @@ func (sc *SpellCorrector) substitutionCost(a, b rune) float64 {
 	return 1.8
 }
 
+// insertionCost - стоимость лишнего символа typed после символа кандидата prev.
+func (sc *SpellCorrector) insertionCost(prev, typed rune) float64 {
+	if sc.errModel != nil {
+		return sc.errModel.Insertion(prev, typed)
+	}
+	return sc.config.NeighborInsDel
+}
+
+// deletionCost - стоимость пропуска символа кандидата intended после prev.
+func (sc *SpellCorrector) deletionCost(prev, intended rune) float64 {
+	if sc.errModel != nil {
+		return sc.errModel.Deletion(prev, intended)
+	}
+	return sc.config.NeighborInsDel
+}
+
+// transpositionCost - стоимость перестановки соседних символов ab кандидата.
+func (sc *SpellCorrector) transpositionCost(a, b rune) float64 {
+	if sc.errModel != nil {
+		return sc.errModel.Transposition(a, b)
+	}
+	return sc.config.TransposeCost
+}
+
 // Быстрая проверка «ровно одна перестановка соседних букв»
 func isOneAdjacentSwap(a, b string) bool {
 	ra := []rune(a)
@@ -0,0 +1,232 @@
+package corrector
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"corrector/internal/customdict"
+)
+
+// =====================
+// Кэш пользовательских словарей
+// =====================
+//
+// Словари тенантов и пользователей загружаются из хранилища при первом обращении.
+// Идентификатор тенанта приходит от клиента, поэтому число словарей не ограничено
+// ничем, кроме кэша:
+//   - при переполнении вытесняется словарь, к которому дольше всего не обращались;
+//   - словарь без обращений дольше idle вытесняется при следующей вставке или Resync;
+//   - словарь тенанта по умолчанию не вытесняется.
+//
+// Вытесненный словарь при следующем обращении читается из хранилища заново.
+// Для пользователя без личных слов кэшируется nil, а не пустой словарь: такие записи
+// дешевы, и хранилище не опрашивается при каждом запросе.
+// Неудачная загрузка (хранилище недоступно) запоминается: до следующей попытки
+// обращения получают пустой словарь без запроса к хранилищу, пауза между попытками
+// растет от loadRetryMin до loadRetryMax.
+
+const (
+	loadRetryMin = time.Second
+	loadRetryMax = time.Minute
+)
+
+// cacheItem - словарь в кэше и время последнего обращения к нему (UnixNano).
+type cacheItem struct {
+	lex  *lexicon
+	used atomic.Int64
+}
+
+// loadFailure - неудачная загрузка словаря: когда повторить и с какой паузой.
+type loadFailure struct {
+	retry time.Time
+	delay time.Duration
+}
+
+// lexiconCache - загруженные словари по ключу (тенант или тенант+"\x00"+пользователь).
+// Методы безопасны для конкурентного вызова.
+type lexiconCache struct {
+	mu     sync.RWMutex
+	max    int           // наибольшее число словарей (0 - без ограничения)
+	idle   time.Duration // сколько словарь хранится без обращений (0 - без ограничения)
+	items  map[string]*cacheItem
+	failed map[string]loadFailure
+}
+
+func newLexiconCache(max int, idle time.Duration) *lexiconCache {
+	return &lexiconCache{
+		max:    max,
+		idle:   idle,
+		items:  make(map[string]*cacheItem),
+		failed: make(map[string]loadFailure),
+	}
+}
+
+// get возвращает словарь из кэша и отмечает обращение к нему; ok = false, если словаря нет.
+func (c *lexiconCache) get(key string) (l *lexicon, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	it := c.items[key]
+	if it == nil {
+		return nil, false
+	}
+	it.used.Store(time.Now().UnixNano())
+	return it.lex, true
+}
+
+// loaded возвращает словарь из кэша, не отмечая обращения.
+func (c *lexiconCache) loaded(key string) *lexicon {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if it := c.items[key]; it != nil {
+		return it.lex
+	}
+	return nil
+}
+
+// keys возвращает ключи всех словарей кэша.
+func (c *lexiconCache) keys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make([]string, 0, len(c.items))
+	for k := range c.items {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// size возвращает число словарей в кэше.
+func (c *lexiconCache) size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
+// put добавляет загруженный словарь и возвращает словарь из кэша: если словарь
+// с этим ключом уже загрузили параллельно, остается прежний.
+func (c *lexiconCache) put(key string, l *lexicon) *lexicon {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.failed, key)
+	if it := c.items[key]; it != nil {
+		it.used.Store(time.Now().UnixNano())
+		return it.lex
+	}
+	c.insertLocked(key, l)
+	return l
+}
+
+// set кладет словарь в кэш, заменяя прежний.
+func (c *lexiconCache) set(key string, l *lexicon) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if it := c.items[key]; it != nil {
+		it.lex = l
+		it.used.Store(time.Now().UnixNano())
+		return
+	}
+	c.insertLocked(key, l)
+}
+
+func (c *lexiconCache) insertLocked(key string, l *lexicon) {
+	now := time.Now()
+	c.sweepLocked(now)
+	if c.max > 0 && len(c.items) >= c.max {
+		c.evictOldestLocked()
+	}
+	it := &cacheItem{lex: l}
+	it.used.Store(now.UnixNano())
+	c.items[key] = it
+}
+
+// drop убирает словарь из кэша: следующее обращение прочитает его из хранилища без паузы.
+func (c *lexiconCache) drop(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+	delete(c.failed, key)
+}
+
+// replace подменяет словарь, если он еще в кэше (перечитанный при Resync);
+// вытесненный за время чтения словарь не возвращается.
+func (c *lexiconCache) replace(key string, l *lexicon) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if it := c.items[key]; it != nil {
+		it.lex = l
+	}
+}
+
+// sweep вытесняет словари, к которым не обращались дольше idle.
+func (c *lexiconCache) sweep() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweepLocked(time.Now())
+}
+
+func (c *lexiconCache) sweepLocked(now time.Time) {
+	for k, f := range c.failed {
+		if now.After(f.retry.Add(loadRetryMax)) {
+			delete(c.failed, k)
+		}
+	}
+	if c.idle <= 0 {
+		return
+	}
+	cutoff := now.Add(-c.idle).UnixNano()
+	for k, it := range c.items {
+		if k != DefaultTenant && it.used.Load() < cutoff {
+			delete(c.items, k)
+		}
+	}
+}
+
+func (c *lexiconCache) evictOldestLocked() {
+	oldest, oldestUsed := "", int64(0)
+	found := false
+	for k, it := range c.items {
+		if k == DefaultTenant {
+			continue
+		}
+		if used := it.used.Load(); !found || used < oldestUsed {
+			oldest, oldestUsed, found = k, used, true
+		}
+	}
+	if found {
+		delete(c.items, oldest)
+	}
+}
+
+// backingOff сообщает, что прошлая загрузка словаря не удалась и повторять ее рано.
+func (c *lexiconCache) backingOff(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	f, ok := c.failed[key]
+	return ok && time.Now().Before(f.retry)
+}
+
+// fail запоминает неудачную загрузку словаря и удваивает паузу до следующей попытки.
+func (c *lexiconCache) fail(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delay := loadRetryMin
+	if f, ok := c.failed[key]; ok {
+		delay = 2 * f.delay
+	}
+	if delay > loadRetryMax {
+		delay = loadRetryMax
+	}
+	c.failed[key] = loadFailure{retry: time.Now().Add(delay), delay: delay}
+}
+
+// skipMalformed пропускает записи хранилища, которые не удалось разобрать
+// (customdict.ErrMalformed): поврежденная запись не должна оставлять тенанта без словаря.
+func skipMalformed[T any](key string, items []T, err error) ([]T, error) {
+	if err != nil && errors.Is(err, customdict.ErrMalformed) {
+		log.Printf("предупреждение: в словаре %q пропущены поврежденные записи: %v", key, err)
+		return items, nil
+	}
+	return items, err
+}
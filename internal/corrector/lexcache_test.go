@@ -0,0 +1,169 @@
+package corrector
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"corrector/internal/customdict"
+)
+
+// flakyStore - хранилище, чтение слов из которого можно сломать.
+type flakyStore struct {
+	customdict.Store
+	down      bool // хранилище недоступно
+	malformed bool // одна из записей повреждена
+	reads     int
+}
+
+func (s *flakyStore) All(tenant string) ([]customdict.Entry, error) {
+	s.reads++
+	if s.down {
+		return nil, errors.New("connection refused")
+	}
+	entries, err := s.Store.All(tenant)
+	if s.malformed {
+		err = errors.Join(err, fmt.Errorf("entry %q: %w", "битая", customdict.ErrMalformed))
+	}
+	return entries, err
+}
+
+func TestTenantCacheEviction(t *testing.T) {
+	store := customdict.NewMemory()
+	if err := store.Add("b", customdict.Entry{Word: "зиплайн"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.MorphDictPath = buildMorphDict(t)
+	cfg.MaxTenantLexicons = 3
+	sc, err := NewSpellCorrector(cfg, "testdata/freq.txt", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc.lexiconFor("a")
+	time.Sleep(time.Millisecond)
+	sc.lexiconFor("b")
+	time.Sleep(time.Millisecond)
+	sc.lexiconFor("a")
+	sc.lexiconFor("c")
+	if sc.tenants.size() != 3 || sc.tenants.loaded("b") != nil || sc.tenants.loaded(DefaultTenant) == nil {
+		t.Errorf("в кэше %v, ожидается вытеснение давно не использованного b", sc.tenants.keys())
+	}
+	// Вытесненный словарь читается из хранилища заново
+	if !sc.lexiconFor("b").has("зиплайн") {
+		t.Error("слово вытесненного тенанта потеряно")
+	}
+
+	sc.tenants.idle = time.Millisecond
+	time.Sleep(2 * time.Millisecond)
+	sc.Resync()
+	if keys := sc.tenants.keys(); len(keys) != 1 || keys[0] != DefaultTenant {
+		t.Errorf("после Resync в кэше %q, ожидается только тенант по умолчанию", keys)
+	}
+}
+
+func TestTenantLoadFailure(t *testing.T) {
+	store := &flakyStore{Store: customdict.NewMemory()}
+	if err := store.Add("acme", customdict.Entry{Word: "зиплайн"}); err != nil {
+		t.Fatal(err)
+	}
+	sc := newTestCorrector(t, store)
+
+	store.down, store.reads = true, 0
+	for range 3 {
+		if sc.lexiconFor("acme").has("зиплайн") {
+			t.Fatal("словарь загружен из недоступного хранилища")
+		}
+	}
+	if store.reads != 1 {
+		t.Errorf("чтений хранилища во время паузы: %d, ожидается 1", store.reads)
+	}
+	if sc.tenants.loaded("acme") != nil {
+		t.Error("пустой словарь недоступного хранилища закэширован")
+	}
+
+	// Пауза истекла, хранилище доступно, но одна запись повреждена
+	store.down, store.malformed = false, true
+	sc.tenants.failed["acme"] = loadFailure{retry: time.Now().Add(-time.Second), delay: loadRetryMin}
+	if !sc.lexiconFor("acme").has("зиплайн") {
+		t.Error("поврежденная запись помешала загрузить словарь")
+	}
+	if sc.tenants.loaded("acme") == nil {
+		t.Error("словарь с пропущенной поврежденной записью не закэширован")
+	}
+}
+
+func TestTenantCacheWithoutStore(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.UseMorphology = false
+	cfg.MaxTenantLexicons = 2
+	sc, err := NewSpellCorrector(cfg, "testdata/freq.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sc.AddCustomWord("acme", customdict.Entry{Word: "зиплайн"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tenant := range []string{"a", "b", "c"} {
+		sc.lexiconFor(tenant)
+	}
+	if !sc.lexiconFor("acme").has("зиплайн") {
+		t.Error("без хранилища словарь тенанта вытеснен вместе со словами")
+	}
+}
+
+func TestUserLexicons(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MorphDictPath = buildMorphDict(t)
+	cfg.MaxUserLexicons = 2
+	sc, err := NewSpellCorrector(cfg, "testdata/freq.txt", customdict.NewMemory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := Scope{Tenant: "acme", User: "alice"}
+
+	// Пользователь без слов: в кэше nil, а не пустой словарь
+	sc.CorrectTextFor(alice, "зиплаин", false)
+	if l, ok := sc.users.get(userKey(alice)); !ok || l != nil {
+		t.Errorf("кэш для пользователя без слов: %v, %v", l, ok)
+	}
+
+	for _, word := range []string{"", "  ", "два слова"} {
+		if err := sc.AddUserWord(alice, word); !errors.Is(err, ErrInvalidWord) {
+			t.Errorf("AddUserWord(%q) = %v, ожидается ErrInvalidWord", word, err)
+		}
+	}
+	if err := sc.AddUserWord(alice, "Зиплайн"); err != nil {
+		t.Fatal(err)
+	}
+	if words, _ := sc.UserWords(alice); len(words) != 1 || words[0] != "зиплайн" {
+		t.Errorf("UserWords(alice) = %v", words)
+	}
+	if s := suggestions(sc.CorrectTextFor(alice, "зиплаин", false)); !slices.Contains(s, "зиплайн") {
+		t.Errorf("подсказки для alice: %v", s)
+	}
+
+	// Кэш ограничен MaxUserLexicons; вытесненный словарь читается из хранилища
+	for _, user := range []string{"bob", "carol"} {
+		if err := sc.AddUserWord(Scope{Tenant: "acme", User: user}, "слово"); err != nil {
+			t.Fatal(err)
+		}
+		sc.UserWords(Scope{Tenant: "acme", User: user})
+	}
+	if n := sc.users.size(); n > 2 {
+		t.Errorf("в кэше %d личных словарей, ожидается не больше 2", n)
+	}
+	if words, _ := sc.UserWords(alice); len(words) != 1 {
+		t.Errorf("UserWords(alice) после вытеснения = %v", words)
+	}
+
+	if existed, err := sc.RemoveUserWord(alice, "зиплайн"); err != nil || !existed {
+		t.Fatalf("RemoveUserWord = %v, %v", existed, err)
+	}
+	if l, ok := sc.users.get(userKey(alice)); !ok || l != nil {
+		t.Errorf("после удаления последнего слова в кэше %v, %v", l, ok)
+	}
+}
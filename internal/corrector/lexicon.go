@@ -0,0 +1,653 @@
+package corrector
+
+import (
+	"errors"
+	"log"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	symspell "corrector/pkg"
+	"corrector/pkg/options"
+	"corrector/pkg/verbosity"
+
+	"corrector/internal/analyzer"
+	"corrector/internal/customdict"
+)
+
+// =====================
+// Пользовательские словари тенантов
+// =====================
+//
+// Базовый словарь (частоты и индекс SymSpell) общий для всех и после загрузки не меняется.
+// Слова каждого тенанта лежат в отдельном небольшом лексиконе со своим индексом SymSpell:
+// при коррекции кандидаты берутся из базового индекса и из индекса тенанта,
+// так что слово одного тенанта не влияет на исправления у других.
+// Поверх словаря тенанта может лежать личный словарь пользователя (base → tenant → user),
+// устроенный так же, но без лексем.
+
+// DefaultTenant - тенант по умолчанию (общий словарь, как до появления тенантов).
+const DefaultTenant = customdict.DefaultTenant
+
+var (
+	// ErrInvalidTenant возвращается для идентификатора тенанта недопустимого вида.
+	ErrInvalidTenant = errors.New("некорректный идентификатор тенанта")
+	// ErrInvalidUser возвращается для пустого или недопустимого идентификатора пользователя.
+	ErrInvalidUser = errors.New("некорректный идентификатор пользователя")
+	// ErrUserLimit возвращается, если личный словарь пользователя заполнен (см. MaxUserWords).
+	ErrUserLimit = errors.New("личный словарь пользователя заполнен")
+)
+
+var idRe = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// ValidTenant сообщает, можно ли использовать tenant как идентификатор словаря
+// (он входит в ключи хранилища, поэтому набор символов ограничен).
+func ValidTenant(tenant string) bool {
+	return tenant == DefaultTenant || idRe.MatchString(tenant)
+}
+
+// ValidUser сообщает, можно ли использовать user как идентификатор личного словаря.
+func ValidUser(user string) bool {
+	return idRe.MatchString(user)
+}
+
+// Scope выбирает пользовательские словари, с которыми идет коррекция:
+// словарь тенанта и, если User не пуст, личный словарь пользователя этого тенанта.
+type Scope struct {
+	Tenant string
+	User   string
+}
+
+// Valid сообщает, допустимы ли идентификаторы тенанта и пользователя.
+func (s Scope) Valid() bool {
+	return ValidTenant(s.Tenant) && (s.User == "" || ValidUser(s.User))
+}
+
+// newIndex создает индекс SymSpell с настройками корректора.
+func newIndex(maxEditDistance int) symspell.SymSpell {
+	return symspell.NewSymSpell(
+		options.WithMaxDictionaryEditDistance(maxEditDistance),
+		options.WithPrefixLength(7),
+		options.WithCountThreshold(1),
+		options.WithFrequencyThreshold(10),
+		options.WithFrequencyMultiplier(20),
+	)
+}
+
+// lexicon - пользовательский словарь одного тенанта. Методы безопасны для конкурентного вызова.
+type lexicon struct {
+	mu      sync.RWMutex
+	words   map[string]bool              // все слова пользователя: отдельные слова и формы лексем
+	plain   map[string]customdict.Entry  // слова, добавленные без парадигмы, с метаданными
+	lexemes map[string]customdict.Lexeme // лексемы по лемме: формы в нижнем регистре с тегами
+	// blocked - стоп-лист тенанта: слова, которые нельзя предлагать (см. blocklist.go).
+	blocked map[string]bool
+	// rules - правила принудительной замены: ключ фразы (см. ruleKey) -> замена;
+	// ruleLen - наибольшее число слов и знаков в левой части правила.
+	rules   map[string]customdict.Rule
+	ruleLen int
+	// votes - отзывы о подсказках: пара (см. pairKey) -> голосующий (Vote.Voter) -> подсказка принята.
+	votes map[string]map[string]bool
+	// morph - формы лексем тенанта с тегами для согласования; у общего словаря
+	// они лежат в морфоанализаторе (см. addLexemeForms), и этот лексикон пуст.
+	morph *analyzer.Lexicon
+	// index - кандидаты из слов тенанта (nil, если SymSpell отключен).
+	// SymSpell не умеет удалять слова, поэтому после удаления индекс перестраивается.
+	index   symspell.SymSpell
+	maxDist int
+	// defaultFreq - частота слов без собственной частоты (в том числе форм лексем).
+	defaultFreq float64
+}
+
+func newLexicon(useSymSpell bool, maxDist int, defaultFreq float64) *lexicon {
+	l := &lexicon{
+		words:       make(map[string]bool),
+		plain:       make(map[string]customdict.Entry),
+		lexemes:     make(map[string]customdict.Lexeme),
+		blocked:     make(map[string]bool),
+		rules:       make(map[string]customdict.Rule),
+		votes:       make(map[string]map[string]bool),
+		morph:       analyzer.NewLexicon(),
+		maxDist:     maxDist,
+		defaultFreq: defaultFreq,
+	}
+	if useSymSpell {
+		l.index = newIndex(maxDist)
+	}
+	return l
+}
+
+// has сообщает, есть ли слово в словаре тенанта.
+func (l *lexicon) has(lw string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.words[lw]
+}
+
+// frequency возвращает частоту слова словаря; explicit - частота указана для слова явно.
+func (l *lexicon) frequency(lw string) (freq float64, explicit, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if !l.words[lw] {
+		return 0, false, false
+	}
+	if f := l.plain[lw].Frequency; f > 0 {
+		return float64(f), true, true
+	}
+	return l.defaultFreq, false, true
+}
+
+// entry возвращает запись отдельного слова.
+func (l *lexicon) entry(lw string) (customdict.Entry, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	e, ok := l.plain[lw]
+	return e, ok
+}
+
+// lookup возвращает слова тенанта на расстоянии не больше maxDist от token.
+func (l *lexicon) lookup(token string, maxDist int) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.index == nil || len(l.words) == 0 {
+		return nil
+	}
+	suggs, err := l.index.Lookup(token, verbosity.All, maxDist)
+	if err != nil {
+		return nil
+	}
+	out := make([]string, 0, len(suggs))
+	for _, s := range suggs {
+		out = append(out, s.Term)
+	}
+	return out
+}
+
+// addWord добавляет отдельное слово (e.Word в нижнем регистре) или обновляет его запись.
+func (l *lexicon) addWord(e customdict.Entry) {
+	l.addWords([]customdict.Entry{e})
+}
+
+// addWords добавляет отдельные слова разом: читатели видят либо все слова, либо ни одного.
+// Возвращает число слов, которых в словаре еще не было.
+func (l *lexicon) addWords(entries []customdict.Entry) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	added, reindex := 0, false
+	for _, e := range entries {
+		if !l.words[e.Word] {
+			added++
+		} else if l.plain[e.Word].Frequency != e.Frequency {
+			// Частота слова в индексе изменилась
+			reindex = true
+		}
+		l.plain[e.Word] = e
+		l.registerLocked(e.Word, 0)
+	}
+	if reindex {
+		l.rebuildLocked()
+	}
+	return added
+}
+
+// setLexeme регистрирует формы лексемы, заменяя прежние формы той же леммы.
+// Формы lx должны быть в нижнем регистре.
+// Возвращает формы, которые перестали быть словами тенанта.
+func (l *lexicon) setLexeme(lx customdict.Lexeme) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	removed := l.removeLexemeLocked(lx.Lemma)
+	l.lexemes[lx.Lemma] = lx
+	reindex := len(removed) > 0
+	for _, f := range lx.Forms {
+		// Частота лексемы меняет частоту в индексе и у форм, которые там уже есть
+		reindex = reindex || l.words[f.Word] && lx.Frequency > 0
+		l.registerLocked(f.Word, lx.Frequency)
+	}
+	if reindex {
+		l.rebuildLocked()
+	}
+	return removed
+}
+
+// remove убирает слово: лексему с такой леммой и отдельное слово.
+// Возвращает слова, которые перестали быть словами тенанта.
+func (l *lexicon) remove(lw string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	removed := append(l.removeLexemeLocked(lw), l.removePlainLocked(lw)...)
+	if len(removed) > 0 {
+		l.rebuildLocked()
+	}
+	return removed
+}
+
+// removeWord убирает только отдельное слово (формы лексем остаются).
+func (l *lexicon) removeWord(lw string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	removed := l.removePlainLocked(lw)
+	if len(removed) > 0 {
+		l.rebuildLocked()
+	}
+	return removed
+}
+
+// removeLexeme убирает только лексему с такой леммой.
+func (l *lexicon) removeLexeme(lemma string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	removed := l.removeLexemeLocked(lemma)
+	if len(removed) > 0 {
+		l.rebuildLocked()
+	}
+	return removed
+}
+
+// list возвращает все слова словаря в алфавитном порядке.
+func (l *lexicon) list() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	words := make([]string, 0, len(l.words))
+	for w := range l.words {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// entries возвращает записи отдельных слов в алфавитном порядке (без форм лексем).
+func (l *lexicon) entries() []customdict.Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make([]customdict.Entry, 0, len(l.plain))
+	for _, e := range l.plain {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Word < out[j].Word })
+	return out
+}
+
+// lexemeList возвращает лексемы с формами и тегами в алфавитном порядке лемм.
+func (l *lexicon) lexemeList() []customdict.Lexeme {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make([]customdict.Lexeme, 0, len(l.lexemes))
+	for _, lx := range l.lexemes {
+		lx.Forms = slices.Clone(lx.Forms)
+		out = append(out, lx)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Lemma < out[j].Lemma })
+	return out
+}
+
+// size возвращает число слов словаря.
+func (l *lexicon) size() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.words)
+}
+
+// lexeme возвращает лексему тенанта с такой леммой.
+func (l *lexicon) lexeme(lemma string) (customdict.Lexeme, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	lx, ok := l.lexemes[lemma]
+	return lx, ok
+}
+
+// hasLexeme сообщает, есть ли у тенанта лексема с такой леммой.
+func (l *lexicon) hasLexeme(lemma string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.lexemes[lemma]
+	return ok
+}
+
+// registerLocked добавляет слово в словарь и индекс; lexemeFreq - частота лексемы,
+// формой которой слово добавляется (0 для отдельных слов).
+func (l *lexicon) registerLocked(lw string, lexemeFreq int64) {
+	if l.words[lw] {
+		return
+	}
+	l.words[lw] = true
+	if l.index != nil {
+		l.index.CreateDictionaryEntry(lw, l.indexCountLocked(lw, lexemeFreq))
+	}
+}
+
+// indexCountLocked - частота слова для индекса SymSpell: частота отдельного слова,
+// иначе частота лексемы, иначе частота по умолчанию.
+func (l *lexicon) indexCountLocked(lw string, lexemeFreq int64) int {
+	f := l.defaultFreq
+	if e, ok := l.plain[lw]; ok && e.Frequency > 0 {
+		f = float64(e.Frequency)
+	} else if lexemeFreq > 0 {
+		f = float64(lexemeFreq)
+	}
+	return int(math.Max(1, math.Min(f, math.MaxInt32)))
+}
+
+// unregisterLocked убирает слово, если на него больше не ссылаются
+// ни отдельные слова, ни лексемы; возвращает true, если слово убрано.
+func (l *lexicon) unregisterLocked(lw string) bool {
+	if _, ok := l.plain[lw]; ok {
+		return false
+	}
+	for _, lx := range l.lexemes {
+		for _, f := range lx.Forms {
+			if f.Word == lw {
+				return false
+			}
+		}
+	}
+	delete(l.words, lw)
+	return true
+}
+
+func (l *lexicon) removePlainLocked(lw string) []string {
+	if _, ok := l.plain[lw]; !ok {
+		return nil
+	}
+	delete(l.plain, lw)
+	if l.unregisterLocked(lw) {
+		return []string{lw}
+	}
+	return nil
+}
+
+func (l *lexicon) removeLexemeLocked(lemma string) []string {
+	lx, ok := l.lexemes[lemma]
+	if !ok {
+		return nil
+	}
+	delete(l.lexemes, lemma)
+	var removed []string
+	for _, f := range lx.Forms {
+		if l.words[f.Word] && l.unregisterLocked(f.Word) {
+			removed = append(removed, f.Word)
+		}
+	}
+	return removed
+}
+
+// rebuildLocked перестраивает индекс SymSpell по текущим словам.
+func (l *lexicon) rebuildLocked() {
+	if l.index == nil {
+		return
+	}
+	l.index = newIndex(l.maxDist)
+	// Форма нескольких лексем получает наибольшую из их частот
+	lexemeFreq := make(map[string]int64)
+	for _, lx := range l.lexemes {
+		for _, f := range lx.Forms {
+			if lx.Frequency > lexemeFreq[f.Word] {
+				lexemeFreq[f.Word] = lx.Frequency
+			}
+		}
+	}
+	for w := range l.words {
+		l.index.CreateDictionaryEntry(w, l.indexCountLocked(w, lexemeFreq[w]))
+	}
+}
+
+// isBlocked сообщает, есть ли слово в стоп-листе.
+func (l *lexicon) isBlocked(lw string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.blocked[lw]
+}
+
+// block добавляет слово в стоп-лист.
+func (l *lexicon) block(lw string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.blocked[lw] = true
+}
+
+// unblock убирает слово из стоп-листа.
+func (l *lexicon) unblock(lw string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.blocked, lw)
+}
+
+// blockedList возвращает стоп-лист в алфавитном порядке.
+func (l *lexicon) blockedList() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	words := make([]string, 0, len(l.blocked))
+	for w := range l.blocked {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// setRule добавляет правило замены (r.From - ключ фразы из n токенов) или заменяет прежнее.
+func (l *lexicon) setRule(r customdict.Rule, n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rules[r.From] = r
+	l.ruleLen = max(l.ruleLen, n)
+}
+
+// removeRule убирает правило замены.
+func (l *lexicon) removeRule(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.rules[key]; !ok {
+		return
+	}
+	delete(l.rules, key)
+	l.ruleLen = 0
+	for k := range l.rules {
+		_, n, _ := ruleKey(k)
+		l.ruleLen = max(l.ruleLen, n)
+	}
+}
+
+// rule возвращает правило замены для ключа фразы.
+func (l *lexicon) rule(key string) (customdict.Rule, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	r, ok := l.rules[key]
+	return r, ok
+}
+
+// maxRuleLen возвращает длину самого длинного правила в токенах без пробелов.
+func (l *lexicon) maxRuleLen() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.ruleLen
+}
+
+// ruleList возвращает правила замены, отсортированные по левой части.
+func (l *lexicon) ruleList() []customdict.Rule {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	rules := make([]customdict.Rule, 0, len(l.rules))
+	for _, r := range l.rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].From < rules[j].From })
+	return rules
+}
+
+// lexicons - словари, просматриваемые поверх базового, от общего к личному.
+type lexicons []*lexicon
+
+// blocked сообщает, запрещено ли слово хотя бы в одном из словарей.
+func (ls lexicons) blocked(lw string) bool {
+	for _, l := range ls {
+		if l.isBlocked(lw) {
+			return true
+		}
+	}
+	return false
+}
+
+// has сообщает, есть ли слово хотя бы в одном из словарей.
+func (ls lexicons) has(lw string) bool {
+	for _, l := range ls {
+		if l.has(lw) {
+			return true
+		}
+	}
+	return false
+}
+
+// frequency возвращает частоту слова из самого личного словаря, где оно есть.
+func (ls lexicons) frequency(lw string) (freq float64, explicit, ok bool) {
+	for i := len(ls) - 1; i >= 0; i-- {
+		if freq, explicit, ok = ls[i].frequency(lw); ok {
+			return freq, explicit, true
+		}
+	}
+	return 0, false, false
+}
+
+// parse возвращает разборы слова из лексем с тегами самого личного словаря, где они есть.
+func (ls lexicons) parse(lw string) []*analyzer.Parsed {
+	for i := len(ls) - 1; i >= 0; i-- {
+		if parses := ls[i].morph.Parse(lw); len(parses) > 0 {
+			return parses
+		}
+	}
+	return nil
+}
+
+// lookup собирает кандидатов из индексов всех словарей.
+func (ls lexicons) lookup(token string, maxDist int) []string {
+	var out []string
+	for _, l := range ls {
+		out = append(out, l.lookup(token, maxDist)...)
+	}
+	return out
+}
+
+// lexiconsFor возвращает словари области коррекции.
+func (sc *SpellCorrector) lexiconsFor(scope Scope) lexicons {
+	ls := lexicons{sc.lexiconFor(scope.Tenant)}
+	if scope.User != "" {
+		if ul := sc.userLexiconFor(scope); ul != nil {
+			ls = append(ls, ul)
+		}
+	}
+	return ls
+}
+
+// lexiconFor возвращает словарь тенанта, при первом обращении загружая его из хранилища.
+func (sc *SpellCorrector) lexiconFor(tenant string) *lexicon {
+	l := sc.cachedLexicon(sc.tenants, tenant, func() (*lexicon, error) { return sc.loadLexicon(tenant) })
+	if l == nil {
+		// Хранилище недоступно: пустой словарь до следующей попытки загрузки
+		return sc.newLexicon()
+	}
+	return l
+}
+
+// userLexiconFor возвращает личный словарь пользователя, при первом обращении загружая его
+// из хранилища, или nil, если у пользователя нет слов (или хранилище недоступно).
+func (sc *SpellCorrector) userLexiconFor(scope Scope) *lexicon {
+	return sc.cachedLexicon(sc.users, userKey(scope), func() (*lexicon, error) {
+		return sc.loadUserLexicon(scope)
+	})
+}
+
+// userKey - ключ личного словаря в кэше SpellCorrector.users.
+func userKey(scope Scope) string {
+	return scope.Tenant + "\x00" + scope.User
+}
+
+// cachedLexicon возвращает словарь из кэша или загружает его.
+// Если хранилище недоступно, возвращается nil, который не кэшируется;
+// загрузка повторяется после паузы (см. lexiconCache.fail).
+func (sc *SpellCorrector) cachedLexicon(cache *lexiconCache, key string, load func() (*lexicon, error)) *lexicon {
+	if l, ok := cache.get(key); ok {
+		return l
+	}
+	if cache.backingOff(key) {
+		return nil
+	}
+	l, err := load()
+	if err != nil {
+		log.Printf("предупреждение: не удалось загрузить пользовательский словарь %q: %v", key, err)
+		cache.fail(key)
+		return nil
+	}
+	return cache.put(key, l)
+}
+
+// newLexicon создает пустой словарь с настройками корректора.
+func (sc *SpellCorrector) newLexicon() *lexicon {
+	return newLexicon(sc.config.UseSymSpell, sc.config.MaxEditDistance, sc.customFrequency())
+}
+
+// loadLexicon читает слова и лексемы тенанта из хранилища.
+// Поврежденные записи пропускаются (см. skipMalformed).
+func (sc *SpellCorrector) loadLexicon(tenant string) (*lexicon, error) {
+	l := sc.newLexicon()
+	if sc.dict == nil {
+		return l, nil
+	}
+	entries, err := sc.dict.All(tenant)
+	if entries, err = skipMalformed(tenant, entries, err); err != nil {
+		return nil, err
+	}
+	lexemes, err := sc.dict.Lexemes(tenant)
+	if lexemes, err = skipMalformed(tenant, lexemes, err); err != nil {
+		return nil, err
+	}
+	blocked, err := sc.dict.Blocked(tenant)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := sc.dict.Rules(tenant)
+	if rules, err = skipMalformed(tenant, rules, err); err != nil {
+		return nil, err
+	}
+	votes, err := sc.dict.Votes(tenant)
+	if votes, err = skipMalformed(tenant, votes, err); err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Word = strings.ToLower(entries[i].Word)
+	}
+	l.addWords(entries)
+	for _, lx := range lexemes {
+		sc.addLexemeForms(tenant, l, lx)
+	}
+	for _, w := range blocked {
+		l.block(strings.ToLower(w))
+	}
+	for _, r := range rules {
+		if r, n, err := normalizeRule(r); err == nil {
+			l.setRule(r, n)
+		}
+	}
+	for _, v := range votes {
+		l.addVote(v)
+	}
+	return l, nil
+}
+
+// loadUserLexicon читает личный словарь пользователя из хранилища;
+// для пользователя без слов словарь не создается (nil).
+func (sc *SpellCorrector) loadUserLexicon(scope Scope) (*lexicon, error) {
+	if sc.dict == nil {
+		return nil, nil
+	}
+	words, err := sc.dict.UserWords(scope.Tenant, scope.User)
+	if err != nil || len(words) == 0 {
+		return nil, err
+	}
+	l := sc.newLexicon()
+	for _, w := range words {
+		l.addWord(customdict.Entry{Word: strings.ToLower(w)})
+	}
+	return l, nil
+}
@@ -0,0 +1,35 @@
+package corrector
+
+import (
+	"slices"
+	"testing"
+
+	"corrector/internal/customdict"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	sc := newTestCorrector(t, customdict.NewMemory())
+	if _, err := sc.AddCustomWord("acme", customdict.Entry{Word: "зиплайн"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.AddUserWord(Scope{Tenant: "globex", User: "bob"}, "зиплайн"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		scope Scope
+		known bool
+	}{
+		{Scope{Tenant: "acme"}, true},
+		{Scope{Tenant: "acme", User: "alice"}, true},
+		{Scope{Tenant: "globex", User: "bob"}, true},
+		{Scope{Tenant: "globex"}, false},
+		{Scope{Tenant: "globex", User: "alice"}, false},
+		{Scope{}, false},
+	}
+	for _, tt := range tests {
+		r := sc.CorrectTextFor(tt.scope, "зиплаин", false)
+		if got := slices.Contains(suggestions(r), "зиплайн"); got != tt.known {
+			t.Errorf("%+v: подсказка зиплайн = %v, ожидается %v", tt.scope, got, tt.known)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package corrector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// =====================
+// Профили параметров скоринга
+// =====================
+//
+// Веса скоринга подбираются на размеченном корпусе (cmd/tune) и сохраняются в профиль -
+// JSON-файл с полями Scoring. Сервер применяет профиль поверх настроек по умолчанию;
+// поля, которых нет в профиле, не меняются.
+
+// Scoring - параметры скоринга кандидатов и порогов автозамены.
+type Scoring struct {
+	BetaWeight      float64 `json:"beta_weight"`
+	LambdaPenalty   float64 `json:"lambda_penalty"`
+	GammaMorph      float64 `json:"gamma_morph"`
+	FreqTemperature float64 `json:"freq_temperature"`
+	MarginThreshold float64 `json:"margin_threshold"`
+	TauInVocab      float64 `json:"tau_in_vocab"`
+	TauOutVocab     float64 `json:"tau_out_vocab"`
+	// ConfidenceTemperature не влияет на исправления и подбирается отдельно (cmd/calibrate).
+	ConfidenceTemperature float64 `json:"confidence_temperature"`
+}
+
+// Scoring возвращает параметры скоринга из настроек.
+func (c CorrectorConfig) Scoring() Scoring {
+	return Scoring{
+		BetaWeight:            c.BetaWeight,
+		LambdaPenalty:         c.LambdaPenalty,
+		GammaMorph:            c.GammaMorph,
+		FreqTemperature:       c.FreqTemperature,
+		MarginThreshold:       c.MarginThreshold,
+		TauInVocab:            c.TauInVocab,
+		TauOutVocab:           c.TauOutVocab,
+		ConfidenceTemperature: c.ConfidenceTemperature,
+	}
+}
+
+// ApplyScoring заменяет параметры скоринга в настройках.
+func (c *CorrectorConfig) ApplyScoring(s Scoring) {
+	c.BetaWeight = s.BetaWeight
+	c.LambdaPenalty = s.LambdaPenalty
+	c.GammaMorph = s.GammaMorph
+	c.FreqTemperature = s.FreqTemperature
+	c.MarginThreshold = s.MarginThreshold
+	c.TauInVocab = s.TauInVocab
+	c.TauOutVocab = s.TauOutVocab
+	c.ConfidenceTemperature = s.ConfidenceTemperature
+}
+
+// LoadProfile читает профиль и применяет его поверх параметров скоринга cfg.
+func LoadProfile(path string, cfg *CorrectorConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	s := cfg.Scoring()
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("профиль %s: %w", path, err)
+	}
+	if s.FreqTemperature <= 0 {
+		return fmt.Errorf("профиль %s: freq_temperature должна быть положительной", path)
+	}
+	if s.ConfidenceTemperature <= 0 {
+		return fmt.Errorf("профиль %s: confidence_temperature должна быть положительной", path)
+	}
+	cfg.ApplyScoring(s)
+	return nil
+}
+
+// SaveProfile записывает параметры скоринга в профиль.
+func SaveProfile(path string, s Scoring) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// SetScoring заменяет параметры скоринга уже созданного корректора, чтобы при подборе
+// параметров не загружать словари заново. Вызывать нельзя одновременно с коррекцией.
+func (sc *SpellCorrector) SetScoring(s Scoring) {
+	if s.FreqTemperature != sc.config.FreqTemperature {
+		// Кэш логарифмов частот зависит от температуры
+		sc.logpCache.Clear()
+	}
+	sc.config.ApplyScoring(s)
+}
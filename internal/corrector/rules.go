@@ -0,0 +1,185 @@
+package corrector
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"corrector/internal/customdict"
+)
+
+// =====================
+// Правила принудительной замены
+// =====================
+//
+// Правило тенанта заменяет фразу (одно или несколько слов: "щас" -> "сейчас",
+// "ихний" -> "их", "в кратце" -> "вкратце") всегда, без скоринга кандидатов.
+// Правила применяются в CorrectTextFor до подбора исправлений; замененные слова
+// получают решение "forced_replace" и дальше не исправляются. Регистр фразы в тексте
+// переносится на замену: "Щас" -> "Сейчас", "ЩАС" -> "СЕЙЧАС".
+
+// ErrInvalidRule возвращается для правила с пустой или недопустимой левой частью или пустой заменой.
+var ErrInvalidRule = errors.New("некорректное правило замены")
+
+// maxRuleTokens ограничивает длину левой части правила (слова и знаки без пробелов).
+const maxRuleTokens = 8
+
+// ruleKey приводит фразу к ключу правила: нижний регистр, пробелы между токенами
+// схлопнуты в один. n - число токенов фразы без пробелов. Фраза должна начинаться
+// и заканчиваться словом.
+func ruleKey(phrase string) (key string, n int, ok bool) {
+	tokens := tokenize(strings.TrimSpace(phrase))
+	if len(tokens) == 0 || !isWord(tokens[0]) || !isWord(tokens[len(tokens)-1]) {
+		return "", 0, false
+	}
+	var b strings.Builder
+	for _, t := range tokens {
+		if strings.TrimSpace(t) == "" {
+			b.WriteByte(' ')
+			continue
+		}
+		b.WriteString(strings.ToLower(t))
+		n++
+	}
+	return b.String(), n, n <= maxRuleTokens
+}
+
+// normalizeRule проверяет правило и приводит его левую часть к ключу.
+func normalizeRule(r customdict.Rule) (customdict.Rule, int, error) {
+	key, n, ok := ruleKey(r.From)
+	to := strings.TrimSpace(r.To)
+	if !ok || to == "" {
+		return customdict.Rule{}, 0, fmt.Errorf("%w: %q -> %q", ErrInvalidRule, r.From, r.To)
+	}
+	return customdict.Rule{From: key, To: to}, n, nil
+}
+
+// forcedSpan - фраза tokens[start:end], замененная по правилу на to (уже в регистре фразы).
+type forcedSpan struct {
+	start, end int
+	to         string
+}
+
+// rule возвращает правило для ключа фразы из самого личного словаря, где оно есть.
+func (ls lexicons) rule(key string) (customdict.Rule, bool) {
+	for i := len(ls) - 1; i >= 0; i-- {
+		if r, ok := ls[i].rule(key); ok {
+			return r, true
+		}
+	}
+	return customdict.Rule{}, false
+}
+
+// matchRules находит в тексте фразы, подпадающие под правила замены. Фразы не пересекаются;
+// из правил, начинающихся с одного слова, выбирается самое длинное.
+func (ls lexicons) matchRules(tokens []string) []forcedSpan {
+	maxLen := 0
+	for _, l := range ls {
+		maxLen = max(maxLen, l.maxRuleLen())
+	}
+	if maxLen == 0 {
+		return nil
+	}
+	var spans []forcedSpan
+	for i := 0; i < len(tokens); i++ {
+		if !isWord(tokens[i]) {
+			continue
+		}
+		var b strings.Builder
+		best := forcedSpan{start: -1}
+		for j, n := i, 0; j < len(tokens) && n < maxLen; j++ {
+			if strings.TrimSpace(tokens[j]) == "" {
+				b.WriteByte(' ')
+				continue
+			}
+			b.WriteString(strings.ToLower(tokens[j]))
+			n++
+			if !isWord(tokens[j]) {
+				continue
+			}
+			if r, ok := ls.rule(b.String()); ok {
+				best = forcedSpan{start: i, end: j + 1, to: r.To}
+			}
+		}
+		if best.start < 0 {
+			continue
+		}
+		best.to = matchCase(tokens[best.start:best.end], best.to)
+		spans = append(spans, best)
+		i = best.end - 1
+	}
+	return spans
+}
+
+// matchCase переносит регистр исходной фразы на замену: фраза целиком заглавными
+// (длиннее одной буквы) - замена заглавными, фраза с заглавной буквы - замена с заглавной.
+// Иначе замена остается как записана в правиле.
+func matchCase(src []string, to string) string {
+	letters, upper := 0, true
+	for _, t := range src {
+		if isWord(t) {
+			letters += utf8.RuneCountInString(t)
+			upper = upper && isUpper(t)
+		}
+	}
+	switch {
+	case upper && letters > 1:
+		return strings.ToUpper(to)
+	case isTitle(src[0]) || upper:
+		r, size := utf8.DecodeRuneInString(to)
+		return string(unicode.ToUpper(r)) + to[size:]
+	default:
+		return to
+	}
+}
+
+// AddRule adds a forced replacement rule to the tenant, replacing the rule with the same
+// left-hand side. From is matched case-insensitively and may span several words.
+// It returns the stored rule.
+func (sc *SpellCorrector) AddRule(tenant string, r customdict.Rule) (customdict.Rule, error) {
+	if !ValidTenant(tenant) {
+		return customdict.Rule{}, ErrInvalidTenant
+	}
+	r, n, err := normalizeRule(r)
+	if err != nil {
+		return customdict.Rule{}, err
+	}
+	lex := sc.lexiconFor(tenant)
+	if sc.dict != nil {
+		if err := sc.dict.AddRule(tenant, r); err != nil {
+			return customdict.Rule{}, err
+		}
+	}
+	lex.setRule(r, n)
+	return r, nil
+}
+
+// RemoveRule removes the tenant's rule for from and reports whether it existed.
+func (sc *SpellCorrector) RemoveRule(tenant, from string) (bool, error) {
+	if !ValidTenant(tenant) {
+		return false, ErrInvalidTenant
+	}
+	key, _, ok := ruleKey(from)
+	if !ok {
+		return false, nil
+	}
+	lex := sc.lexiconFor(tenant)
+	_, existed := lex.rule(key)
+	if sc.dict != nil {
+		if _, err := sc.dict.RemoveRule(tenant, key); err != nil {
+			return false, err
+		}
+	}
+	lex.removeRule(key)
+	return existed, nil
+}
+
+// Rules returns the tenant's replacement rules, sorted by their left-hand side.
+func (sc *SpellCorrector) Rules(tenant string) ([]customdict.Rule, error) {
+	if !ValidTenant(tenant) {
+		return nil, ErrInvalidTenant
+	}
+	return sc.lexiconFor(tenant).ruleList(), nil
+}
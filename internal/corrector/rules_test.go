@@ -0,0 +1,49 @@
+package corrector
+
+import (
+	"errors"
+	"testing"
+
+	"corrector/internal/customdict"
+)
+
+func TestRules(t *testing.T) {
+	sc := newTestCorrector(t, customdict.NewMemory())
+	if _, err := sc.AddRule("acme", customdict.Rule{From: "щас", To: "сейчас"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sc.AddRule("acme", customdict.Rule{From: "в  кратце", To: "вкратце"}); err != nil {
+		t.Fatal(err)
+	}
+	acme := Scope{Tenant: "acme"}
+	tests := []struct{ text, want string }{
+		{"Щас приду", "Сейчас приду"},
+		{"ЩАС", "СЕЙЧАС"},
+		{"в кратце привет", "вкратце привет"},
+	}
+	for _, tt := range tests {
+		r := sc.CorrectTextFor(acme, tt.text, false)
+		if r.Corrected != tt.want {
+			t.Errorf("CorrectTextFor(acme, %q) = %q, ожидается %q", tt.text, r.Corrected, tt.want)
+		}
+		if r.DetailedSugs[0].Decision != "forced_replace" {
+			t.Errorf("%q: решение %q, ожидается forced_replace", tt.text, r.DetailedSugs[0].Decision)
+		}
+	}
+	if r := sc.CorrectText("щас", false); r.Corrected != "щас" {
+		t.Errorf("правило acme применено к тенанту по умолчанию: %q", r.Corrected)
+	}
+
+	if existed, err := sc.RemoveRule("acme", "ЩАС"); err != nil || !existed {
+		t.Fatalf("RemoveRule(acme, ЩАС) = %v, %v", existed, err)
+	}
+	if r := sc.CorrectTextFor(acme, "щас", false); r.Corrected != "щас" {
+		t.Errorf("удаленное правило применено: %q", r.Corrected)
+	}
+	if rules, _ := sc.Rules("acme"); len(rules) != 1 || rules[0].From != "в кратце" {
+		t.Errorf("Rules(acme) = %+v", rules)
+	}
+	if _, err := sc.AddRule("acme", customdict.Rule{From: ", щас", To: "сейчас"}); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("AddRule(, щас) = %v, ожидается ErrInvalidRule", err)
+	}
+}
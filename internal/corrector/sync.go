@@ -0,0 +1,187 @@
+package corrector
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"corrector/internal/customdict"
+)
+
+// =====================
+// Синхронизация словарей между репликами
+// =====================
+//
+// Пользовательские словари загружаются из хранилища один раз и дальше меняются в памяти,
+// поэтому слово, добавленное через одну реплику, другим репликам неизвестно.
+// Если хранилище сообщает об изменениях (customdict.Watcher), реплика применяет их сразу;
+// кроме того, все загруженные словари периодически перечитываются целиком,
+// чтобы восстановиться после потерянных событий.
+
+// watchRetryDelay - пауза перед повторной подпиской после сбоя соединения с хранилищем.
+const watchRetryDelay = 5 * time.Second
+
+// StartSync запускает фоновую синхронизацию словарей до отмены ctx:
+// применение событий хранилища (если оно их поддерживает) и полную
+// пересинхронизацию раз в resyncInterval (0 - без нее).
+func (sc *SpellCorrector) StartSync(ctx context.Context, resyncInterval time.Duration) {
+	if sc.dict == nil {
+		return
+	}
+	if w, ok := sc.dict.(customdict.Watcher); ok {
+		go sc.watch(ctx, w)
+	}
+	if resyncInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(resyncInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					sc.Resync()
+				}
+			}
+		}()
+	}
+}
+
+// watch подписывается на события хранилища и переподписывается после сбоев.
+// После переподписки словари перечитываются: события за время сбоя потеряны.
+func (sc *SpellCorrector) watch(ctx context.Context, w customdict.Watcher) {
+	for {
+		err := w.Watch(ctx, sc.applyEvent)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("предупреждение: подписка на изменения словаря прервана: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryDelay):
+		}
+		sc.Resync()
+	}
+}
+
+// applyEvent применяет изменение, сделанное другой репликой. Словари, которые
+// еще не загружены, не трогаем: при загрузке они прочитают актуальное состояние.
+func (sc *SpellCorrector) applyEvent(ev customdict.Event) {
+	word := strings.ToLower(ev.Word)
+	switch ev.Kind {
+	case customdict.EventAdd, customdict.EventAddAll:
+		if l := sc.tenants.loaded(ev.Tenant); l != nil {
+			entries := make([]customdict.Entry, len(ev.Entries))
+			for i, e := range ev.Entries {
+				e.Word = strings.ToLower(e.Word)
+				entries[i] = e
+			}
+			l.addWords(entries)
+		}
+	case customdict.EventRemove:
+		if l := sc.tenants.loaded(ev.Tenant); l != nil {
+			l.removeWord(word)
+		}
+	case customdict.EventAddLexeme:
+		if l := sc.tenants.loaded(ev.Tenant); l != nil && ev.Lexeme != nil {
+			sc.addLexemeForms(ev.Tenant, l, *ev.Lexeme)
+		}
+	case customdict.EventRemoveLexeme:
+		if l := sc.tenants.loaded(ev.Tenant); l != nil {
+			l.removeLexeme(word)
+			sc.dropMorphLexeme(ev.Tenant, l, word)
+		}
+	case customdict.EventAddUserWord:
+		key := userKey(Scope{Tenant: ev.Tenant, User: ev.User})
+		if l := sc.users.loaded(key); l != nil {
+			l.addWord(customdict.Entry{Word: word})
+		} else {
+			// Пользователь был без слов (в кэше nil): перечитаем словарь при обращении
+			sc.users.drop(key)
+		}
+	case customdict.EventRemoveUserWord:
+		key := userKey(Scope{Tenant: ev.Tenant, User: ev.User})
+		if l := sc.users.loaded(key); l != nil {
+			l.removeWord(word)
+			if l.size() == 0 {
+				sc.users.set(key, nil)
+			}
+		}
+	case customdict.EventBlock:
+		if l := sc.tenants.loaded(ev.Tenant); l != nil {
+			l.block(word)
+		}
+	case customdict.EventUnblock:
+		if l := sc.tenants.loaded(ev.Tenant); l != nil {
+			l.unblock(word)
+		}
+	case customdict.EventAddRule:
+		if l := sc.tenants.loaded(ev.Tenant); l != nil && ev.Rule != nil {
+			if r, n, err := normalizeRule(*ev.Rule); err == nil {
+				l.setRule(r, n)
+			}
+		}
+	case customdict.EventRemoveRule:
+		if l := sc.tenants.loaded(ev.Tenant); l != nil {
+			if key, _, ok := ruleKey(ev.Word); ok {
+				l.removeRule(key)
+			}
+		}
+	case customdict.EventVote:
+		if l := sc.tenants.loaded(ev.Tenant); l != nil && ev.Vote != nil {
+			l.addVote(*ev.Vote)
+		}
+	case customdict.EventReload:
+		sc.Resync()
+	}
+}
+
+// Resync перечитывает из хранилища все загруженные словари тенантов и пользователей,
+// предварительно вытеснив словари, к которым давно не обращались.
+// Словарь, который не удалось прочитать, остается прежним.
+func (sc *SpellCorrector) Resync() {
+	sc.tenants.sweep()
+	sc.users.sweep()
+	for _, tenant := range sc.tenants.keys() {
+		l, err := sc.loadLexicon(tenant)
+		if err != nil {
+			log.Printf("предупреждение: не удалось перечитать словарь тенанта %q: %v", tenant, err)
+			continue
+		}
+		if tenant == DefaultTenant && sc.morph != nil {
+			// Лексемы, удаленные другими репликами, убираем и из морфоанализатора
+			for _, lx := range sc.morph.UserLexemes() {
+				if !l.hasLexeme(lx.Lemma) {
+					sc.dropMorphLexeme(tenant, l, lx.Lemma)
+				}
+			}
+		}
+		sc.tenants.replace(tenant, l)
+	}
+	for _, key := range sc.users.keys() {
+		tenant, user, _ := strings.Cut(key, "\x00")
+		l, err := sc.loadUserLexicon(Scope{Tenant: tenant, User: user})
+		if err != nil {
+			log.Printf("предупреждение: не удалось перечитать личный словарь %q тенанта %q: %v", user, tenant, err)
+			continue
+		}
+		sc.users.replace(key, l)
+	}
+}
+
+// dropMorphLexeme убирает лексему из лексикона словаря тенанта или, для общего словаря,
+// из морфоанализатора.
+func (sc *SpellCorrector) dropMorphLexeme(tenant string, lex *lexicon, lemma string) {
+	if tenant != DefaultTenant {
+		lex.morph.RemoveLexeme(lemma)
+		return
+	}
+	if sc.morph == nil {
+		return
+	}
+	if sc.morph.RemoveLexeme(lemma) {
+		sc.parseCache.Clear()
+	}
+}
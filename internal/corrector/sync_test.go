@@ -0,0 +1,111 @@
+package corrector
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"corrector/internal/analyzer"
+	"corrector/internal/customdict"
+)
+
+// TestApplyEvent применяет события другой реплики к загруженному словарю тенанта:
+// каждое событие меняет словарь на месте, без перечитывания из хранилища.
+func TestApplyEvent(t *testing.T) {
+	sc := newTestCorrector(t, customdict.NewMemory())
+	acme := Scope{Tenant: "acme"}
+	sc.CorrectTextFor(acme, "кот", false)
+	l := sc.tenants.loaded("acme")
+	if l == nil {
+		t.Fatal("словарь acme не загружен")
+	}
+
+	sc.applyEvent(customdict.Event{Kind: customdict.EventAddAll, Tenant: "acme", Entries: []customdict.Entry{{Word: "Зиплайн"}}})
+	if r := sc.CorrectTextFor(acme, "зиплаин", false); !slices.Contains(suggestions(r), "зиплайн") {
+		t.Errorf("слово из события не добавлено: %v", suggestions(r))
+	}
+	vote := customdict.Vote{Original: "стле", Suggestion: "стол", Accepted: true, User: "alice"}
+	sc.applyEvent(customdict.Event{Kind: customdict.EventVote, Tenant: "acme", Vote: &vote})
+	if fb, _ := sc.Feedback("acme"); len(fb) != 1 || fb[0].Accepted != 1 {
+		t.Errorf("голос из события не учтен: %+v", fb)
+	}
+	sc.applyEvent(customdict.Event{Kind: customdict.EventBlock, Tenant: "acme", Word: "кот"})
+	if r := sc.CorrectTextFor(acme, "кат", false); r.Corrected == "кот" {
+		t.Error("запрет из события не применен")
+	}
+	sc.applyEvent(customdict.Event{Kind: customdict.EventRemove, Tenant: "acme", Word: "зиплайн"})
+	if r := sc.CorrectTextFor(acme, "зиплаин", false); slices.Contains(suggestions(r), "зиплайн") {
+		t.Errorf("удаленное слово осталось: %v", suggestions(r))
+	}
+	if sc.tenants.loaded("acme") != l {
+		t.Error("словарь acme перечитан вместо применения событий")
+	}
+	// События незагруженного тенанта игнорируются
+	sc.applyEvent(customdict.Event{Kind: customdict.EventAddAll, Tenant: "globex", Entries: []customdict.Entry{{Word: "зиплайн"}}})
+	if sc.tenants.loaded("globex") != nil {
+		t.Error("событие загрузило словарь globex")
+	}
+
+	// Лексема общего словаря попадает в морфоанализатор
+	lx := customdict.Lexeme{Lemma: "вайбить", Forms: []customdict.Form{{Word: "вайбила", Tags: "VERB,impf,intr,femn,sing,past,indc"}}}
+	sc.lexiconFor(DefaultTenant)
+	sc.applyEvent(customdict.Event{Kind: customdict.EventAddLexeme, Lexeme: &lx})
+	if !hasUserParse(sc.Morph(), "вайбила") {
+		t.Error("лексема из события не добавлена в морфоанализатор")
+	}
+	sc.applyEvent(customdict.Event{Kind: customdict.EventRemoveLexeme, Word: "вайбить"})
+	if hasUserParse(sc.Morph(), "вайбила") {
+		t.Error("лексема осталась в морфоанализаторе после удаления")
+	}
+
+	// EventReload перечитывает словари из хранилища
+	sc.applyEvent(customdict.Event{Kind: customdict.EventReload})
+	if sc.tenants.loaded("acme") == l {
+		t.Error("EventReload не перечитал словарь acme")
+	}
+}
+
+// TestResyncKeepsLexemes проверяет, что лексема общего словаря не пропадает
+// из морфоанализатора, пока Resync перечитывает словари.
+func TestResyncKeepsLexemes(t *testing.T) {
+	sc := newTestCorrector(t, customdict.NewMemory())
+	lx := customdict.Lexeme{Lemma: "вайбить", Forms: []customdict.Form{
+		{Word: "вайбить", Tags: "INFN,impf,intr"},
+		{Word: "вайбила", Tags: "VERB,impf,intr,femn,sing,past,indc"},
+	}}
+	if _, err := sc.AddLexeme(DefaultTenant, lx); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				sc.Resync()
+			}
+		}
+	}()
+	for range 2000 {
+		if !hasUserParse(sc.Morph(), "вайбила") {
+			t.Error("лексема пропала из морфоанализатора во время Resync")
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func hasUserParse(m *analyzer.MorphAnalyzer, word string) bool {
+	for _, p := range m.Parse(word) {
+		if p.Origin == analyzer.OriginUser {
+			return true
+		}
+	}
+	return false
+}
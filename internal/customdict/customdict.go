@@ -2,32 +2,415 @@ package customdict
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-// CustomDict wraps a Redis client to store custom dictionary words.
+// CustomDict is a Store backed by Redis.
+//
+// Keys of the default tenant are listed below; other tenants use the same keys
+// suffixed with ":<tenant>".
+//   - Entries are JSON values of the "custom_words" hash, keyed by word.
+//   - Lexemes are JSON values of the "custom_lexemes" hash, keyed by lemma.
+//   - Words stored by earlier versions in the "custom_dict" set are still read
+//     as entries without metadata; removing a word removes it from there too.
+//   - Personal dictionaries are sets under "user_dict:<tenant>:<user>".
+//   - Blocklists are sets under "blocked_words".
+//   - Replacement rules are JSON values of the "replace_rules" hash, keyed by From.
+//   - Feedback votes are JSON values of the "feedback_votes" hash, keyed by pair and voter (see Vote.Voter).
+//
+// Every change is published to the "custom_dict_events" channel in the same
+// transaction, so that other replicas can apply it (see Watch).
 type CustomDict struct {
-	client *redis.Client
-	key    string
+	client     *redis.Client
+	key        string
+	legacyKey  string
+	lexemesKey string
+	blockedKey string
+	rulesKey   string
+	votesKey   string
+	channel    string
+	origin     string
 }
 
-// New creates a new CustomDict with the provided Redis client.
+// Entry is a custom word with optional metadata.
+type Entry struct {
+	Word string `json:"word"`
+	// Frequency replaces the default frequency of custom words when positive.
+	Frequency int64     `json:"frequency,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// UnmarshalJSON accepts both an object and a bare word,
+// which is how words were stored before entries had metadata.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var word string
+	if err := json.Unmarshal(data, &word); err == nil {
+		*e = Entry{Word: word}
+		return nil
+	}
+	type entry Entry
+	return json.Unmarshal(data, (*entry)(e))
+}
+
+// Lexeme is a custom word stored together with all of its inflected forms,
+// so that the forms can be whitelisted and removed as a unit.
+// The metadata is the same as for an Entry and applies to every form.
+type Lexeme struct {
+	Lemma string `json:"lemma"`
+	Forms []Form `json:"forms"`
+	// Frequency replaces the default frequency of the forms when positive.
+	Frequency int64     `json:"frequency,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// Form is a single inflected form of a custom lexeme.
+type Form struct {
+	Word string `json:"word"`
+	Tags string `json:"tags,omitempty"`
+}
+
+// Rule is a forced replacement: the words From, matched case-insensitively,
+// are always replaced with To. From may span several words separated by spaces.
+type Rule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Vote is a user's feedback on a suggestion: whether replacing Original with
+// Suggestion was accepted or rejected. A store keeps one vote per user and pair.
+type Vote struct {
+	Original   string `json:"original"`
+	Suggestion string `json:"suggestion"`
+	// User is empty for anonymous feedback; all anonymous votes count as one user.
+	User string `json:"user,omitempty"`
+	// Client identifies who sent the vote (the client address, or an identity
+	// set by an authenticating proxy). Unlike User it is not chosen by the client.
+	Client   string    `json:"client,omitempty"`
+	Accepted bool      `json:"accepted"`
+	Context  string    `json:"context,omitempty"`
+	Time     time.Time `json:"time,omitzero"`
+}
+
+// Voter returns who the vote counts for: the client if known, otherwise the user.
+// A client has one vote per pair whatever user ids it sends.
+func (v Vote) Voter() string {
+	if v.Client != "" {
+		return v.Client
+	}
+	return v.User
+}
+
+// key identifies the pair and the voter of the vote.
+func (v Vote) key() string {
+	return v.Original + "\x00" + v.Suggestion + "\x00" + v.Voter()
+}
+
+// New creates a Redis-backed store with the provided client.
 func New(client *redis.Client) *CustomDict {
-	return &CustomDict{client: client, key: "custom_dict"}
+	return &CustomDict{
+		client:     client,
+		key:        "custom_words",
+		legacyKey:  "custom_dict",
+		lexemesKey: "custom_lexemes",
+		blockedKey: "blocked_words",
+		rulesKey:   "replace_rules",
+		votesKey:   "feedback_votes",
+		channel:    "custom_dict_events",
+		origin:     newOrigin(),
+	}
+}
+
+// tenantKey returns the Redis key of the tenant's copy of base.
+func tenantKey(base, tenant string) string {
+	if tenant == DefaultTenant {
+		return base
+	}
+	return base + ":" + tenant
+}
+
+// userKey returns the Redis key of the user's personal dictionary.
+func userKey(tenant, user string) string {
+	return "user_dict:" + tenant + ":" + user
+}
+
+// write runs change and publishes ev in a single MULTI/EXEC transaction.
+func (cd *CustomDict) write(ev Event, change func(p redis.Pipeliner)) error {
+	ev.Origin = cd.origin
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = cd.client.TxPipelined(context.Background(), func(p redis.Pipeliner) error {
+		change(p)
+		p.Publish(context.Background(), cd.channel, data)
+		return nil
+	})
+	return err
+}
+
+// Add stores an entry in the tenant's custom dictionary, replacing the entry for the same word.
+func (cd *CustomDict) Add(tenant string, e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return cd.write(Event{Kind: EventAdd, Tenant: tenant, Word: e.Word, Entries: []Entry{e}}, func(p redis.Pipeliner) {
+		p.HSet(context.Background(), tenantKey(cd.key, tenant), e.Word, data)
+	})
+}
+
+// AddAll stores many entries in the tenant's custom dictionary in one transaction.
+func (cd *CustomDict) AddAll(tenant string, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	fields := make([]interface{}, 0, 2*len(entries))
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, e.Word, data)
+	}
+	return cd.write(Event{Kind: EventAddAll, Tenant: tenant, Entries: entries}, func(p redis.Pipeliner) {
+		p.HSet(context.Background(), tenantKey(cd.key, tenant), fields...)
+	})
+}
+
+// Remove deletes a word from the tenant's custom dictionary.
+func (cd *CustomDict) Remove(tenant, word string) error {
+	return cd.write(Event{Kind: EventRemove, Tenant: tenant, Word: word}, func(p redis.Pipeliner) {
+		p.HDel(context.Background(), tenantKey(cd.key, tenant), word)
+		p.SRem(context.Background(), tenantKey(cd.legacyKey, tenant), word)
+	})
+}
+
+// All returns all entries of the tenant's custom dictionary.
+func (cd *CustomDict) All(tenant string) ([]Entry, error) {
+	ctx := context.Background()
+	stored, err := cd.client.HGetAll(ctx, tenantKey(cd.key, tenant)).Result()
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := cd.client.SMembers(ctx, tenantKey(cd.legacyKey, tenant)).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(stored)+len(legacy))
+	var errs []error
+	for word, data := range stored {
+		var e Entry
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			errs = append(errs, fmt.Errorf("entry %q: %w: %w", word, ErrMalformed, err))
+			continue
+		}
+		entries = append(entries, e)
+	}
+	for _, word := range legacy {
+		if _, ok := stored[word]; !ok {
+			entries = append(entries, Entry{Word: word})
+		}
+	}
+	return entries, errors.Join(errs...)
+}
+
+// AddLexeme stores a lexeme under its lemma, replacing any previous forms.
+func (cd *CustomDict) AddLexeme(tenant string, lx Lexeme) error {
+	data, err := json.Marshal(lx)
+	if err != nil {
+		return err
+	}
+	return cd.write(Event{Kind: EventAddLexeme, Tenant: tenant, Lexeme: &lx}, func(p redis.Pipeliner) {
+		p.HSet(context.Background(), tenantKey(cd.lexemesKey, tenant), lx.Lemma, data)
+	})
+}
+
+// RemoveLexeme deletes the lexeme with the given lemma.
+// It reports whether such a lexeme existed.
+func (cd *CustomDict) RemoveLexeme(tenant, lemma string) (bool, error) {
+	var del *redis.IntCmd
+	err := cd.write(Event{Kind: EventRemoveLexeme, Tenant: tenant, Word: lemma}, func(p redis.Pipeliner) {
+		del = p.HDel(context.Background(), tenantKey(cd.lexemesKey, tenant), lemma)
+	})
+	if err != nil {
+		return false, err
+	}
+	return del.Val() > 0, nil
+}
+
+// Lexemes returns all lexemes stored for the tenant.
+func (cd *CustomDict) Lexemes(tenant string) ([]Lexeme, error) {
+	entries, err := cd.client.HGetAll(context.Background(), tenantKey(cd.lexemesKey, tenant)).Result()
+	if err != nil {
+		return nil, err
+	}
+	lexemes := make([]Lexeme, 0, len(entries))
+	var errs []error
+	for lemma, data := range entries {
+		var lx Lexeme
+		if err := json.Unmarshal([]byte(data), &lx); err != nil {
+			errs = append(errs, fmt.Errorf("lexeme %q: %w: %w", lemma, ErrMalformed, err))
+			continue
+		}
+		lexemes = append(lexemes, lx)
+	}
+	return lexemes, errors.Join(errs...)
+}
+
+// AddUserWord inserts a word into the personal dictionary of a user of the tenant.
+func (cd *CustomDict) AddUserWord(tenant, user, word string) error {
+	return cd.write(Event{Kind: EventAddUserWord, Tenant: tenant, User: user, Word: word}, func(p redis.Pipeliner) {
+		p.SAdd(context.Background(), userKey(tenant, user), word)
+	})
+}
+
+// RemoveUserWord deletes a word from the user's personal dictionary and reports whether it existed.
+func (cd *CustomDict) RemoveUserWord(tenant, user, word string) (bool, error) {
+	var rem *redis.IntCmd
+	err := cd.write(Event{Kind: EventRemoveUserWord, Tenant: tenant, User: user, Word: word}, func(p redis.Pipeliner) {
+		rem = p.SRem(context.Background(), userKey(tenant, user), word)
+	})
+	if err != nil {
+		return false, err
+	}
+	return rem.Val() > 0, nil
+}
+
+// UserWords returns all words of the user's personal dictionary.
+func (cd *CustomDict) UserWords(tenant, user string) ([]string, error) {
+	return cd.client.SMembers(context.Background(), userKey(tenant, user)).Result()
+}
+
+// Block adds a word to the tenant's blocklist.
+func (cd *CustomDict) Block(tenant, word string) error {
+	return cd.write(Event{Kind: EventBlock, Tenant: tenant, Word: word}, func(p redis.Pipeliner) {
+		p.SAdd(context.Background(), tenantKey(cd.blockedKey, tenant), word)
+	})
+}
+
+// Unblock deletes a word from the tenant's blocklist and reports whether it was there.
+func (cd *CustomDict) Unblock(tenant, word string) (bool, error) {
+	var rem *redis.IntCmd
+	err := cd.write(Event{Kind: EventUnblock, Tenant: tenant, Word: word}, func(p redis.Pipeliner) {
+		rem = p.SRem(context.Background(), tenantKey(cd.blockedKey, tenant), word)
+	})
+	if err != nil {
+		return false, err
+	}
+	return rem.Val() > 0, nil
+}
+
+// Blocked returns all words of the tenant's blocklist.
+func (cd *CustomDict) Blocked(tenant string) ([]string, error) {
+	return cd.client.SMembers(context.Background(), tenantKey(cd.blockedKey, tenant)).Result()
+}
+
+// AddRule stores a replacement rule of the tenant, replacing the rule with the same From.
+func (cd *CustomDict) AddRule(tenant string, r Rule) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return cd.write(Event{Kind: EventAddRule, Tenant: tenant, Rule: &r}, func(p redis.Pipeliner) {
+		p.HSet(context.Background(), tenantKey(cd.rulesKey, tenant), r.From, data)
+	})
+}
+
+// RemoveRule deletes the tenant's rule for from and reports whether it existed.
+func (cd *CustomDict) RemoveRule(tenant, from string) (bool, error) {
+	var del *redis.IntCmd
+	err := cd.write(Event{Kind: EventRemoveRule, Tenant: tenant, Word: from}, func(p redis.Pipeliner) {
+		del = p.HDel(context.Background(), tenantKey(cd.rulesKey, tenant), from)
+	})
+	if err != nil {
+		return false, err
+	}
+	return del.Val() > 0, nil
+}
+
+// Rules returns all replacement rules of the tenant.
+func (cd *CustomDict) Rules(tenant string) ([]Rule, error) {
+	stored, err := cd.client.HGetAll(context.Background(), tenantKey(cd.rulesKey, tenant)).Result()
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]Rule, 0, len(stored))
+	var errs []error
+	for from, data := range stored {
+		var r Rule
+		if err := json.Unmarshal([]byte(data), &r); err != nil {
+			errs = append(errs, fmt.Errorf("rule %q: %w: %w", from, ErrMalformed, err))
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules, errors.Join(errs...)
 }
 
-// Add inserts a word into the custom dictionary.
-func (cd *CustomDict) Add(word string) error {
-	return cd.client.SAdd(context.Background(), cd.key, word).Err()
+// AddVote stores a user's feedback on a suggestion, replacing the voter's previous vote on the same pair.
+func (cd *CustomDict) AddVote(tenant string, v Vote) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return cd.write(Event{Kind: EventVote, Tenant: tenant, Vote: &v}, func(p redis.Pipeliner) {
+		p.HSet(context.Background(), tenantKey(cd.votesKey, tenant), v.key(), data)
+	})
 }
 
-// Remove deletes a word from the custom dictionary.
-func (cd *CustomDict) Remove(word string) error {
-	return cd.client.SRem(context.Background(), cd.key, word).Err()
+// Votes returns all feedback votes of the tenant.
+func (cd *CustomDict) Votes(tenant string) ([]Vote, error) {
+	stored, err := cd.client.HGetAll(context.Background(), tenantKey(cd.votesKey, tenant)).Result()
+	if err != nil {
+		return nil, err
+	}
+	votes := make([]Vote, 0, len(stored))
+	var errs []error
+	for key, data := range stored {
+		var v Vote
+		if err := json.Unmarshal([]byte(data), &v); err != nil {
+			errs = append(errs, fmt.Errorf("vote %q: %w: %w", key, ErrMalformed, err))
+			continue
+		}
+		votes = append(votes, v)
+	}
+	return votes, errors.Join(errs...)
 }
 
-// All returns all words stored in the custom dictionary.
-func (cd *CustomDict) All() ([]string, error) {
-	return cd.client.SMembers(context.Background(), cd.key).Result()
+// Watch subscribes to the change channel and calls fn for changes made by other instances.
+// It returns when ctx is cancelled or the subscription fails.
+func (cd *CustomDict) Watch(ctx context.Context, fn func(Event)) error {
+	sub := cd.client.Subscribe(ctx, cd.channel)
+	defer sub.Close()
+	// Wait for the subscription to be confirmed so that connection errors surface here.
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return errors.New("custom dictionary subscription closed")
+			}
+			var ev Event
+			if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil || ev.Origin == cd.origin {
+				continue
+			}
+			fn(ev)
+		}
+	}
 }
@@ -0,0 +1,201 @@
+package customdict
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"maps"
+	"slices"
+)
+
+// EventKind identifies the kind of change made to a store.
+type EventKind string
+
+const (
+	EventAdd            EventKind = "add"
+	EventAddAll         EventKind = "add_all"
+	EventRemove         EventKind = "remove"
+	EventAddLexeme      EventKind = "add_lexeme"
+	EventRemoveLexeme   EventKind = "remove_lexeme"
+	EventAddUserWord    EventKind = "add_user_word"
+	EventRemoveUserWord EventKind = "remove_user_word"
+	EventBlock          EventKind = "block"
+	EventUnblock        EventKind = "unblock"
+	EventAddRule        EventKind = "add_rule"
+	EventRemoveRule     EventKind = "remove_rule"
+	EventVote           EventKind = "vote"
+	// EventReload means that the store changed in an unknown way
+	// (for example, too many changes piled up while nobody watched)
+	// and every cached dictionary should be reloaded.
+	EventReload EventKind = "reload"
+)
+
+// Event describes a change made to a store by some process.
+type Event struct {
+	Kind   EventKind `json:"kind"`
+	Tenant string    `json:"tenant,omitempty"`
+	User   string    `json:"user,omitempty"`
+	// Word is the added, removed, blocked or unblocked word, the lemma of a removed
+	// lexeme or the From of a removed rule.
+	Word string `json:"word,omitempty"`
+	// Entries are the stored entries for EventAdd and EventAddAll.
+	Entries []Entry `json:"entries,omitempty"`
+	// Lexeme is the stored lexeme for EventAddLexeme.
+	Lexeme *Lexeme `json:"lexeme,omitempty"`
+	// Rule is the stored rule for EventAddRule.
+	Rule *Rule `json:"rule,omitempty"`
+	// Vote is the stored vote for EventVote.
+	Vote *Vote `json:"vote,omitempty"`
+	// Origin identifies the store instance that made the change.
+	Origin string `json:"origin,omitempty"`
+}
+
+// Watcher is implemented by stores shared between processes.
+// Watch calls fn for every change made through other store instances until ctx
+// is cancelled or the connection fails; changes made through the same instance
+// are not reported. Events may be lost (for example, while reconnecting),
+// so consumers should also reload their state periodically.
+type Watcher interface {
+	Watch(ctx context.Context, fn func(Event)) error
+}
+
+var (
+	_ Watcher = (*CustomDict)(nil)
+	_ Watcher = (*File)(nil)
+)
+
+// newOrigin returns a random identifier of a store instance.
+func newOrigin() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// diffTenants returns the events that turn the dictionaries before into after.
+// Tenants, users and items are visited in sorted order.
+func diffTenants(before, after map[string]*memoryTenant) []Event {
+	var events []Event
+	for _, name := range unionKeys(before, after) {
+		events = diffTenant(events, name, before[name], after[name])
+	}
+	return events
+}
+
+// diffTenant appends the events that turn one tenant's dictionary old into cur.
+// Either may be nil.
+func diffTenant(events []Event, tenant string, old, cur *memoryTenant) []Event {
+	if old == nil {
+		old = &memoryTenant{}
+	}
+	if cur == nil {
+		cur = &memoryTenant{}
+	}
+	var added []Entry
+	for _, w := range slices.Sorted(maps.Keys(cur.words)) {
+		if e, ok := old.words[w]; !ok || !sameEntry(e, cur.words[w]) {
+			added = append(added, cur.words[w])
+		}
+	}
+	if len(added) > 0 {
+		events = append(events, Event{Kind: EventAddAll, Tenant: tenant, Entries: added})
+	}
+	for _, w := range slices.Sorted(maps.Keys(old.words)) {
+		if _, ok := cur.words[w]; !ok {
+			events = append(events, Event{Kind: EventRemove, Tenant: tenant, Word: w})
+		}
+	}
+
+	for _, lemma := range slices.Sorted(maps.Keys(cur.lexemes)) {
+		lx := cur.lexemes[lemma]
+		if prev, ok := old.lexemes[lemma]; !ok || !sameLexeme(prev, lx) {
+			events = append(events, Event{Kind: EventAddLexeme, Tenant: tenant, Lexeme: &lx})
+		}
+	}
+	for _, lemma := range slices.Sorted(maps.Keys(old.lexemes)) {
+		if _, ok := cur.lexemes[lemma]; !ok {
+			events = append(events, Event{Kind: EventRemoveLexeme, Tenant: tenant, Word: lemma})
+		}
+	}
+
+	for _, user := range unionKeys(old.users, cur.users) {
+		for _, w := range slices.Sorted(maps.Keys(cur.users[user])) {
+			if _, ok := old.users[user][w]; !ok {
+				events = append(events, Event{Kind: EventAddUserWord, Tenant: tenant, User: user, Word: w})
+			}
+		}
+		for _, w := range slices.Sorted(maps.Keys(old.users[user])) {
+			if _, ok := cur.users[user][w]; !ok {
+				events = append(events, Event{Kind: EventRemoveUserWord, Tenant: tenant, User: user, Word: w})
+			}
+		}
+	}
+
+	for _, w := range slices.Sorted(maps.Keys(cur.blocked)) {
+		if _, ok := old.blocked[w]; !ok {
+			events = append(events, Event{Kind: EventBlock, Tenant: tenant, Word: w})
+		}
+	}
+	for _, w := range slices.Sorted(maps.Keys(old.blocked)) {
+		if _, ok := cur.blocked[w]; !ok {
+			events = append(events, Event{Kind: EventUnblock, Tenant: tenant, Word: w})
+		}
+	}
+
+	for _, from := range slices.Sorted(maps.Keys(cur.rules)) {
+		r := cur.rules[from]
+		if prev, ok := old.rules[from]; !ok || prev != r {
+			events = append(events, Event{Kind: EventAddRule, Tenant: tenant, Rule: &r})
+		}
+	}
+	for _, from := range slices.Sorted(maps.Keys(old.rules)) {
+		if _, ok := cur.rules[from]; !ok {
+			events = append(events, Event{Kind: EventRemoveRule, Tenant: tenant, Word: from})
+		}
+	}
+
+	// Votes are never deleted, only replaced
+	for _, k := range slices.Sorted(maps.Keys(cur.votes)) {
+		v := cur.votes[k]
+		if prev, ok := old.votes[k]; !ok || !sameVote(prev, v) {
+			events = append(events, Event{Kind: EventVote, Tenant: tenant, Vote: &v})
+		}
+	}
+	return events
+}
+
+// unionKeys returns the keys present in either map, sorted.
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := slices.Collect(maps.Keys(a))
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// sameEntry compares entries by value; times are compared as instants,
+// since an entry read back from JSON loses its location and monotonic reading.
+func sameEntry(a, b Entry) bool {
+	created, updated := a.CreatedAt, a.UpdatedAt
+	a.CreatedAt, a.UpdatedAt = b.CreatedAt, b.UpdatedAt
+	return a == b && created.Equal(b.CreatedAt) && updated.Equal(b.UpdatedAt)
+}
+
+// sameLexeme compares lexemes by forms and metadata, like sameEntry.
+func sameLexeme(a, b Lexeme) bool {
+	if !slices.Equal(a.Forms, b.Forms) {
+		return false
+	}
+	ea := Entry{Word: a.Lemma, Frequency: a.Frequency, Note: a.Note, Author: a.Author, CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt}
+	eb := Entry{Word: b.Lemma, Frequency: b.Frequency, Note: b.Note, Author: b.Author, CreatedAt: b.CreatedAt, UpdatedAt: b.UpdatedAt}
+	return sameEntry(ea, eb)
+}
+
+// sameVote compares votes by value, like sameEntry.
+func sameVote(a, b Vote) bool {
+	t := a.Time
+	a.Time = b.Time
+	return a == b && t.Equal(b.Time)
+}
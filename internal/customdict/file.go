@@ -0,0 +1,537 @@
+package customdict
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// File is a Store backed by a local JSON file. The whole dictionary is kept
+// in memory and the file is rewritten atomically (write to a temporary file,
+// then rename) after every change except votes (see below), so a crash never
+// leaves a torn file.
+//
+// Several processes may share the file. Changes are serialized by an exclusive
+// advisory lock (flock) on a lock file next to it (path + ".lock"): under the
+// lock a File rereads the file if another process has written it since, applies
+// the change and writes the result. The file also carries a version counter
+// that is incremented on every write; Watch polls it to notice writes of other
+// processes. On systems without flock (Windows) no lock is taken and the file
+// must not be shared.
+//
+// When a File rereads the file, it compares the new contents with the old ones
+// and records a fine-grained Event for every difference, which Watch then reports.
+// Replicas can then apply the changes one by one instead of reloading everything.
+//
+// Votes arrive far more often than other changes, so AddVote does not rewrite
+// the file. It appends the vote to a log next to it (path + ".votes"), which is
+// replayed on top of the file. The next full write folds the log into the file.
+// The log is also folded once it grows past maxVoteLog.
+type File struct {
+	mem  *Memory
+	path string
+	// lock is the open lock file; it stays open for the lifetime of the File.
+	lock *os.File
+	// version and stat describe the file the in-memory dictionary corresponds to.
+	version uint64
+	stat    os.FileInfo
+	// voteLog and voteOffset describe the vote log: its file and how much of it is applied.
+	voteLog    os.FileInfo
+	voteOffset int64
+	// events are the changes of other processes picked up under the lock
+	// that Watch has not reported yet.
+	events []Event
+	// pollInterval is how often Watch checks the file for changes made by other processes.
+	pollInterval time.Duration
+}
+
+// filePollInterval is the default File.pollInterval.
+const filePollInterval = 2 * time.Second
+
+// maxFileEvents is the number of unreported events at which a File stops
+// recording them and reports a single EventReload instead.
+const maxFileEvents = 10_000
+
+// maxVoteLog is the size of the vote log at which AddVote folds it into the file.
+const maxVoteLog = 1 << 20
+
+// voteLogEntry is a line of the vote log.
+type voteLogEntry struct {
+	Tenant string `json:"tenant,omitempty"`
+	Vote   Vote   `json:"vote"`
+}
+
+// tenantContents is the on-disk layout of a single tenant's dictionary.
+type tenantContents struct {
+	Words   []Entry             `json:"words"`
+	Lexemes []Lexeme            `json:"lexemes"`
+	Users   map[string][]string `json:"users,omitempty"`
+	Blocked []string            `json:"blocked,omitempty"`
+	Rules   []Rule              `json:"rules,omitempty"`
+	Votes   []Vote              `json:"votes,omitempty"`
+}
+
+// fileContents is the on-disk layout of a File store: the default tenant's
+// dictionary at the top level and the other tenants under "tenants".
+type fileContents struct {
+	Version uint64 `json:"version"`
+	tenantContents
+	Tenants map[string]*tenantContents `json:"tenants,omitempty"`
+}
+
+// NewFile opens the JSON store at path, creating it on first write if it does not exist.
+// The lock file path + ".lock" is created right away.
+func NewFile(path string) (*File, error) {
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{mem: NewMemory(), path: path, lock: lock, pollInterval: filePollInterval}
+	if err := f.locked(func() error { return nil }); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	f.events = nil
+	return f, nil
+}
+
+// refreshLocked rereads the file and the vote log if another process has written
+// them since they were last read, and records the changes for Watch.
+// The caller must hold the write lock (or own f exclusively).
+func (f *File) refreshLocked() error {
+	before := f.mem.tenants
+	reloaded, err := f.refreshFileLocked()
+	if err != nil {
+		return err
+	}
+	replayed, err := f.replayVotesLocked(reloaded)
+	if reloaded {
+		// The log was replayed from its start on top of the reloaded file:
+		// the differences include both
+		f.report(diffTenants(before, f.mem.tenants)...)
+	} else {
+		for _, e := range replayed {
+			f.report(Event{Kind: EventVote, Tenant: e.Tenant, Vote: &e.Vote})
+		}
+	}
+	return err
+}
+
+// report records events for Watch. If Watch falls too far behind,
+// the queue collapses into a single EventReload.
+func (f *File) report(events ...Event) {
+	if len(f.events) == 1 && f.events[0].Kind == EventReload {
+		return
+	}
+	if len(f.events)+len(events) > maxFileEvents {
+		f.events = []Event{{Kind: EventReload}}
+		return
+	}
+	f.events = append(f.events, events...)
+}
+
+// refreshFileLocked rereads the file if it was written by another process since
+// it was last read and reports whether the dictionary was reloaded.
+func (f *File) refreshFileLocked() (bool, error) {
+	st, err := os.Stat(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if f.stat != nil && os.SameFile(st, f.stat) && st.ModTime().Equal(f.stat.ModTime()) && st.Size() == f.stat.Size() {
+		return false, nil
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return false, err
+	}
+	var contents fileContents
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return false, fmt.Errorf("custom dictionary %s: %w", f.path, err)
+	}
+	loaded := f.stat != nil
+	f.stat = st
+	if loaded && contents.Version == f.version {
+		return false, nil
+	}
+	f.mem.tenants = make(map[string]*memoryTenant)
+	f.load(DefaultTenant, &contents.tenantContents)
+	for name, tc := range contents.Tenants {
+		if tc != nil {
+			f.load(name, tc)
+		}
+	}
+	f.version = contents.Version
+	return true, nil
+}
+
+// votesPath is the path of the vote log.
+func (f *File) votesPath() string {
+	return f.path + ".votes"
+}
+
+// replayVotesLocked applies the votes appended to the log since it was last read,
+// or the whole log if fromStart is set (the file was reloaded) or the log was replaced,
+// and returns the applied entries.
+// An incomplete last line (a write in progress) is left for the next call.
+func (f *File) replayVotesLocked(fromStart bool) ([]voteLogEntry, error) {
+	st, err := os.Stat(f.votesPath())
+	if errors.Is(err, os.ErrNotExist) {
+		f.voteLog, f.voteOffset = nil, 0
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fromStart || f.voteLog == nil || !os.SameFile(st, f.voteLog) || st.Size() < f.voteOffset {
+		f.voteOffset = 0
+	}
+	f.voteLog = st
+	if st.Size() == f.voteOffset {
+		return nil, nil
+	}
+	lf, err := os.Open(f.votesPath())
+	if err != nil {
+		return nil, err
+	}
+	defer lf.Close()
+	data, err := io.ReadAll(io.NewSectionReader(lf, f.voteOffset, st.Size()-f.voteOffset))
+	if err != nil {
+		return nil, err
+	}
+	var applied []voteLogEntry
+	for {
+		line, rest, ok := bytes.Cut(data, []byte{'\n'})
+		if !ok {
+			break
+		}
+		var e voteLogEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return applied, fmt.Errorf("custom dictionary %s: %w", f.votesPath(), err)
+		}
+		f.mem.tenant(e.Tenant).votes[e.Vote.key()] = e.Vote
+		f.voteOffset += int64(len(line)) + 1
+		data, applied = rest, append(applied, e)
+	}
+	return applied, nil
+}
+
+func (f *File) load(tenant string, tc *tenantContents) {
+	t := f.mem.tenant(tenant)
+	for _, e := range tc.Words {
+		t.words[e.Word] = e
+	}
+	for _, lx := range tc.Lexemes {
+		t.lexemes[lx.Lemma] = lx
+	}
+	for user, words := range tc.Users {
+		for _, w := range words {
+			f.mem.addUserWordLocked(tenant, user, w)
+		}
+	}
+	for _, w := range tc.Blocked {
+		t.blocked[w] = struct{}{}
+	}
+	for _, r := range tc.Rules {
+		t.rules[r.From] = r
+	}
+	for _, v := range tc.Votes {
+		t.votes[v.key()] = v
+	}
+}
+
+// Add stores an entry in the tenant's custom dictionary, replacing the entry for the same word.
+func (f *File) Add(tenant string, e Entry) error {
+	return f.update(func(m *Memory) { m.tenant(tenant).words[e.Word] = e })
+}
+
+// AddAll stores many entries in the tenant's custom dictionary with a single write.
+func (f *File) AddAll(tenant string, entries []Entry) error {
+	return f.update(func(m *Memory) {
+		t := m.tenant(tenant)
+		for _, e := range entries {
+			t.words[e.Word] = e
+		}
+	})
+}
+
+// Remove deletes a word from the tenant's custom dictionary.
+func (f *File) Remove(tenant, word string) error {
+	return f.update(func(m *Memory) { delete(m.tenant(tenant).words, word) })
+}
+
+// All returns all entries of the tenant's custom dictionary, sorted by word.
+func (f *File) All(tenant string) ([]Entry, error) { return f.mem.All(tenant) }
+
+// AddLexeme stores a lexeme under its lemma, replacing any previous forms.
+func (f *File) AddLexeme(tenant string, lx Lexeme) error {
+	return f.update(func(m *Memory) { m.tenant(tenant).lexemes[lx.Lemma] = lx })
+}
+
+// RemoveLexeme deletes the lexeme with the given lemma and reports whether it existed.
+func (f *File) RemoveLexeme(tenant, lemma string) (bool, error) {
+	var existed bool
+	err := f.update(func(m *Memory) {
+		t := m.tenant(tenant)
+		_, existed = t.lexemes[lemma]
+		delete(t.lexemes, lemma)
+	})
+	return existed, err
+}
+
+// Lexemes returns all lexemes stored for the tenant, sorted by lemma.
+func (f *File) Lexemes(tenant string) ([]Lexeme, error) { return f.mem.Lexemes(tenant) }
+
+// AddUserWord inserts a word into the personal dictionary of a user of the tenant.
+func (f *File) AddUserWord(tenant, user, word string) error {
+	return f.update(func(m *Memory) { m.addUserWordLocked(tenant, user, word) })
+}
+
+// RemoveUserWord deletes a word from the user's personal dictionary and reports whether it existed.
+func (f *File) RemoveUserWord(tenant, user, word string) (bool, error) {
+	var existed bool
+	err := f.update(func(m *Memory) { existed = m.removeUserWordLocked(tenant, user, word) })
+	return existed, err
+}
+
+// UserWords returns all words of the user's personal dictionary, sorted.
+func (f *File) UserWords(tenant, user string) ([]string, error) { return f.mem.UserWords(tenant, user) }
+
+// Block adds a word to the tenant's blocklist.
+func (f *File) Block(tenant, word string) error {
+	return f.update(func(m *Memory) { m.tenant(tenant).blocked[word] = struct{}{} })
+}
+
+// Unblock deletes a word from the tenant's blocklist and reports whether it was there.
+func (f *File) Unblock(tenant, word string) (bool, error) {
+	var existed bool
+	err := f.update(func(m *Memory) { existed = m.unblockLocked(tenant, word) })
+	return existed, err
+}
+
+// Blocked returns all words of the tenant's blocklist, sorted.
+func (f *File) Blocked(tenant string) ([]string, error) { return f.mem.Blocked(tenant) }
+
+// AddRule stores a replacement rule of the tenant, replacing the rule with the same From.
+func (f *File) AddRule(tenant string, r Rule) error {
+	return f.update(func(m *Memory) { m.tenant(tenant).rules[r.From] = r })
+}
+
+// RemoveRule deletes the tenant's rule for from and reports whether it existed.
+func (f *File) RemoveRule(tenant, from string) (bool, error) {
+	var existed bool
+	err := f.update(func(m *Memory) { existed = m.removeRuleLocked(tenant, from) })
+	return existed, err
+}
+
+// Rules returns all replacement rules of the tenant, sorted by From.
+func (f *File) Rules(tenant string) ([]Rule, error) { return f.mem.Rules(tenant) }
+
+// AddVote stores a user's feedback on a suggestion, replacing the voter's previous vote on the same pair.
+// The vote is appended to the vote log instead of rewriting the file.
+func (f *File) AddVote(tenant string, v Vote) error {
+	return f.locked(func() error {
+		if f.voteOffset >= maxVoteLog {
+			return f.changeLocked(func(m *Memory) { m.tenant(tenant).votes[v.key()] = v })
+		}
+		if err := f.appendVoteLocked(tenant, v); err != nil {
+			return err
+		}
+		f.mem.tenant(tenant).votes[v.key()] = v
+		return nil
+	})
+}
+
+// appendVoteLocked appends a vote to the vote log.
+func (f *File) appendVoteLocked(tenant string, v Vote) error {
+	data, err := json.Marshal(voteLogEntry{Tenant: tenant, Vote: v})
+	if err != nil {
+		return err
+	}
+	lf, err := os.OpenFile(f.votesPath(), os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer lf.Close()
+	// Everything past voteOffset is an incomplete line left by a crashed write
+	if err := lf.Truncate(f.voteOffset); err != nil {
+		return err
+	}
+	if _, err := lf.WriteAt(append(data, '\n'), f.voteOffset); err != nil {
+		return err
+	}
+	if err := lf.Sync(); err != nil {
+		return err
+	}
+	st, err := lf.Stat()
+	if err != nil {
+		return err
+	}
+	f.voteLog, f.voteOffset = st, st.Size()
+	return nil
+}
+
+// Votes returns all feedback votes of the tenant, sorted by pair and voter.
+func (f *File) Votes(tenant string) ([]Vote, error) { return f.mem.Votes(tenant) }
+
+// Watch polls the file and the vote log and reports the changes other processes
+// have made to them: an event per changed word, lexeme, rule or vote, or a single
+// EventReload if there were too many. It returns when ctx is cancelled.
+func (f *File) Watch(ctx context.Context, fn func(Event)) error {
+	ticker := time.NewTicker(f.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		var events []Event
+		err := f.locked(func() error {
+			events, f.events = f.events, nil
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, ev := range events {
+			fn(ev)
+		}
+	}
+}
+
+// update applies change under the write lock and the file lock and persists the result.
+func (f *File) update(change func(m *Memory)) error {
+	return f.locked(func() error { return f.changeLocked(change) })
+}
+
+// locked runs fn under the write lock and the file lock, after picking up
+// the changes other processes have made.
+func (f *File) locked(fn func() error) error {
+	f.mem.mu.Lock()
+	defer f.mem.mu.Unlock()
+	if err := lockFile(f.lock); err != nil {
+		return fmt.Errorf("custom dictionary %s: lock: %w", f.path, err)
+	}
+	defer unlockFile(f.lock)
+
+	if err := f.refreshLocked(); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// changeLocked applies change and rewrites the file.
+// If the file cannot be written, the in-memory change is rolled back.
+func (f *File) changeLocked(change func(m *Memory)) error {
+	m := f.mem
+	saved := m.clone()
+	change(m)
+	if err := f.writeLocked(); err != nil {
+		m.tenants = saved
+		return err
+	}
+	return nil
+}
+
+func (f *File) writeLocked() error {
+	contents := fileContents{Version: f.version + 1}
+	for name, t := range f.mem.tenants {
+		tc := &tenantContents{Words: make([]Entry, 0, len(t.words)), Lexemes: make([]Lexeme, 0, len(t.lexemes))}
+		for _, e := range t.words {
+			tc.Words = append(tc.Words, e)
+		}
+		for _, lx := range t.lexemes {
+			tc.Lexemes = append(tc.Lexemes, lx)
+		}
+		for user, words := range t.users {
+			if tc.Users == nil {
+				tc.Users = make(map[string][]string, len(t.users))
+			}
+			for w := range words {
+				tc.Users[user] = append(tc.Users[user], w)
+			}
+		}
+		for w := range t.blocked {
+			tc.Blocked = append(tc.Blocked, w)
+		}
+		for _, r := range t.rules {
+			tc.Rules = append(tc.Rules, r)
+		}
+		for _, v := range t.votes {
+			tc.Votes = append(tc.Votes, v)
+		}
+		sortContents(tc)
+		switch {
+		case name == DefaultTenant:
+			contents.tenantContents = *tc
+		case len(tc.Words) > 0 || len(tc.Lexemes) > 0 || len(tc.Users) > 0 || len(tc.Blocked) > 0 || len(tc.Rules) > 0 || len(tc.Votes) > 0:
+			if contents.Tenants == nil {
+				contents.Tenants = make(map[string]*tenantContents)
+			}
+			contents.Tenants[name] = tc
+		}
+	}
+	if contents.Words == nil {
+		contents.Words = []Entry{}
+	}
+	if contents.Lexemes == nil {
+		contents.Lexemes = []Lexeme{}
+	}
+	data, err := json.MarshalIndent(contents, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return err
+	}
+	f.version = contents.Version
+	if st, err := os.Stat(f.path); err == nil {
+		f.stat = st
+	}
+	// The votes of the log are in the file now. If the log cannot be emptied,
+	// replaying it later is harmless: a vote replaces the same vote.
+	if f.voteLog != nil && os.Truncate(f.votesPath(), 0) == nil {
+		if st, err := os.Stat(f.votesPath()); err == nil {
+			f.voteLog, f.voteOffset = st, 0
+		}
+	}
+	return nil
+}
+
+func sortContents(c *tenantContents) {
+	sortEntries(c.Words)
+	sort.Slice(c.Lexemes, func(i, j int) bool { return c.Lexemes[i].Lemma < c.Lexemes[j].Lemma })
+	for _, words := range c.Users {
+		sort.Strings(words)
+	}
+	sort.Strings(c.Blocked)
+	sortRules(c.Rules)
+	sortVotes(c.Votes)
+}
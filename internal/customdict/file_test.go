@@ -0,0 +1,235 @@
+package customdict
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestFileSharedWrites writes through two File stores sharing one file, as two
+// processes would: no write may be lost.
+func TestFileSharedWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.json")
+	stores := make([]*File, 2)
+	for i := range stores {
+		f, err := NewFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stores[i] = f
+	}
+
+	const perStore = 50
+	var wg sync.WaitGroup
+	for i, f := range stores {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range perStore {
+				if err := f.Add(DefaultTenant, Entry{Word: fmt.Sprintf("w%d-%d", i, j)}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	f, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := f.All(DefaultTenant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(stores)*perStore {
+		t.Errorf("file holds %d words, want %d", len(entries), len(stores)*perStore)
+	}
+}
+
+// TestFileVoteLog checks that votes are appended to the vote log instead of
+// rewriting the file, and that other stores and later writes pick them up.
+func TestFileVoteLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.json")
+	f, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Add(DefaultTenant, Entry{Word: "word"}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		v := Vote{Original: "teh", Suggestion: "the", Accepted: true, Client: fmt.Sprint("client", i)}
+		if err := f.AddVote("acme", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The same client again replaces its vote
+	if err := other.AddVote("acme", Vote{Original: "teh", Suggestion: "the", Client: "client0"}); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) || !after.ModTime().Equal(before.ModTime()) {
+		t.Error("AddVote rewrote the file")
+	}
+
+	check := func(s *File, what string) {
+		t.Helper()
+		s.mem.mu.Lock()
+		err := s.refreshLocked()
+		s.mem.mu.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		votes, err := s.Votes("acme")
+		if err != nil {
+			t.Fatal(err)
+		}
+		accepted := 0
+		for _, v := range votes {
+			if v.Accepted {
+				accepted++
+			}
+		}
+		if len(votes) != 3 || accepted != 2 {
+			t.Errorf("%s: votes %+v, want 3 votes, 2 accepted", what, votes)
+		}
+	}
+	check(f, "first store")
+	check(other, "second store")
+
+	// A full write folds the log into the file
+	if err := f.Add(DefaultTenant, Entry{Word: "other"}); err != nil {
+		t.Fatal(err)
+	}
+	if st, err := os.Stat(path + ".votes"); err != nil || st.Size() != 0 {
+		t.Errorf("vote log after a full write: %v, %v", st, err)
+	}
+	reopened, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(reopened, "reopened store")
+	check(other, "second store after the fold")
+}
+
+// TestFileWatch checks that Watch reports each change of another process
+// as its own event, including votes appended to the log, and no EventReload.
+func TestFileWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.json")
+	f, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.pollInterval = 5 * time.Millisecond
+	other, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Add("acme", Entry{Word: "own"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan Event, 100)
+	go f.Watch(ctx, func(ev Event) { events <- ev })
+	// expect waits for the next events and checks their kinds and words.
+	// Changes picked up by the same poll are reported in diff order, so order is ignored.
+	expect := func(want ...string) {
+		t.Helper()
+		var got []string
+		for len(got) < len(want) {
+			select {
+			case ev := <-events:
+				w := ev.Word
+				switch {
+				case len(ev.Entries) > 0:
+					w = ev.Entries[0].Word
+				case ev.Lexeme != nil:
+					w = ev.Lexeme.Lemma
+				case ev.Rule != nil:
+					w = ev.Rule.From
+				case ev.Vote != nil:
+					w = ev.Vote.Original
+				}
+				got = append(got, string(ev.Kind)+" "+ev.Tenant+" "+w)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("events %q, want %q", got, want)
+			}
+		}
+		slices.Sort(got)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("events %q, want %q", got, want)
+		}
+	}
+
+	if err := other.Add("acme", Entry{Word: "word"}); err != nil {
+		t.Fatal(err)
+	}
+	expect("add_all acme word")
+
+	if err := other.AddVote("acme", Vote{Original: "teh", Suggestion: "the", Client: "c1"}); err != nil {
+		t.Fatal(err)
+	}
+	expect("vote acme teh")
+
+	// The next full write folds the vote log into the file: the vote is not reported again
+	if err := other.AddLexeme(DefaultTenant, Lexeme{Lemma: "вайб", Forms: []Form{{Word: "вайб", Tags: "NOUN"}}}); err != nil {
+		t.Fatal(err)
+	}
+	expect("add_lexeme  вайб")
+
+	if err := other.Block("acme", "кот"); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.AddRule("acme", Rule{From: "щас", To: "сейчас"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.AddUserWord("acme", "bob", "зиплайн"); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Remove("acme", "own"); err != nil {
+		t.Fatal(err)
+	}
+	expect("block acme кот", "add_rule acme щас", "add_user_word acme зиплайн", "remove acme own")
+
+	// Changes made through f itself are not reported
+	if err := f.Add("acme", Entry{Word: "mine"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("own change reported: %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestFileEventOverflow(t *testing.T) {
+	f := &File{}
+	f.report(Event{Kind: EventVote})
+	f.report(make([]Event, maxFileEvents)...)
+	f.report(Event{Kind: EventAdd})
+	if len(f.events) != 1 || f.events[0].Kind != EventReload {
+		t.Errorf("events after overflow: %d, want a single reload", len(f.events))
+	}
+}
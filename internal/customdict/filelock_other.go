@@ -0,0 +1,11 @@
+//go:build !unix
+
+package customdict
+
+import "os"
+
+// lockFile is a no-op where flock is not available: the file must not be shared
+// between processes there.
+func lockFile(*os.File) error { return nil }
+
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,412 @@
+// Package customdict persists the dictionaries users add on top of the base vocabulary.
+//
+// Each tenant has its own words, lexemes, blocklist, replacement rules and feedback votes.
+// Users of a tenant may also have personal dictionaries.
+// Store is the common interface; Memory, File and CustomDict (Redis) implement it.
+// File and CustomDict report changes made by other processes through Watch.
+package customdict
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// DefaultTenant is the namespace of the shared dictionary. It is stored under
+// the keys used before tenants were introduced, so existing data stays visible.
+const DefaultTenant = ""
+
+// ErrMalformed is wrapped by the errors a Store returns for stored items it cannot decode.
+// Listing methods (All, Lexemes, Rules, Votes) return the items they could decode together
+// with such errors, so callers may skip the malformed items instead of failing the whole read.
+var ErrMalformed = errors.New("malformed stored item")
+
+// Store is the persistence backend for custom words and lexemes.
+// Every method takes the tenant whose dictionary it operates on;
+// dictionaries of different tenants are independent.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Add stores an entry in the tenant's custom dictionary, replacing the entry for the same word.
+	Add(tenant string, e Entry) error
+	// AddAll stores many entries in the tenant's custom dictionary at once:
+	// either all of them are stored or none.
+	AddAll(tenant string, entries []Entry) error
+	// Remove deletes a word from the tenant's custom dictionary.
+	Remove(tenant, word string) error
+	// All returns all entries of the tenant's custom dictionary.
+	All(tenant string) ([]Entry, error)
+	// AddLexeme stores a lexeme under its lemma, replacing any previous forms.
+	AddLexeme(tenant string, lx Lexeme) error
+	// RemoveLexeme deletes the lexeme with the given lemma and reports whether it existed.
+	RemoveLexeme(tenant, lemma string) (bool, error)
+	// Lexemes returns all lexemes stored for the tenant.
+	Lexemes(tenant string) ([]Lexeme, error)
+
+	// AddUserWord inserts a word into the personal dictionary of a user of the tenant.
+	AddUserWord(tenant, user, word string) error
+	// RemoveUserWord deletes a word from the user's personal dictionary and reports whether it existed.
+	RemoveUserWord(tenant, user, word string) (bool, error)
+	// UserWords returns all words of the user's personal dictionary.
+	UserWords(tenant, user string) ([]string, error)
+
+	// Block adds a word to the tenant's blocklist: words that must never be suggested.
+	Block(tenant, word string) error
+	// Unblock deletes a word from the tenant's blocklist and reports whether it was there.
+	Unblock(tenant, word string) (bool, error)
+	// Blocked returns all words of the tenant's blocklist.
+	Blocked(tenant string) ([]string, error)
+
+	// AddRule stores a replacement rule of the tenant, replacing the rule with the same From.
+	AddRule(tenant string, r Rule) error
+	// RemoveRule deletes the tenant's rule for from and reports whether it existed.
+	RemoveRule(tenant, from string) (bool, error)
+	// Rules returns all replacement rules of the tenant.
+	Rules(tenant string) ([]Rule, error)
+
+	// AddVote stores a user's feedback on a suggestion, replacing the previous
+	// vote of the same voter (see Vote.Voter) on the same pair.
+	AddVote(tenant string, v Vote) error
+	// Votes returns all feedback votes of the tenant.
+	Votes(tenant string) ([]Vote, error)
+}
+
+var (
+	_ Store = (*CustomDict)(nil)
+	_ Store = (*Memory)(nil)
+	_ Store = (*File)(nil)
+)
+
+// Memory is an in-memory Store. Its contents are lost when the process exits,
+// which makes it suitable for tests and for running without Redis.
+type Memory struct {
+	mu      sync.RWMutex
+	tenants map[string]*memoryTenant
+}
+
+// memoryTenant holds the dictionary, the blocklist, the replacement rules and
+// the feedback votes of a single tenant and the personal dictionaries of its users.
+type memoryTenant struct {
+	words   map[string]Entry
+	lexemes map[string]Lexeme
+	users   map[string]map[string]struct{}
+	blocked map[string]struct{}
+	rules   map[string]Rule
+	votes   map[string]Vote // keyed by Vote.key
+}
+
+// NewMemory creates an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{tenants: make(map[string]*memoryTenant)}
+}
+
+// tenant returns the dictionary of the tenant, creating it if needed.
+// The caller must hold the write lock.
+func (m *Memory) tenant(name string) *memoryTenant {
+	t := m.tenants[name]
+	if t == nil {
+		t = &memoryTenant{
+			words:   make(map[string]Entry),
+			lexemes: make(map[string]Lexeme),
+			users:   make(map[string]map[string]struct{}),
+			blocked: make(map[string]struct{}),
+			rules:   make(map[string]Rule),
+			votes:   make(map[string]Vote),
+		}
+		m.tenants[name] = t
+	}
+	return t
+}
+
+// clone returns a deep copy of all tenant dictionaries. The caller must hold a lock.
+func (m *Memory) clone() map[string]*memoryTenant {
+	out := make(map[string]*memoryTenant, len(m.tenants))
+	for name, t := range m.tenants {
+		c := &memoryTenant{
+			words:   make(map[string]Entry, len(t.words)),
+			lexemes: make(map[string]Lexeme, len(t.lexemes)),
+			users:   make(map[string]map[string]struct{}, len(t.users)),
+			blocked: make(map[string]struct{}, len(t.blocked)),
+			rules:   make(map[string]Rule, len(t.rules)),
+			votes:   make(map[string]Vote, len(t.votes)),
+		}
+		for w, e := range t.words {
+			c.words[w] = e
+		}
+		for k, lx := range t.lexemes {
+			c.lexemes[k] = lx
+		}
+		for user, words := range t.users {
+			cw := make(map[string]struct{}, len(words))
+			for w := range words {
+				cw[w] = struct{}{}
+			}
+			c.users[user] = cw
+		}
+		for w := range t.blocked {
+			c.blocked[w] = struct{}{}
+		}
+		for from, r := range t.rules {
+			c.rules[from] = r
+		}
+		for k, v := range t.votes {
+			c.votes[k] = v
+		}
+		out[name] = c
+	}
+	return out
+}
+
+// Add stores an entry in the tenant's custom dictionary, replacing the entry for the same word.
+func (m *Memory) Add(tenant string, e Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tenant(tenant).words[e.Word] = e
+	return nil
+}
+
+// AddAll stores many entries in the tenant's custom dictionary at once.
+func (m *Memory) AddAll(tenant string, entries []Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.tenant(tenant)
+	for _, e := range entries {
+		t.words[e.Word] = e
+	}
+	return nil
+}
+
+// Remove deletes a word from the tenant's custom dictionary.
+func (m *Memory) Remove(tenant, word string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t := m.tenants[tenant]; t != nil {
+		delete(t.words, word)
+	}
+	return nil
+}
+
+// All returns all entries of the tenant's custom dictionary, sorted by word.
+func (m *Memory) All(tenant string) ([]Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t := m.tenants[tenant]
+	if t == nil {
+		return []Entry{}, nil
+	}
+	entries := make([]Entry, 0, len(t.words))
+	for _, e := range t.words {
+		entries = append(entries, e)
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Word < entries[j].Word })
+}
+
+// AddLexeme stores a lexeme under its lemma, replacing any previous forms.
+func (m *Memory) AddLexeme(tenant string, lx Lexeme) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tenant(tenant).lexemes[lx.Lemma] = lx
+	return nil
+}
+
+// RemoveLexeme deletes the lexeme with the given lemma and reports whether it existed.
+func (m *Memory) RemoveLexeme(tenant, lemma string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.tenants[tenant]
+	if t == nil {
+		return false, nil
+	}
+	_, ok := t.lexemes[lemma]
+	delete(t.lexemes, lemma)
+	return ok, nil
+}
+
+// Lexemes returns all lexemes stored for the tenant, sorted by lemma.
+func (m *Memory) Lexemes(tenant string) ([]Lexeme, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t := m.tenants[tenant]
+	if t == nil {
+		return []Lexeme{}, nil
+	}
+	lexemes := make([]Lexeme, 0, len(t.lexemes))
+	for _, lx := range t.lexemes {
+		lexemes = append(lexemes, lx)
+	}
+	sort.Slice(lexemes, func(i, j int) bool { return lexemes[i].Lemma < lexemes[j].Lemma })
+	return lexemes, nil
+}
+
+// AddUserWord inserts a word into the personal dictionary of a user of the tenant.
+func (m *Memory) AddUserWord(tenant, user, word string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.addUserWordLocked(tenant, user, word)
+	return nil
+}
+
+func (m *Memory) addUserWordLocked(tenant, user, word string) {
+	t := m.tenant(tenant)
+	words := t.users[user]
+	if words == nil {
+		words = make(map[string]struct{})
+		t.users[user] = words
+	}
+	words[word] = struct{}{}
+}
+
+// RemoveUserWord deletes a word from the user's personal dictionary and reports whether it existed.
+func (m *Memory) RemoveUserWord(tenant, user, word string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.removeUserWordLocked(tenant, user, word), nil
+}
+
+func (m *Memory) removeUserWordLocked(tenant, user, word string) bool {
+	t := m.tenants[tenant]
+	if t == nil {
+		return false
+	}
+	words := t.users[user]
+	_, ok := words[word]
+	delete(words, word)
+	if len(words) == 0 {
+		delete(t.users, user)
+	}
+	return ok
+}
+
+// UserWords returns all words of the user's personal dictionary, sorted.
+func (m *Memory) UserWords(tenant, user string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var set map[string]struct{}
+	if t := m.tenants[tenant]; t != nil {
+		set = t.users[user]
+	}
+	words := make([]string, 0, len(set))
+	for w := range set {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words, nil
+}
+
+// Block adds a word to the tenant's blocklist.
+func (m *Memory) Block(tenant, word string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tenant(tenant).blocked[word] = struct{}{}
+	return nil
+}
+
+// Unblock deletes a word from the tenant's blocklist and reports whether it was there.
+func (m *Memory) Unblock(tenant, word string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.unblockLocked(tenant, word), nil
+}
+
+func (m *Memory) unblockLocked(tenant, word string) bool {
+	t := m.tenants[tenant]
+	if t == nil {
+		return false
+	}
+	_, ok := t.blocked[word]
+	delete(t.blocked, word)
+	return ok
+}
+
+// Blocked returns all words of the tenant's blocklist, sorted.
+func (m *Memory) Blocked(tenant string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var set map[string]struct{}
+	if t := m.tenants[tenant]; t != nil {
+		set = t.blocked
+	}
+	words := make([]string, 0, len(set))
+	for w := range set {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words, nil
+}
+
+// AddRule stores a replacement rule of the tenant, replacing the rule with the same From.
+func (m *Memory) AddRule(tenant string, r Rule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tenant(tenant).rules[r.From] = r
+	return nil
+}
+
+// RemoveRule deletes the tenant's rule for from and reports whether it existed.
+func (m *Memory) RemoveRule(tenant, from string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.removeRuleLocked(tenant, from), nil
+}
+
+func (m *Memory) removeRuleLocked(tenant, from string) bool {
+	t := m.tenants[tenant]
+	if t == nil {
+		return false
+	}
+	_, ok := t.rules[from]
+	delete(t.rules, from)
+	return ok
+}
+
+// Rules returns all replacement rules of the tenant, sorted by From.
+func (m *Memory) Rules(tenant string) ([]Rule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t := m.tenants[tenant]
+	if t == nil {
+		return []Rule{}, nil
+	}
+	rules := make([]Rule, 0, len(t.rules))
+	for _, r := range t.rules {
+		rules = append(rules, r)
+	}
+	sortRules(rules)
+	return rules, nil
+}
+
+func sortRules(rules []Rule) {
+	sort.Slice(rules, func(i, j int) bool { return rules[i].From < rules[j].From })
+}
+
+// AddVote stores a user's feedback on a suggestion, replacing the voter's previous vote on the same pair.
+func (m *Memory) AddVote(tenant string, v Vote) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tenant(tenant).votes[v.key()] = v
+	return nil
+}
+
+// Votes returns all feedback votes of the tenant, sorted by pair and voter.
+func (m *Memory) Votes(tenant string) ([]Vote, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t := m.tenants[tenant]
+	if t == nil {
+		return []Vote{}, nil
+	}
+	votes := make([]Vote, 0, len(t.votes))
+	for _, v := range t.votes {
+		votes = append(votes, v)
+	}
+	sortVotes(votes)
+	return votes, nil
+}
+
+func sortVotes(votes []Vote) {
+	sort.Slice(votes, func(i, j int) bool { return votes[i].key() < votes[j].key() })
+}
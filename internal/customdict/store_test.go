@@ -0,0 +1,75 @@
+package customdict
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// TestStores runs the same checks against every Store that needs no server.
+// The corrector tests use Memory, so it must behave like the persistent stores.
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(*testing.T) Store { return NewMemory() },
+		"file": func(t *testing.T) Store {
+			f, err := NewFile(filepath.Join(t.TempDir(), "custom.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return f
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+			words := func(tenant string) []string {
+				t.Helper()
+				entries, err := s.All(tenant)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var out []string
+				for _, e := range entries {
+					out = append(out, e.Word)
+				}
+				sort.Strings(out)
+				return out
+			}
+
+			if err := s.Add(DefaultTenant, Entry{Word: "зиплайн", Frequency: 500, Note: "brand"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.AddAll(DefaultTenant, []Entry{{Word: "вайб"}, {Word: "кринж"}}); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := words(DefaultTenant), []string{"вайб", "зиплайн", "кринж"}; !slices.Equal(got, want) {
+				t.Errorf("All = %q, want %q", got, want)
+			}
+
+			// Add replaces the entry for the same word
+			if err := s.Add(DefaultTenant, Entry{Word: "зиплайн", Frequency: 700}); err != nil {
+				t.Fatal(err)
+			}
+			entries, _ := s.All(DefaultTenant)
+			for _, e := range entries {
+				if e.Word == "зиплайн" && (e.Frequency != 700 || e.Note != "") {
+					t.Errorf("replaced entry = %+v", e)
+				}
+			}
+
+			if err := s.Remove(DefaultTenant, "вайб"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Remove(DefaultTenant, "нет такого"); err != nil {
+				t.Errorf("Remove of a missing word: %v", err)
+			}
+			if got, want := words(DefaultTenant), []string{"зиплайн", "кринж"}; !slices.Equal(got, want) {
+				t.Errorf("All after Remove = %q, want %q", got, want)
+			}
+			if got := words("acme"); len(got) != 0 {
+				t.Errorf("All(acme) = %q, want empty", got)
+			}
+		})
+	}
+}
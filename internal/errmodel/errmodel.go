@@ -0,0 +1,238 @@
+// Package errmodel содержит модель ошибок набора: стоимости посимвольных правок
+// (замен, вставок, пропусков и перестановок), оцененные по корпусу пар
+// "опечатка -> исправление". Модель обучается командой errtrain (см. Trainer)
+// и заменяет в корректоре ручные таблицы стоимостей.
+//
+// Стоимости выражены в тех же единицах, что и ручные таблицы корректора: 1 - типичная
+// правка, частые ошибки дешевле, редкие дороже. Стоимость правки равна -ln P(правка),
+// деленному на -ln средней вероятности правки одного символа в корпусе, и ограничена
+// отрезком [MinCost, MaxCost].
+package errmodel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// Start - условный символ перед первой буквой слова в контексте вставок и пропусков.
+const Start = '^'
+
+const (
+	// MinCost и MaxCost ограничивают стоимость одной правки.
+	MinCost = 0.05
+	MaxCost = 3.0
+)
+
+// Model - обученная модель ошибок. Ключи таблиц - пары символов (см. методы).
+// Model неизменяема после загрузки и безопасна для конкурентного чтения.
+type Model struct {
+	sub      map[[2]rune]float64 // задуманный, набранный
+	subOther map[rune]float64    // задуманный -> любая не встречавшаяся замена
+	del      map[[2]rune]float64 // предыдущий, пропущенный
+	delAny   map[rune]float64    // пропущенный символ без учета контекста
+	ins      map[[2]rune]float64 // предыдущий задуманный, вставленный
+	insOther map[rune]float64    // предыдущий задуманный -> любая не встречавшаяся вставка
+	insAny   map[rune]float64    // вставленный символ без учета контекста
+	trans    map[[2]rune]float64 // задуманная пара, набранная в обратном порядке
+	def      Defaults
+	// Pairs - число пар корпуса, по которым обучена модель.
+	Pairs int
+}
+
+// Defaults - стоимости правок символов, которых не было в корпусе.
+type Defaults struct {
+	Substitution  float64 `json:"substitution"`
+	Deletion      float64 `json:"deletion"`
+	Insertion     float64 `json:"insertion"`
+	Transposition float64 `json:"transposition"`
+}
+
+// Substitution - стоимость набора typed вместо intended.
+func (m *Model) Substitution(intended, typed rune) float64 {
+	if c, ok := m.sub[[2]rune{intended, typed}]; ok {
+		return c
+	}
+	if c, ok := m.subOther[intended]; ok {
+		return c
+	}
+	return m.def.Substitution
+}
+
+// Deletion - стоимость пропуска символа intended после prev (Start в начале слова).
+func (m *Model) Deletion(prev, intended rune) float64 {
+	if c, ok := m.del[[2]rune{prev, intended}]; ok {
+		return c
+	}
+	if c, ok := m.delAny[intended]; ok {
+		return c
+	}
+	return m.def.Deletion
+}
+
+// Insertion - стоимость лишнего символа typed после задуманного prev (Start в начале слова).
+func (m *Model) Insertion(prev, typed rune) float64 {
+	if c, ok := m.ins[[2]rune{prev, typed}]; ok {
+		return c
+	}
+	if c, ok := m.insOther[prev]; ok {
+		return c
+	}
+	if c, ok := m.insAny[typed]; ok {
+		return c
+	}
+	return m.def.Insertion
+}
+
+// Transposition - стоимость набора задуманной пары ab как ba.
+func (m *Model) Transposition(a, b rune) float64 {
+	if c, ok := m.trans[[2]rune{a, b}]; ok {
+		return c
+	}
+	return m.def.Transposition
+}
+
+// fileModel - формат файла модели: ключи таблиц - строки из одного или двух символов.
+type fileModel struct {
+	Version       int                `json:"version"`
+	Pairs         int                `json:"pairs"`
+	Substitution  map[string]float64 `json:"substitution"`
+	SubOther      map[string]float64 `json:"substitution_other"`
+	Deletion      map[string]float64 `json:"deletion"`
+	DeletionAny   map[string]float64 `json:"deletion_any"`
+	Insertion     map[string]float64 `json:"insertion"`
+	InsOther      map[string]float64 `json:"insertion_other"`
+	InsertionAny  map[string]float64 `json:"insertion_any"`
+	Transposition map[string]float64 `json:"transposition"`
+	Default       Defaults           `json:"default"`
+}
+
+const fileVersion = 1
+
+// Load читает модель из файла.
+func Load(path string) (*Model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("модель ошибок %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Read читает модель в формате JSON.
+func Read(r io.Reader) (*Model, error) {
+	var fm fileModel
+	if err := json.NewDecoder(r).Decode(&fm); err != nil {
+		return nil, err
+	}
+	if fm.Version != fileVersion {
+		return nil, fmt.Errorf("неподдерживаемая версия %d", fm.Version)
+	}
+	m := &Model{def: fm.Default, Pairs: fm.Pairs}
+	var err error
+	if m.sub, err = pairTable(fm.Substitution); err != nil {
+		return nil, fmt.Errorf("substitution: %w", err)
+	}
+	if m.del, err = pairTable(fm.Deletion); err != nil {
+		return nil, fmt.Errorf("deletion: %w", err)
+	}
+	if m.ins, err = pairTable(fm.Insertion); err != nil {
+		return nil, fmt.Errorf("insertion: %w", err)
+	}
+	if m.trans, err = pairTable(fm.Transposition); err != nil {
+		return nil, fmt.Errorf("transposition: %w", err)
+	}
+	if m.subOther, err = charTable(fm.SubOther); err != nil {
+		return nil, fmt.Errorf("substitution_other: %w", err)
+	}
+	if m.delAny, err = charTable(fm.DeletionAny); err != nil {
+		return nil, fmt.Errorf("deletion_any: %w", err)
+	}
+	if m.insOther, err = charTable(fm.InsOther); err != nil {
+		return nil, fmt.Errorf("insertion_other: %w", err)
+	}
+	if m.insAny, err = charTable(fm.InsertionAny); err != nil {
+		return nil, fmt.Errorf("insertion_any: %w", err)
+	}
+	return m, nil
+}
+
+// Save записывает модель в файл.
+func (m *Model) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := m.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Write записывает модель в формате JSON.
+func (m *Model) Write(w io.Writer) error {
+	fm := fileModel{
+		Version:       fileVersion,
+		Pairs:         m.Pairs,
+		Substitution:  pairKeys(m.sub),
+		SubOther:      charKeys(m.subOther),
+		Deletion:      pairKeys(m.del),
+		DeletionAny:   charKeys(m.delAny),
+		Insertion:     pairKeys(m.ins),
+		InsOther:      charKeys(m.insOther),
+		InsertionAny:  charKeys(m.insAny),
+		Transposition: pairKeys(m.trans),
+		Default:       m.def,
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(fm)
+}
+
+func pairTable(in map[string]float64) (map[[2]rune]float64, error) {
+	out := make(map[[2]rune]float64, len(in))
+	for k, v := range in {
+		a, n := utf8.DecodeRuneInString(k)
+		b, m := utf8.DecodeRuneInString(k[n:])
+		if n == 0 || m == 0 || n+m != len(k) {
+			return nil, fmt.Errorf("ключ %q: ожидается пара символов", k)
+		}
+		out[[2]rune{a, b}] = v
+	}
+	return out, nil
+}
+
+func charTable(in map[string]float64) (map[rune]float64, error) {
+	out := make(map[rune]float64, len(in))
+	for k, v := range in {
+		r, n := utf8.DecodeRuneInString(k)
+		if n == 0 || n != len(k) {
+			return nil, fmt.Errorf("ключ %q: ожидается один символ", k)
+		}
+		out[r] = v
+	}
+	return out, nil
+}
+
+func pairKeys(in map[[2]rune]float64) map[string]float64 {
+	out := make(map[string]float64, len(in))
+	for k, v := range in {
+		out[string(k[:])] = v
+	}
+	return out
+}
+
+func charKeys(in map[rune]float64) map[string]float64 {
+	out := make(map[string]float64, len(in))
+	for k, v := range in {
+		out[string(k)] = v
+	}
+	return out
+}
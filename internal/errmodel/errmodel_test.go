@@ -0,0 +1,58 @@
+package errmodel
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestWriteRead проверяет, что записанная модель читается обратно без изменений.
+func TestWriteRead(t *testing.T) {
+	tr := NewTrainer()
+	tr.MinContextCount = 2
+	for _, p := range [][2]string{
+		{"кат", "кот"}, {"кат", "кот"}, {"кт", "кот"}, {"окт", "кот"},
+		{"скот", "кот"}, {"дим", "дом"}, {"дом", "дом"}, {"ёж", "еж"},
+	} {
+		tr.Add(p[0], p[1], 1)
+	}
+	m, err := tr.Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Pairs != m.Pairs || got.def != m.def {
+		t.Errorf("прочитано: %d пар, %+v; ожидается %d, %+v", got.Pairs, got.def, m.Pairs, m.def)
+	}
+	runes := []rune{Start, 'к', 'о', 'т', 'а', 'д', 'и', 'м', 'с', 'е', 'ё', 'ж', 'я'}
+	for _, a := range runes {
+		for _, b := range runes {
+			if got.Substitution(a, b) != m.Substitution(a, b) ||
+				got.Deletion(a, b) != m.Deletion(a, b) ||
+				got.Insertion(a, b) != m.Insertion(a, b) ||
+				got.Transposition(a, b) != m.Transposition(a, b) {
+				t.Errorf("стоимости правок %q, %q изменились после записи и чтения", a, b)
+			}
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, in := range []string{
+		`{"version": 2}`,
+		`{"version": 1, "substitution": {"абв": 1}}`,
+		`{"version": 1, "deletion_any": {"аб": 1}}`,
+		`не JSON`,
+	} {
+		if _, err := Read(strings.NewReader(in)); err == nil {
+			t.Errorf("Read(%s) без ошибки", in)
+		}
+	}
+}
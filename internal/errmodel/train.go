@@ -0,0 +1,277 @@
+package errmodel
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// OpKind - вид посимвольной правки в выравнивании опечатки с исправлением.
+type OpKind int
+
+const (
+	Match OpKind = iota
+	Substitute
+	Insert    // в опечатке лишний символ Typed
+	Delete    // в опечатке пропущен символ Intended
+	Transpose // задуманная пара Intended, Typed набрана в обратном порядке
+)
+
+// Op - шаг выравнивания. Prev - предыдущий задуманный символ (Start в начале слова);
+// для Transpose Intended и Typed - первый и второй символы задуманной пары.
+type Op struct {
+	Kind     OpKind
+	Prev     rune
+	Intended rune
+	Typed    rune
+}
+
+// Align выравнивает опечатку с исправлением по расстоянию Дамерау-Левенштейна
+// (с ограничением на соседние перестановки) и возвращает правки в порядке слева направо.
+// Из равноценных выравниваний выбирается то, где совпадения и замены идут раньше вставок и пропусков.
+func Align(typo, correct string) []Op {
+	a, b := []rune(typo), []rune(correct)
+	d := editTable(a, b)
+	var ops []Op
+	prev := func(j int) rune {
+		if j == 0 {
+			return Start
+		}
+		return b[j-1]
+	}
+	i, j := len(a), len(b)
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && a[i-1] == b[j-1] && d[i][j] == d[i-1][j-1]:
+			ops = append(ops, Op{Kind: Match, Prev: prev(j - 1), Intended: b[j-1], Typed: a[i-1]})
+			i, j = i-1, j-1
+		case i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && a[i-1] != a[i-2] && d[i][j] == d[i-2][j-2]+1:
+			ops = append(ops, Op{Kind: Transpose, Prev: prev(j - 2), Intended: b[j-2], Typed: b[j-1]})
+			i, j = i-2, j-2
+		case i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+1:
+			ops = append(ops, Op{Kind: Substitute, Prev: prev(j - 1), Intended: b[j-1], Typed: a[i-1]})
+			i, j = i-1, j-1
+		case j > 0 && d[i][j] == d[i][j-1]+1:
+			ops = append(ops, Op{Kind: Delete, Prev: prev(j - 1), Intended: b[j-1]})
+			j--
+		default:
+			ops = append(ops, Op{Kind: Insert, Prev: prev(j), Typed: a[i-1]})
+			i--
+		}
+	}
+	for l, r := 0, len(ops)-1; l < r; l, r = l+1, r-1 {
+		ops[l], ops[r] = ops[r], ops[l]
+	}
+	return ops
+}
+
+// editTable - таблица расстояний Дамерау-Левенштейна между префиксами a и b.
+func editTable(a, b []rune) [][]int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d
+}
+
+// Trainer накапливает статистику правок по парам "опечатка -> исправление" и строит Model.
+type Trainer struct {
+	// Smoothing - аддитивное сглаживание счетчиков (псевдосчет каждой правки).
+	Smoothing float64
+	// MinContextCount - сколько раз символ или пара символов должны встретиться
+	// в исправлениях, чтобы вероятность правки оценивалась с учетом контекста.
+	MinContextCount float64
+	// MaxDistance - пары с большим числом правок считаются шумом и пропускаются.
+	MaxDistance int
+
+	pairs    int
+	total    float64 // символов в исправлениях
+	chars    map[rune]float64
+	bigrams  map[[2]rune]float64
+	alphabet map[rune]bool
+	sub      map[[2]rune]float64
+	del      map[[2]rune]float64
+	delAny   map[rune]float64
+	ins      map[[2]rune]float64
+	insAny   map[rune]float64
+	trans    map[[2]rune]float64
+	edits    [5]float64 // по OpKind
+}
+
+// NewTrainer создает Trainer с настройками по умолчанию.
+func NewTrainer() *Trainer {
+	return &Trainer{
+		Smoothing:       0.5,
+		MinContextCount: 20,
+		MaxDistance:     3,
+		chars:           make(map[rune]float64),
+		bigrams:         make(map[[2]rune]float64),
+		alphabet:        make(map[rune]bool),
+		sub:             make(map[[2]rune]float64),
+		del:             make(map[[2]rune]float64),
+		delAny:          make(map[rune]float64),
+		ins:             make(map[[2]rune]float64),
+		insAny:          make(map[rune]float64),
+		trans:           make(map[[2]rune]float64),
+	}
+}
+
+// Add учитывает пару с весом weight (число повторов пары в корпусе).
+// Слова приводятся к нижнему регистру. Возвращает false, если пара пропущена:
+// пустое слово или больше MaxDistance правок.
+func (t *Trainer) Add(typo, correct string, weight float64) bool {
+	typo, correct = strings.ToLower(typo), strings.ToLower(correct)
+	if typo == "" || correct == "" || weight <= 0 {
+		return false
+	}
+	ops := Align(typo, correct)
+	n := 0
+	for _, op := range ops {
+		if op.Kind != Match {
+			n++
+		}
+	}
+	if n > t.MaxDistance {
+		return false
+	}
+
+	t.pairs++
+	t.chars[Start] += weight
+	prev := Start
+	for _, r := range correct {
+		t.total += weight
+		t.chars[r] += weight
+		t.bigrams[[2]rune{prev, r}] += weight
+		t.alphabet[r] = true
+		prev = r
+	}
+	for _, r := range typo {
+		t.alphabet[r] = true
+	}
+	for _, op := range ops {
+		t.edits[op.Kind] += weight
+		switch op.Kind {
+		case Substitute:
+			t.sub[[2]rune{op.Intended, op.Typed}] += weight
+		case Delete:
+			t.del[[2]rune{op.Prev, op.Intended}] += weight
+			t.delAny[op.Intended] += weight
+		case Insert:
+			t.ins[[2]rune{op.Prev, op.Typed}] += weight
+			t.insAny[op.Typed] += weight
+		case Transpose:
+			t.trans[[2]rune{op.Intended, op.Typed}] += weight
+		}
+	}
+	return true
+}
+
+// Pairs возвращает число учтенных пар.
+func (t *Trainer) Pairs() int { return t.pairs }
+
+// ErrNoEdits возвращается, если в учтенных парах нет ни одной правки.
+var ErrNoEdits = errors.New("в корпусе нет ни одной правки")
+
+// Model оценивает вероятности правок и переводит их в стоимости.
+//
+// Замена: P(набран t | задуман c) по счетчикам замен символа c.
+// Пропуск: P(пропущен c | после p) по паре pc, если она встречалась не реже
+// MinContextCount раз, иначе P(пропущен c) по символу.
+// Вставка: P(лишний t | после задуманного p) по символу p, если он достаточно частый,
+// иначе P(лишний t) по всем позициям.
+// Перестановка: P(пара cd набрана как dc) по паре cd, если она достаточно частая,
+// иначе общая доля перестановок.
+func (t *Trainer) Model() (*Model, error) {
+	var edits float64
+	for k, n := range t.edits {
+		if OpKind(k) != Match {
+			edits += n
+		}
+	}
+	if edits == 0 || t.total == 0 {
+		return nil, ErrNoEdits
+	}
+	unit := -math.Log(edits / t.total)
+	if unit < 1 {
+		// Правка почти в каждом символе: масштаб по средней правке теряет смысл
+		unit = 1
+	}
+	cost := func(p float64) float64 {
+		return math.Max(MinCost, math.Min(MaxCost, -math.Log(p)/unit))
+	}
+	a := t.Smoothing
+	k := float64(len(t.alphabet))
+	positions := t.total + t.chars[Start] // места для вставки: после каждого символа и в начале слова
+
+	m := &Model{
+		sub:      make(map[[2]rune]float64),
+		subOther: make(map[rune]float64),
+		del:      make(map[[2]rune]float64),
+		delAny:   make(map[rune]float64),
+		ins:      make(map[[2]rune]float64),
+		insOther: make(map[rune]float64),
+		insAny:   make(map[rune]float64),
+		trans:    make(map[[2]rune]float64),
+		Pairs:    t.pairs,
+	}
+	for key, n := range t.sub {
+		m.sub[key] = cost((n + a) / (t.chars[key[0]] + a*k))
+	}
+	for c, n := range t.chars {
+		if c == Start {
+			continue
+		}
+		m.subOther[c] = cost(a / (n + a*k))
+		m.delAny[c] = cost((t.delAny[c] + a) / (n + 2*a))
+	}
+	var withinWord float64
+	for key, n := range t.bigrams {
+		if key[0] != Start {
+			withinWord += n
+		}
+		if n < t.MinContextCount {
+			continue
+		}
+		m.del[key] = cost((t.del[key] + a) / (n + 2*a))
+		if key[0] != Start && key[0] != key[1] {
+			m.trans[key] = cost((t.trans[key] + a) / (n + 2*a))
+		}
+	}
+	for p, n := range t.chars {
+		if n < t.MinContextCount {
+			continue
+		}
+		m.insOther[p] = cost(a / (n + a*k))
+	}
+	for key, n := range t.ins {
+		if _, ok := m.insOther[key[0]]; ok {
+			m.ins[key] = cost((n + a) / (t.chars[key[0]] + a*k))
+		}
+	}
+	for r := range t.alphabet {
+		m.insAny[r] = cost((t.insAny[r] + a) / (positions + a*k))
+	}
+	m.def = Defaults{
+		Substitution:  cost((t.edits[Substitute] + a) / (t.total + a) / k),
+		Deletion:      cost((t.edits[Delete] + a) / (t.total + 2*a)),
+		Insertion:     cost((t.edits[Insert] + a) / (positions + 2*a) / k),
+		Transposition: cost((t.edits[Transpose] + a) / (withinWord + 2*a)),
+	}
+	return m, nil
+}
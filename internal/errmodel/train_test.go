@@ -0,0 +1,106 @@
+package errmodel
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestAlign(t *testing.T) {
+	tests := []struct {
+		typo, correct string
+		want          []Op
+	}{
+		{"кот", "кот", []Op{
+			{Kind: Match, Prev: Start, Intended: 'к', Typed: 'к'},
+			{Kind: Match, Prev: 'к', Intended: 'о', Typed: 'о'},
+			{Kind: Match, Prev: 'о', Intended: 'т', Typed: 'т'},
+		}},
+		{"кат", "кот", []Op{
+			{Kind: Match, Prev: Start, Intended: 'к', Typed: 'к'},
+			{Kind: Substitute, Prev: 'к', Intended: 'о', Typed: 'а'},
+			{Kind: Match, Prev: 'о', Intended: 'т', Typed: 'т'},
+		}},
+		{"кошт", "кот", []Op{
+			{Kind: Match, Prev: Start, Intended: 'к', Typed: 'к'},
+			{Kind: Match, Prev: 'к', Intended: 'о', Typed: 'о'},
+			{Kind: Insert, Prev: 'о', Typed: 'ш'},
+			{Kind: Match, Prev: 'о', Intended: 'т', Typed: 'т'},
+		}},
+		// Лишний символ в начале слова: контекст - Start
+		{"скот", "кот", []Op{
+			{Kind: Insert, Prev: Start, Typed: 'с'},
+			{Kind: Match, Prev: Start, Intended: 'к', Typed: 'к'},
+			{Kind: Match, Prev: 'к', Intended: 'о', Typed: 'о'},
+			{Kind: Match, Prev: 'о', Intended: 'т', Typed: 'т'},
+		}},
+		{"кт", "кот", []Op{
+			{Kind: Match, Prev: Start, Intended: 'к', Typed: 'к'},
+			{Kind: Delete, Prev: 'к', Intended: 'о'},
+			{Kind: Match, Prev: 'о', Intended: 'т', Typed: 'т'},
+		}},
+		// Перестановка - одна правка, а не две замены
+		{"окт", "кот", []Op{
+			{Kind: Transpose, Prev: Start, Intended: 'к', Typed: 'о'},
+			{Kind: Match, Prev: 'о', Intended: 'т', Typed: 'т'},
+		}},
+		{"", "да", []Op{
+			{Kind: Delete, Prev: Start, Intended: 'д'},
+			{Kind: Delete, Prev: 'д', Intended: 'а'},
+		}},
+	}
+	for _, tt := range tests {
+		if got := Align(tt.typo, tt.correct); !slices.Equal(got, tt.want) {
+			t.Errorf("Align(%q, %q) = %+v, ожидается %+v", tt.typo, tt.correct, got, tt.want)
+		}
+	}
+}
+
+func TestTrainer(t *testing.T) {
+	tr := NewTrainer()
+	tr.MinContextCount = 5
+	for range 10 {
+		tr.Add("кат", "кот", 1)
+		tr.Add("окно", "окно", 1)
+	}
+	tr.Add("кт", "кот", 3)
+	tr.Add("окт", "кот", 1)
+	if tr.Add("", "кот", 1) || tr.Add("абвгд", "кот", 1) {
+		t.Error("учтена пустая пара или пара с числом правок больше MaxDistance")
+	}
+	if tr.Pairs() != 22 {
+		t.Errorf("Pairs = %d, ожидается 22", tr.Pairs())
+	}
+	m, err := tr.Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Частая правка дешевле редкой, редкая - дешевле не встречавшейся
+	if sub, other := m.Substitution('о', 'а'), m.Substitution('о', 'у'); sub >= other {
+		t.Errorf("замена о->а (%.3f) не дешевле не встречавшейся о->у (%.3f)", sub, other)
+	}
+	if del, other := m.Deletion('к', 'о'), m.Deletion('к', 'н'); del >= other {
+		t.Errorf("пропуск о после к (%.3f) не дешевле пропуска н (%.3f)", del, other)
+	}
+	if tp, other := m.Transposition('к', 'о'), m.Transposition('н', 'о'); tp >= other {
+		t.Errorf("перестановка ко (%.3f) не дешевле не встречавшейся но (%.3f)", tp, other)
+	}
+	for _, c := range []float64{
+		m.Substitution('о', 'а'), m.Substitution('я', 'ю'),
+		m.Deletion('к', 'о'), m.Deletion(Start, 'я'),
+		m.Insertion('о', 'ш'), m.Insertion(Start, 'я'),
+		m.Transposition('к', 'о'), m.Transposition('я', 'ю'),
+	} {
+		if c < MinCost || c > MaxCost {
+			t.Errorf("стоимость %.3f вне [%v, %v]", c, MinCost, MaxCost)
+		}
+	}
+
+	// Корпус без правок
+	empty := NewTrainer()
+	empty.Add("кот", "кот", 1)
+	if _, err := empty.Model(); !errors.Is(err, ErrNoEdits) {
+		t.Errorf("Model без правок: %v, ожидается ErrNoEdits", err)
+	}
+}
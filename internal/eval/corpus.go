@@ -0,0 +1,77 @@
+// Package eval оценивает качество корректора на размеченном корпусе: для каждого
+// предложения известен правильный вариант, по которому считаются точность и полнота
+// обнаружения и исправления ошибок на уровне слов, доля ложных исправлений в чистом
+// тексте и разбивка по типам ошибок. Отчет (Report) сериализуется в JSON с устойчивым
+// порядком полей, чтобы отчеты разных прогонов можно было сравнивать diff'ом.
+//
+// Пакет используется командой cmd/eval и подбором параметров (cmd/tune); пороги
+// качества в тестах проверяет evaltest.Check.
+package eval
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Sample - предложение корпуса и его правильный вариант.
+type Sample struct {
+	Original string `json:"original"`
+	Expected string `json:"expected"`
+	// Type - тип ошибки из разметки; если пуст, тип каждой ошибки определяется по правке.
+	Type string `json:"type,omitempty"`
+}
+
+// Clean сообщает, что в предложении нет ошибок.
+func (s Sample) Clean() bool { return s.Original == s.Expected }
+
+// ReadCorpusFile читает корпус из файла (см. ReadCorpus).
+func ReadCorpusFile(path string) ([]Sample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	samples, err := ReadCorpus(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return samples, nil
+}
+
+// ReadCorpus читает корпус в формате TSV: исходное предложение, правильный вариант
+// и необязательный тип ошибки через табуляцию. Пустой правильный вариант означает
+// чистое предложение без ошибок. Пустые строки и строки с '#' пропускаются.
+//
+//	превет, как дила?	привет, как дела?
+//	мама мыла раму
+//	сдесь	здесь	phonetic
+func ReadCorpus(r io.Reader) ([]Sample, error) {
+	var samples []Sample
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for s.Scan() {
+		lineNo++
+		line := strings.TrimRight(s.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("строка %d: ожидается \"исходное[<TAB>правильное[<TAB>тип]]\"", lineNo)
+		}
+		sample := Sample{Original: strings.TrimSpace(fields[0])}
+		sample.Expected = sample.Original
+		if len(fields) > 1 && strings.TrimSpace(fields[1]) != "" {
+			sample.Expected = strings.TrimSpace(fields[1])
+		}
+		if len(fields) > 2 {
+			sample.Type = strings.TrimSpace(fields[2])
+		}
+		samples = append(samples, sample)
+	}
+	return samples, s.Err()
+}
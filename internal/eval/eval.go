@@ -0,0 +1,289 @@
+package eval
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"corrector/internal/errmodel"
+)
+
+// CorrectFunc исправляет текст, например
+//
+//	func(text string) string { return sc.CorrectText(text, false).Corrected }
+type CorrectFunc func(text string) string
+
+// Options - настройки оценки.
+type Options struct {
+	// MaxExamples - сколько ошибочно обработанных предложений включать в отчет (0 - ни одного).
+	MaxExamples int
+}
+
+// Counts - матрица ошибок и производные метрики.
+type Counts struct {
+	TP        int     `json:"tp"`
+	FP        int     `json:"fp"`
+	FN        int     `json:"fn"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F05       float64 `json:"f0_5"`
+}
+
+// finish вычисляет точность, полноту и F0.5 (точность вдвое важнее полноты).
+// При отсутствии срабатываний точность считается равной 1, при отсутствии ошибок - полнота.
+func (c *Counts) finish() {
+	c.Precision, c.Recall = 1, 1
+	if c.TP+c.FP > 0 {
+		c.Precision = float64(c.TP) / float64(c.TP+c.FP)
+	}
+	if c.TP+c.FN > 0 {
+		c.Recall = float64(c.TP) / float64(c.TP+c.FN)
+	}
+	c.F05 = 0
+	if d := 0.25*c.Precision + c.Recall; d > 0 {
+		c.F05 = 1.25 * c.Precision * c.Recall / d
+	}
+	c.Precision, c.Recall, c.F05 = round(c.Precision), round(c.Recall), round(c.F05)
+}
+
+// CleanStats - ложные исправления в предложениях без ошибок.
+type CleanStats struct {
+	Sentences        int     `json:"sentences"`
+	ChangedSentences int     `json:"changed_sentences"`
+	Tokens           int     `json:"tokens"`
+	ChangedTokens    int     `json:"changed_tokens"`
+	FalsePositive    float64 `json:"false_positive_rate"`
+}
+
+// TypeStats - результаты по одному типу ошибок.
+type TypeStats struct {
+	Errors    int     `json:"errors"`
+	Detected  int     `json:"detected"`
+	Corrected int     `json:"corrected"`
+	Recall    float64 `json:"recall"`
+}
+
+// Example - предложение, обработанное с ошибкой.
+type Example struct {
+	Original  string `json:"original"`
+	Expected  string `json:"expected"`
+	Corrected string `json:"corrected"`
+}
+
+// Report - результаты оценки на корпусе.
+type Report struct {
+	Sentences int `json:"sentences"`
+	Tokens    int `json:"tokens"`
+	Errors    int `json:"errors"`
+	// Detection - обнаружение: слово изменено там, где была ошибка.
+	Detection Counts `json:"detection"`
+	// Correction - исправление: слово заменено правильным вариантом.
+	Correction Counts `json:"correction"`
+	// SentenceAccuracy - доля предложений, исправленных в точности как в разметке.
+	SentenceAccuracy float64              `json:"sentence_accuracy"`
+	Clean            CleanStats           `json:"clean"`
+	ByType           map[string]TypeStats `json:"by_type"`
+	Examples         []Example            `json:"examples,omitempty"`
+}
+
+// Evaluate прогоняет корпус через correct и считает метрики на уровне слов.
+// Сравнение слов не зависит от регистра; знаки препинания не учитываются.
+func Evaluate(correct CorrectFunc, samples []Sample, opt Options) *Report {
+	rep := &Report{ByType: make(map[string]TypeStats)}
+	exact := 0
+	for _, s := range samples {
+		got := correct(s.Original)
+		orig := Words(s.Original)
+		gold := Align(orig, Words(s.Expected))
+		pred := Align(orig, Words(got))
+
+		rep.Sentences++
+		rep.Tokens += len(orig)
+		clean := s.Clean()
+		if clean {
+			rep.Clean.Sentences++
+			rep.Clean.Tokens += len(orig)
+		}
+		// Слово рядом со слиянием или разделением слов само считается частью этой ошибки
+		merged := func(i int) bool {
+			return i >= 0 && i < len(gold) && (gold[i] == "" || strings.Contains(gold[i], " "))
+		}
+		changedSentence, ok := false, true
+		for i, o := range orig {
+			isErr, changed, fixed := gold[i] != o, pred[i] != o, pred[i] == gold[i]
+			changedSentence = changedSentence || changed
+			ok = ok && fixed
+			if clean && changed {
+				rep.Clean.ChangedTokens++
+			}
+			if isErr {
+				rep.Errors++
+				typ := s.Type
+				switch {
+				case typ != "":
+				case merged(i - 1), merged(i + 1):
+					typ = "split_merge"
+				default:
+					typ = errorType(o, gold[i])
+				}
+				ts := rep.ByType[typ]
+				ts.Errors++
+				if changed {
+					ts.Detected++
+				}
+				if fixed {
+					ts.Corrected++
+				}
+				rep.ByType[typ] = ts
+			}
+			switch {
+			case isErr && changed:
+				rep.Detection.TP++
+			case changed:
+				rep.Detection.FP++
+			case isErr:
+				rep.Detection.FN++
+			}
+			switch {
+			case isErr && fixed:
+				rep.Correction.TP++
+			case changed && !fixed:
+				rep.Correction.FP++
+				if isErr {
+					rep.Correction.FN++
+				}
+			case isErr:
+				rep.Correction.FN++
+			}
+		}
+		if clean && changedSentence {
+			rep.Clean.ChangedSentences++
+		}
+		if ok {
+			exact++
+		} else if len(rep.Examples) < opt.MaxExamples {
+			rep.Examples = append(rep.Examples, Example{Original: s.Original, Expected: s.Expected, Corrected: got})
+		}
+	}
+	rep.Detection.finish()
+	rep.Correction.finish()
+	if rep.Sentences > 0 {
+		rep.SentenceAccuracy = round(float64(exact) / float64(rep.Sentences))
+	}
+	if rep.Clean.Tokens > 0 {
+		rep.Clean.FalsePositive = round(float64(rep.Clean.ChangedTokens) / float64(rep.Clean.Tokens))
+	}
+	for typ, ts := range rep.ByType {
+		ts.Recall = round(float64(ts.Corrected) / float64(ts.Errors))
+		rep.ByType[typ] = ts
+	}
+	return rep
+}
+
+var wordRe = regexp.MustCompile(`[А-Яа-яЁёA-Za-z]+|\d+`)
+
+// Words возвращает слова текста в нижнем регистре.
+func Words(text string) []string {
+	ws := wordRe.FindAllString(text, -1)
+	for i, w := range ws {
+		ws[i] = strings.ToLower(w)
+	}
+	return ws
+}
+
+// Align сопоставляет каждому слову a слово b по выравниванию Левенштейна на словах.
+// Слова b, вставленные между словами a, присоединяются через пробел к предыдущему
+// слову (или к первому, если вставка в начале); слово a без пары получает "".
+func Align(a, b []string) []string {
+	if len(a) == len(b) {
+		return b
+	}
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+		}
+	}
+	out := make([]string, len(a))
+	var pending []string // вставленные слова b, которые присоединяются к предыдущему слову a
+	for i, j := len(a), len(b); i > 0 || j > 0; {
+		switch {
+		case i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+boolInt(a[i-1] != b[j-1]):
+			out[i-1] = strings.Join(append([]string{b[j-1]}, pending...), " ")
+			pending = nil
+			i, j = i-1, j-1
+		case i > 0 && d[i][j] == d[i-1][j]+1:
+			out[i-1] = strings.Join(pending, " ")
+			pending = nil
+			i--
+		default:
+			pending = append([]string{b[j-1]}, pending...)
+			j--
+		}
+	}
+	if len(pending) > 0 && len(out) > 0 {
+		out[0] = strings.TrimSpace(strings.Join(pending, " ") + " " + out[0])
+	}
+	return out
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// errorType определяет тип ошибки по правке слова o в g: ё/е, перестановка, замена,
+// пропуск или лишняя буква (одна правка), несколько правок, слитное или раздельное написание.
+func errorType(o, g string) string {
+	switch {
+	case g == "" || strings.Contains(g, " "):
+		return "split_merge"
+	case strings.ReplaceAll(o, "ё", "е") == strings.ReplaceAll(g, "ё", "е"):
+		return "yo"
+	}
+	var kinds []errmodel.OpKind
+	for _, op := range errmodel.Align(o, g) {
+		if op.Kind != errmodel.Match {
+			kinds = append(kinds, op.Kind)
+		}
+	}
+	if len(kinds) != 1 {
+		return "multiple"
+	}
+	switch kinds[0] {
+	case errmodel.Substitute:
+		return "substitution"
+	case errmodel.Insert:
+		return "insertion"
+	case errmodel.Delete:
+		return "deletion"
+	default:
+		return "transposition"
+	}
+}
+
+func round(x float64) float64 { return math.Round(x*1e4) / 1e4 }
+
+// Types возвращает типы ошибок отчета в алфавитном порядке.
+func (r *Report) Types() []string {
+	types := make([]string, 0, len(r.ByType))
+	for t := range r.ByType {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,88 @@
+package eval
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAlign(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{"замена", "мама мила раму", "мама мыла раму", []string{"мама", "мыла", "раму"}},
+		{"слияние", "по этому не пришел", "поэтому не пришел", []string{"", "поэтому", "не", "пришел"}},
+		{"разделение", "вобщем ясно", "в общем ясно", []string{"в общем", "ясно"}},
+		{"вставка в начале", "мыла раму", "мама мыла раму", []string{"мама мыла", "раму"}},
+		{"вставка в начале одного слова", "привет", "ну привет", []string{"ну привет"}},
+		{"вставка в конце", "мама мыла", "мама мыла раму", []string{"мама", "мыла раму"}},
+		{"пропуск", "мама мыла раму", "мама раму", []string{"мама", "", "раму"}},
+		{"пустое", "", "мама", []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Align(strings.Fields(tc.a), strings.Fields(tc.b)); !slices.Equal(got, tc.want) {
+				t.Errorf("Align(%q, %q) = %q, ожидается %q", tc.a, tc.b, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestErrorType(t *testing.T) {
+	for _, tc := range []struct{ o, g, want string }{
+		{"ежик", "ёжик", "yo"},
+		{"дон", "дом", "substitution"},
+		{"кто", "кот", "transposition"},
+		{"доом", "дом", "insertion"},
+		{"дм", "дом", "deletion"},
+		{"дм", "дама", "multiple"},
+		{"вобщем", "в общем", "split_merge"},
+		{"по", "", "split_merge"},
+	} {
+		if got := errorType(tc.o, tc.g); got != tc.want {
+			t.Errorf("errorType(%q, %q) = %q, ожидается %q", tc.o, tc.g, got, tc.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	samples := []Sample{
+		{Original: "по этому не пришел", Expected: "поэтому не пришел"},
+		{Original: "вобщем ясно", Expected: "в общем ясно"},
+		{Original: "мама мила раму", Expected: "мама мыла раму"},
+		{Original: "мыла раму", Expected: "мыла раму"},
+	}
+	// Корректор исправляет первые два предложения, пропускает ошибку в третьем
+	// и дописывает слово в начало чистого четвертого
+	fixed := map[string]string{
+		"по этому не пришел": "поэтому не пришел",
+		"вобщем ясно":        "в общем ясно",
+		"мыла раму":          "мама мыла раму",
+	}
+	rep := Evaluate(func(text string) string {
+		if got, ok := fixed[text]; ok {
+			return got
+		}
+		return text
+	}, samples, Options{MaxExamples: 10})
+
+	if rep.Errors != 4 {
+		t.Errorf("ошибок %d, ожидается 4", rep.Errors)
+	}
+	if ts := rep.ByType["split_merge"]; ts.Errors != 3 || ts.Corrected != 3 {
+		t.Errorf("split_merge: %+v, ожидается 3 исправленных из 3", ts)
+	}
+	if ts := rep.ByType["substitution"]; ts.Errors != 1 || ts.Detected != 0 {
+		t.Errorf("substitution: %+v, ожидается 1 необнаруженная", ts)
+	}
+	if rep.Correction.TP != 3 || rep.Correction.FP != 1 || rep.Correction.FN != 1 {
+		t.Errorf("исправление: %+v", rep.Correction)
+	}
+	if rep.Clean.Sentences != 1 || rep.Clean.ChangedTokens != 1 || rep.Clean.FalsePositive != 0.5 {
+		t.Errorf("чистые предложения: %+v", rep.Clean)
+	}
+	if rep.SentenceAccuracy != 0.5 || len(rep.Examples) != 2 {
+		t.Errorf("точность по предложениям %v, примеров %d", rep.SentenceAccuracy, len(rep.Examples))
+	}
+}
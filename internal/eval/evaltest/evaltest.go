@@ -0,0 +1,39 @@
+// Package evaltest проверяет в тестах, что качество корректора на корпусе (eval.Report)
+// не ниже заданных порогов. Пакет импортирует testing и поэтому отделен от eval,
+// который входит в сборку команд.
+package evaltest
+
+import (
+	"testing"
+
+	"corrector/internal/eval"
+)
+
+// Thresholds - минимальное качество для Check; нулевые поля не проверяются.
+type Thresholds struct {
+	MinCorrectionF05       float64
+	MinCorrectionPrecision float64
+	MinDetectionRecall     float64
+	// MaxFalsePositiveRate - наибольшая доля измененных слов в чистых предложениях (0 - не проверять).
+	MaxFalsePositiveRate float64
+}
+
+// Check завершает тест с ошибкой, если отчет не удовлетворяет порогам:
+//
+//	rep := eval.Evaluate(correct, samples, eval.Options{})
+//	evaltest.Check(t, rep, evaltest.Thresholds{MinCorrectionF05: 0.7, MaxFalsePositiveRate: 0.01})
+func Check(tb testing.TB, rep *eval.Report, th Thresholds) {
+	tb.Helper()
+	if th.MinCorrectionF05 > 0 && rep.Correction.F05 < th.MinCorrectionF05 {
+		tb.Errorf("F0.5 исправления %.4f < %.4f", rep.Correction.F05, th.MinCorrectionF05)
+	}
+	if th.MinCorrectionPrecision > 0 && rep.Correction.Precision < th.MinCorrectionPrecision {
+		tb.Errorf("точность исправления %.4f < %.4f", rep.Correction.Precision, th.MinCorrectionPrecision)
+	}
+	if th.MinDetectionRecall > 0 && rep.Detection.Recall < th.MinDetectionRecall {
+		tb.Errorf("полнота обнаружения %.4f < %.4f", rep.Detection.Recall, th.MinDetectionRecall)
+	}
+	if th.MaxFalsePositiveRate > 0 && rep.Clean.FalsePositive > th.MaxFalsePositiveRate {
+		tb.Errorf("доля ложных исправлений %.4f > %.4f", rep.Clean.FalsePositive, th.MaxFalsePositiveRate)
+	}
+}
@@ -0,0 +1,237 @@
+// Package tune подбирает параметры скоринга корректора (corrector.Scoring) на размеченном
+// корпусе: покоординатный спуск по сетке значений каждого параметра, при желании
+// начинающийся с лучшей из нескольких случайных точек, и анализ чувствительности
+// метрики к каждому параметру вокруг найденного оптимума.
+package tune
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	sc "corrector/internal/corrector"
+	"corrector/internal/eval"
+)
+
+// Param - настраиваемый параметр: сетка значений от Min до Max с шагом Step.
+type Param struct {
+	Name           string
+	Min, Max, Step float64
+	field          func(s *sc.Scoring) *float64
+}
+
+// grid возвращает значения сетки параметра.
+func (p Param) grid() []float64 {
+	var out []float64
+	for v := p.Min; v <= p.Max+p.Step/2; v += p.Step {
+		out = append(out, math.Round(v*1e6)/1e6)
+	}
+	return out
+}
+
+// DefaultParams - параметры скоринга и их диапазоны по умолчанию.
+func DefaultParams() []Param {
+	return []Param{
+		{Name: "beta_weight", Min: 0.5, Max: 2, Step: 0.1, field: func(s *sc.Scoring) *float64 { return &s.BetaWeight }},
+		{Name: "lambda_penalty", Min: 0.3, Max: 2, Step: 0.1, field: func(s *sc.Scoring) *float64 { return &s.LambdaPenalty }},
+		{Name: "gamma_morph", Min: 0, Max: 2, Step: 0.15, field: func(s *sc.Scoring) *float64 { return &s.GammaMorph }},
+		{Name: "freq_temperature", Min: 1, Max: 4, Step: 0.25, field: func(s *sc.Scoring) *float64 { return &s.FreqTemperature }},
+		{Name: "margin_threshold", Min: 0, Max: 1, Step: 0.05, field: func(s *sc.Scoring) *float64 { return &s.MarginThreshold }},
+		{Name: "tau_in_vocab", Min: 0, Max: 2, Step: 0.1, field: func(s *sc.Scoring) *float64 { return &s.TauInVocab }},
+		{Name: "tau_out_vocab", Min: 0, Max: 2, Step: 0.1, field: func(s *sc.Scoring) *float64 { return &s.TauOutVocab }},
+	}
+}
+
+// Objective - оптимизируемая метрика: Metric отчета eval при ограничении
+// на долю ложных исправлений в чистых предложениях.
+type Objective struct {
+	// Metric - f0_5 (по умолчанию), precision или recall исправления.
+	Metric string
+	// MaxFalsePositive - бюджет ложных исправлений (0 - без ограничения). Точка сверх
+	// бюджета недопустима и хуже любой допустимой (см. Point.Better); ее значение
+	// штрафуется пропорционально превышению, чтобы спуск мог выйти из недопустимой области.
+	MaxFalsePositive float64
+}
+
+// falsePositivePenalty - штраф за единицу превышения бюджета ложных исправлений.
+const falsePositivePenalty = 10
+
+// Value возвращает значение цели для отчета (чем больше, тем лучше).
+func (o Objective) Value(rep *eval.Report) float64 {
+	var v float64
+	switch o.Metric {
+	case "precision":
+		v = rep.Correction.Precision
+	case "recall":
+		v = rep.Correction.Recall
+	default:
+		v = rep.Correction.F05
+	}
+	if !o.Feasible(rep) {
+		v -= falsePositivePenalty * (rep.Clean.FalsePositive - o.MaxFalsePositive)
+	}
+	return v
+}
+
+// Feasible сообщает, укладывается ли отчет в бюджет ложных исправлений.
+func (o Objective) Feasible(rep *eval.Report) bool {
+	return o.MaxFalsePositive <= 0 || rep.Clean.FalsePositive <= o.MaxFalsePositive
+}
+
+// Validate проверяет название метрики.
+func (o Objective) Validate() error {
+	switch o.Metric {
+	case "", "f0_5", "precision", "recall":
+		return nil
+	}
+	return fmt.Errorf("неизвестная метрика %q", o.Metric)
+}
+
+// Point - оцененный набор параметров.
+type Point struct {
+	Scoring   sc.Scoring `json:"scoring"`
+	Objective float64    `json:"objective"`
+	// Feasible - точка укладывается в бюджет ложных исправлений.
+	Feasible bool         `json:"feasible"`
+	Report   *eval.Report `json:"-"`
+}
+
+// Better сообщает, что точка p лучше q: допустимая точка лучше недопустимой,
+// при равной допустимости лучше точка с большим значением цели.
+func (p Point) Better(q Point) bool {
+	if p.Feasible != q.Feasible {
+		return p.Feasible
+	}
+	return p.Objective > q.Objective+1e-9
+}
+
+// Tuner оценивает наборы параметров, запоминая уже оцененные.
+type Tuner struct {
+	Params    []Param
+	Objective Objective
+	// Evaluate прогоняет корпус с заданными параметрами.
+	Evaluate func(s sc.Scoring) *eval.Report
+	// Log, если задан, получает сообщения о ходе подбора.
+	Log func(format string, args ...any)
+
+	cache map[sc.Scoring]Point
+}
+
+// point оценивает набор параметров (или берет оценку из кэша).
+func (t *Tuner) point(s sc.Scoring) Point {
+	if t.cache == nil {
+		t.cache = make(map[sc.Scoring]Point)
+	}
+	if p, ok := t.cache[s]; ok {
+		return p
+	}
+	rep := t.Evaluate(s)
+	p := Point{Scoring: s, Objective: t.Objective.Value(rep), Feasible: t.Objective.Feasible(rep), Report: rep}
+	t.cache[s] = p
+	return p
+}
+
+// Evaluations возвращает число прогонов корпуса.
+func (t *Tuner) Evaluations() int { return len(t.cache) }
+
+func (t *Tuner) logf(format string, args ...any) {
+	if t.Log != nil {
+		t.Log(format, args...)
+	}
+}
+
+// RandomStart оценивает start и n случайных точек сетки и возвращает лучшую (см. Point.Better).
+func (t *Tuner) RandomStart(start sc.Scoring, n int, rng *rand.Rand) Point {
+	best := t.point(start)
+	for i := 0; i < n; i++ {
+		s := start
+		for _, p := range t.Params {
+			g := p.grid()
+			*p.field(&s) = g[rng.Intn(len(g))]
+		}
+		if pt := t.point(s); pt.Better(best) {
+			best = pt
+			t.logf("случайная точка %d: %.4f", i+1, pt.Objective)
+		}
+	}
+	return best
+}
+
+// CoordinateDescent улучшает start по одному параметру за раз: для каждого параметра
+// перебирается его сетка при остальных фиксированных. Останавливается, когда за круг
+// ни один параметр не улучшил точку (см. Point.Better), или после rounds кругов.
+// Результат недопустим, только если допустимых точек спуск не встретил.
+func (t *Tuner) CoordinateDescent(start Point, rounds int) Point {
+	best := start
+	for round := 1; round <= rounds; round++ {
+		improved := false
+		for _, p := range t.Params {
+			for _, v := range p.grid() {
+				s := best.Scoring
+				*p.field(&s) = v
+				if pt := t.point(s); pt.Better(best) {
+					best = pt
+					improved = true
+					t.logf("круг %d: %s=%g -> %.4f", round, p.Name, v, pt.Objective)
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return best
+}
+
+// Sample - значение параметра и результат при остальных параметрах оптимума.
+type Sample struct {
+	Value         float64 `json:"value"`
+	Objective     float64 `json:"objective"`
+	F05           float64 `json:"f0_5"`
+	FalsePositive float64 `json:"false_positive_rate"`
+	Feasible      bool    `json:"feasible"`
+}
+
+// Sensitivity - чувствительность цели к одному параметру вокруг оптимума.
+type Sensitivity struct {
+	Best float64 `json:"best"`
+	// Spread - разность лучшего и худшего значений цели на сетке параметра.
+	Spread float64 `json:"spread"`
+	// Plateau - отрезок значений, где точка допустима (если допустим оптимум)
+	// и цель не хуже оптимума более чем на 1%.
+	PlateauMin float64  `json:"plateau_min"`
+	PlateauMax float64  `json:"plateau_max"`
+	Curve      []Sample `json:"curve"`
+}
+
+// Sensitivity перебирает сетку каждого параметра при остальных, равных best.
+func (t *Tuner) Sensitivity(best Point) map[string]Sensitivity {
+	out := make(map[string]Sensitivity, len(t.Params))
+	for _, p := range t.Params {
+		sens := Sensitivity{Best: *p.field(&best.Scoring)}
+		lo, hi := math.Inf(1), math.Inf(-1)
+		sens.PlateauMin, sens.PlateauMax = sens.Best, sens.Best
+		tolerance := 0.01 * math.Abs(best.Objective)
+		for _, v := range p.grid() {
+			s := best.Scoring
+			*p.field(&s) = v
+			pt := t.point(s)
+			sens.Curve = append(sens.Curve, Sample{
+				Value:         v,
+				Objective:     round(pt.Objective),
+				F05:           pt.Report.Correction.F05,
+				FalsePositive: pt.Report.Clean.FalsePositive,
+				Feasible:      pt.Feasible,
+			})
+			lo, hi = math.Min(lo, pt.Objective), math.Max(hi, pt.Objective)
+			if pt.Objective >= best.Objective-tolerance && (pt.Feasible || !best.Feasible) {
+				sens.PlateauMin, sens.PlateauMax = math.Min(sens.PlateauMin, v), math.Max(sens.PlateauMax, v)
+			}
+		}
+		sens.Spread = round(hi - lo)
+		out[p.Name] = sens
+	}
+	return out
+}
+
+func round(x float64) float64 { return math.Round(x*1e4) / 1e4 }
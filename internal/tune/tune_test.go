@@ -0,0 +1,127 @@
+package tune
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	sc "corrector/internal/corrector"
+	"corrector/internal/eval"
+)
+
+// newTestTuner возвращает Tuner с синтетическим корпусом: F0.5 растет при уменьшении
+// margin_threshold и максимальна при beta_weight = 1.2, а доля ложных исправлений
+// равна floor + 0.02*(1 - margin_threshold). Штраф за превышение бюджета растет
+// медленнее F0.5, так что без ограничения спуск ушел бы в недопустимую область.
+func newTestTuner(maxFP, floor float64) *Tuner {
+	var params []Param
+	for _, p := range DefaultParams() {
+		if p.Name == "beta_weight" || p.Name == "margin_threshold" {
+			params = append(params, p)
+		}
+	}
+	return &Tuner{
+		Params:    params,
+		Objective: Objective{MaxFalsePositive: maxFP},
+		Evaluate: func(s sc.Scoring) *eval.Report {
+			rep := &eval.Report{}
+			rep.Correction.F05 = 0.9 - 0.5*s.MarginThreshold - (s.BetaWeight-1.2)*(s.BetaWeight-1.2)
+			rep.Clean.FalsePositive = floor + 0.02*(1-s.MarginThreshold)
+			return rep
+		},
+	}
+}
+
+func TestCoordinateDescent(t *testing.T) {
+	tuner := newTestTuner(0.0041, 0)
+	start := sc.DefaultConfig().Scoring()
+	start.BetaWeight, start.MarginThreshold = 0.5, 0.3
+	best := tuner.CoordinateDescent(tuner.RandomStart(start, 0, nil), 5)
+
+	// Меньшие margin_threshold дают большую цель даже со штрафом за превышение бюджета,
+	// но допустимая точка лучше любой недопустимой
+	if !best.Feasible || math.Abs(best.Scoring.MarginThreshold-0.8) > 1e-9 || math.Abs(best.Scoring.BetaWeight-1.2) > 1e-9 {
+		t.Errorf("лучшая точка: beta_weight=%g margin_threshold=%g допустима=%v, ожидается 1.2, 0.8, true",
+			best.Scoring.BetaWeight, best.Scoring.MarginThreshold, best.Feasible)
+	}
+	if math.Abs(best.Objective-0.5) > 1e-9 {
+		t.Errorf("цель в лучшей точке: %.4f, ожидается 0.5", best.Objective)
+	}
+
+	// Повторные точки берутся из кэша
+	n := tuner.Evaluations()
+	tuner.CoordinateDescent(best, 5)
+	if tuner.Evaluations() != n {
+		t.Errorf("повторный спуск из оптимума прогнал корпус %d раз", tuner.Evaluations()-n)
+	}
+
+	sens := tuner.Sensitivity(best)["margin_threshold"]
+	if sens.Best != best.Scoring.MarginThreshold || sens.PlateauMin != 0.8 || sens.PlateauMax != 0.8 {
+		t.Errorf("чувствительность margin_threshold: %+v", sens)
+	}
+	for _, s := range sens.Curve {
+		if s.Feasible != (s.Value >= 0.8-1e-9) {
+			t.Errorf("margin_threshold=%g: допустима=%v", s.Value, s.Feasible)
+		}
+	}
+}
+
+func TestCoordinateDescentInfeasible(t *testing.T) {
+	// Доля ложных исправлений не опускается ниже 0.01 при бюджете 0.005
+	tuner := newTestTuner(0.005, 0.01)
+	best := tuner.CoordinateDescent(tuner.RandomStart(sc.DefaultConfig().Scoring(), 10, rand.New(rand.NewSource(1))), 5)
+	if best.Feasible {
+		t.Fatalf("недостижимый бюджет: точка %+v допустима", best.Scoring)
+	}
+	if best.Objective >= best.Report.Correction.F05 {
+		t.Errorf("цель недопустимой точки %.4f без штрафа", best.Objective)
+	}
+}
+
+func TestRandomStart(t *testing.T) {
+	tuner := newTestTuner(0.0041, 0)
+	start := sc.DefaultConfig().Scoring()
+	start.MarginThreshold = 0
+	best := tuner.RandomStart(start, 30, rand.New(rand.NewSource(1)))
+	if !best.Feasible || best.Scoring.MarginThreshold < 0.8 {
+		t.Errorf("лучшая случайная точка: margin_threshold=%g допустима=%v", best.Scoring.MarginThreshold, best.Feasible)
+	}
+	if tuner.Evaluations() > 31 {
+		t.Errorf("прогонов корпуса: %d из 31 точки", tuner.Evaluations())
+	}
+}
+
+func TestPointBetter(t *testing.T) {
+	feasible := Point{Objective: 0.1, Feasible: true}
+	infeasible := Point{Objective: 0.9}
+	if !feasible.Better(infeasible) || infeasible.Better(feasible) {
+		t.Error("допустимая точка должна быть лучше недопустимой")
+	}
+	if !(Point{Objective: 0.2, Feasible: true}).Better(feasible) || feasible.Better(feasible) {
+		t.Error("при равной допустимости лучше большая цель")
+	}
+}
+
+func TestObjective(t *testing.T) {
+	rep := &eval.Report{}
+	rep.Correction.Precision, rep.Correction.Recall, rep.Correction.F05 = 0.8, 0.4, 0.6
+	rep.Clean.FalsePositive = 0.03
+	tests := []struct {
+		o        Objective
+		value    float64
+		feasible bool
+	}{
+		{Objective{}, 0.6, true},
+		{Objective{Metric: "precision"}, 0.8, true},
+		{Objective{Metric: "recall", MaxFalsePositive: 0.05}, 0.4, true},
+		{Objective{MaxFalsePositive: 0.01}, 0.6 - falsePositivePenalty*0.02, false},
+	}
+	for _, tt := range tests {
+		if v, ok := tt.o.Value(rep), tt.o.Feasible(rep); math.Abs(v-tt.value) > 1e-9 || ok != tt.feasible {
+			t.Errorf("%+v: %.4f %v, ожидается %.4f %v", tt.o, v, ok, tt.value, tt.feasible)
+		}
+	}
+	if (Objective{Metric: "f1"}).Validate() == nil {
+		t.Error("неизвестная метрика принята")
+	}
+}